@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/zegl/tre/compiler/compiler"
@@ -18,26 +19,90 @@ import (
 
 var debug bool
 
-func Build(path, goroot, outputBinaryPath string, setDebug bool, optimize bool) error {
-	c := compiler.NewCompiler()
-	debug = setDebug
+// Options controls how a package is compiled
+type Options struct {
+	// Print the parsed source code and the generated IR
+	Debug bool
 
-	err := compilePackage(c, path, goroot, "main")
+	// Enable clang optimizations
+	Optimize bool
+
+	// Generate DWARF debug information, so that the program can be debugged with gdb or lldb
+	DebugInfo bool
+
+	// The platform to generate code for, defaults to the host platform
+	Target compiler.Target
+}
+
+func (o Options) target() compiler.Target {
+	if o.Target == (compiler.Target{}) {
+		return compiler.HostTarget()
+	}
+	return o.Target
+}
+
+func Build(path, goroot, outputBinaryPath string, opts Options) error {
+	target := opts.target()
+	if err := target.Validate(); err != nil {
+		return err
+	}
+
+	c := compiler.NewCompilerForTarget(target)
+	debug = opts.Debug
+
+	if opts.DebugInfo {
+		c.EnableDebugInfo(path)
+	}
+
+	err := compilePackage(c, path, goroot, "main", "main")
 	if err != nil {
 		return err
 	}
 
+	return link(c, outputBinaryPath, opts)
+}
+
+// link compiles the IR in c with clang. The kind of output depends on the
+// extension of outputPath:
+//
+//	.ll  LLVM IR
+//	.bc  LLVM bitcode
+//	.s   Assembly
+//	.o   Object file
+//	.a   Static library (c-archive), functions marked with "//export Name" can be called from C
+//
+// Any other path is linked to an executable.
+func link(c *compiler.Compiler, outputPath string, opts Options) error {
+	target := opts.target()
+
+	if outputPath == "" {
+		outputPath = "output-binary"
+	}
+
+	ext := filepath.Ext(outputPath)
+
+	// The library is linked to a program that has it's own main function
+	if ext == ".a" {
+		c.BuildLibrary()
+	}
+
 	compiled := c.GetIR()
 
 	if debug {
 		fmt.Println(compiled)
 	}
 
+	// The IR can be written directly, without any help from clang
+	if ext == ".ll" && !opts.Optimize {
+		return ioutil.WriteFile(outputPath, []byte(compiled), 0666)
+	}
+
 	// Get dir to save temporary dirs in
 	tmpDir, err := ioutil.TempDir("", "tre")
 	if err != nil {
 		panic(err)
 	}
+	defer os.RemoveAll(tmpDir)
 
 	// Write LLVM IR to disk
 	err = ioutil.WriteFile(tmpDir+"/main.ll", []byte(compiled), 0666)
@@ -45,20 +110,46 @@ func Build(path, goroot, outputBinaryPath string, setDebug bool, optimize bool)
 		panic(err)
 	}
 
-	if outputBinaryPath == "" {
-		outputBinaryPath = "output-binary"
-	}
+	clangOutputPath := outputPath
 
 	clangArgs := []string{
-		"-Wno-override-module", // Disable override target triple warnings
-		tmpDir + "/main.ll",    // Path to LLVM IR
-		"-o", outputBinaryPath, // Output path
+		"-Wno-override-module",        // Disable override target triple warnings
+		"--target=" + target.Triple(), // Target platform, is needed when cross compiling
+		tmpDir + "/main.ll",           // Path to LLVM IR
+	}
+
+	switch ext {
+	case ".ll":
+		clangArgs = append(clangArgs, "-S", "-emit-llvm")
+	case ".bc":
+		clangArgs = append(clangArgs, "-c", "-emit-llvm")
+	case ".s":
+		clangArgs = append(clangArgs, "-S")
+	case ".o":
+		clangArgs = append(clangArgs, "-c")
+	case ".a":
+		// Create an object file, that is added to the archive below
+		clangArgs = append(clangArgs, "-c")
+		clangOutputPath = tmpDir + "/main.o"
+	default:
+		// Math intrinsics such as llvm.pow are lowered to calls to libm, and
+		// threads are created with pthreads. Both are part of libc on the
+		// other platforms.
+		if target.GOOS == "linux" {
+			clangArgs = append(clangArgs, "-lm", "-lpthread")
+		}
 	}
 
-	if optimize {
+	clangArgs = append(clangArgs, "-o", clangOutputPath) // Output path
+
+	if opts.Optimize {
 		clangArgs = append(clangArgs, "-O3")
 	}
 
+	if opts.DebugInfo {
+		clangArgs = append(clangArgs, "-g")
+	}
+
 	// Invoke clang compiler to compile LLVM IR to a binary executable
 	cmd := exec.Command("clang", clangArgs...)
 	output, err := cmd.CombinedOutput()
@@ -72,15 +163,40 @@ func Build(path, goroot, outputBinaryPath string, setDebug bool, optimize bool)
 		return errors.New("Clang failure")
 	}
 
+	if ext == ".a" {
+		// Replace the archive if it already exists, instead of adding to it
+		err = os.Remove(outputPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		output, err = exec.Command("ar", "rcs", outputPath, clangOutputPath).CombinedOutput()
+		if err != nil {
+			fmt.Println(string(output))
+			return err
+		}
+	}
+
 	return nil
 }
 
-func compilePackage(c *compiler.Compiler, path, goroot, name string) error {
-	f, err := os.Stat(path)
+func compilePackage(c *compiler.Compiler, path, goroot, importPath, name string) error {
+	parsedFiles, err := parsePackage(path, false)
 	if err != nil {
 		return err
 	}
 
+	return compileFiles(c, parsedFiles, path, goroot, importPath, name)
+}
+
+// parsePackage parses all files in the package at path. Test files are only
+// included if includeTests is set.
+func parsePackage(path string, includeTests bool) ([]parser.FileNode, error) {
+	f, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
 	var parsedFiles []parser.FileNode
 
 	// Parse all files in the folder
@@ -92,6 +208,10 @@ func compilePackage(c *compiler.Compiler, path, goroot, name string) error {
 
 		for _, file := range files {
 			if !file.IsDir() {
+				if isTestFile(file.Name()) && !includeTests {
+					continue
+				}
+
 				// Tre files doesn't have to contain valid Go code, and is used to prevent issues
 				// with some of the go tools (like vgo)
 				if strings.HasSuffix(file.Name(), ".go") || strings.HasSuffix(file.Name(), ".tre") {
@@ -104,6 +224,12 @@ func compilePackage(c *compiler.Compiler, path, goroot, name string) error {
 		parsedFiles = append(parsedFiles, parseFile(path))
 	}
 
+	return parsedFiles, nil
+}
+
+// compileFiles compiles all imported packages and then parsedFiles as package
+// name, that is imported with importPath
+func compileFiles(c *compiler.Compiler, parsedFiles []parser.FileNode, path, goroot, importPath, name string) error {
 	// Scan for ImportNodes
 	// Use importNodes to import more packages
 	for _, file := range parsedFiles {
@@ -121,6 +247,15 @@ func compilePackage(c *compiler.Compiler, path, goroot, name string) error {
 						continue
 					}
 
+					// Has already been imported by another package
+					if c.HasPackage(packagePath) {
+						continue
+					}
+
+					// Packages are named after the last element of the path,
+					// such as bits in "math/bits"
+					packageName := filepath.Base(packagePath)
+
 					searchPaths := []string{
 						path + "/vendor/" + packagePath,
 						goroot + "/" + packagePath,
@@ -138,12 +273,13 @@ func compilePackage(c *compiler.Compiler, path, goroot, name string) error {
 							log.Printf("Loading %s from %s", packagePath, sp)
 						}
 
-						err = compilePackage(c, sp, goroot, packagePath)
+						err = compilePackage(c, sp, goroot, packagePath, packageName)
 						if err != nil {
 							return err
 						}
 
 						importSuccessful = true
+						break
 					}
 
 					if !importSuccessful {
@@ -161,9 +297,14 @@ func compilePackage(c *compiler.Compiler, path, goroot, name string) error {
 	return c.Compile(parser.PackageNode{
 		Files: parsedFiles,
 		Name:  name,
+		Path:  importPath,
 	})
 }
 
+func isTestFile(name string) bool {
+	return strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, "_test.tre")
+}
+
 func parseFile(path string) parser.FileNode {
 	// Read specified input file
 	fileContents, err := ioutil.ReadFile(path)
@@ -171,8 +312,14 @@ func parseFile(path string) parser.FileNode {
 		panic(err)
 	}
 
+	parsed := parseSource(string(fileContents))
+	parsed.Path = path
+	return parsed
+}
+
+func parseSource(source string) parser.FileNode {
 	// Run input code through the lexer. A list of tokens is returned.
-	lexed := lexer.Lex(string(fileContents))
+	lexed := lexer.Lex(source)
 
 	// Run lexed source through the parser. A syntax tree is returned.
 	parsed := parser.Parse(lexed, debug)
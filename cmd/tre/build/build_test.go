@@ -0,0 +1,173 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zegl/tre/compiler/compiler"
+)
+
+func TestBuildOutputModes(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tre-build-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	for _, ext := range []string{".ll", ".bc", ".s", ".o", ".a"} {
+		t.Run(ext, func(t *testing.T) {
+			outputPath := tmpDir + "/export" + ext
+			err := Build("testdata/export", testGoroot(), outputPath, Options{})
+			assert.Nil(t, err)
+
+			stat, err := os.Stat(outputPath)
+			assert.Nil(t, err)
+			assert.True(t, stat.Size() > 0)
+		})
+	}
+
+	ir, err := ioutil.ReadFile(tmpDir + "/export.ll")
+	assert.Nil(t, err)
+	assert.Contains(t, string(ir), "define i64 @Add(")
+	assert.Contains(t, string(ir), "define i32 @main(")
+
+	asm, err := ioutil.ReadFile(tmpDir + "/export.s")
+	assert.Nil(t, err)
+	assert.Contains(t, string(asm), "Add")
+}
+
+func TestBuildCArchive(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tre-build-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	err = Build("testdata/export", testGoroot(), tmpDir+"/libexport.a", Options{})
+	assert.Nil(t, err)
+
+	// Link the archive into a C program
+	cPath, err := filepath.Abs("testdata/export/main.c")
+	assert.Nil(t, err)
+	output, err := exec.Command("clang", cPath, tmpDir+"/libexport.a", "-o", tmpDir+"/program").CombinedOutput()
+	assert.Nil(t, err, string(output))
+
+	output, err = exec.Command(tmpDir + "/program").CombinedOutput()
+	assert.Nil(t, err)
+	assert.Equal(t, "hello from tre\n3 100", strings.TrimSpace(string(output)))
+}
+
+func TestBuildCrossCompile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tre-build-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		target  compiler.Target
+		triple  string
+		syscall string
+	}{
+		{compiler.Target{GOOS: "linux", GOARCH: "amd64"}, "x86_64-pc-linux-gnu", `asm sideeffect "syscall", "={rax},{rax},{rdi},{rsi},{rdx},~{rcx},~{r11},~{memory}"(i64 1,`},
+		{compiler.Target{GOOS: "linux", GOARCH: "arm64"}, "aarch64-pc-linux-gnu", `asm sideeffect "svc #0", "={x0},{x8},0,{x1},{x2}"(i64 64,`},
+		{compiler.Target{GOOS: "darwin", GOARCH: "amd64"}, "x86_64-apple-macosx10.13.0", `asm sideeffect "syscall", "={rax},{rax},{rdi},{rsi},{rdx},~{rcx},~{r11},~{memory}"(i64 33554436,`},
+		{compiler.Target{GOOS: "darwin", GOARCH: "arm64"}, "arm64-apple-macosx11.0.0", `asm sideeffect "svc #0x80", "={x0},{x16},0,{x1},{x2}"(i64 4,`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.triple, func(t *testing.T) {
+			outputPath := tmpDir + "/" + tc.triple + ".ll"
+			err := Build("testdata/print", testGoroot(), outputPath, Options{Target: tc.target})
+			assert.Nil(t, err)
+
+			ir, err := ioutil.ReadFile(outputPath)
+			assert.Nil(t, err)
+			assert.Contains(t, string(ir), `target triple = "`+tc.triple+`"`)
+			assert.Contains(t, string(ir), `target datalayout = "`+tc.target.DataLayout()+`"`)
+			assert.Contains(t, string(ir), tc.syscall)
+		})
+	}
+}
+
+func TestBuildUnsupportedTarget(t *testing.T) {
+	err := Build("testdata/print", testGoroot(), "", Options{Target: compiler.Target{GOOS: "linux", GOARCH: "386"}})
+	assert.EqualError(t, err, "unsupported GOARCH: 386")
+}
+
+func TestBuildDebugInfo(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tre-build-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	err = Build("testdata/debug", testGoroot(), tmpDir+"/debug.ll", Options{DebugInfo: true})
+	assert.Nil(t, err)
+
+	ir, err := ioutil.ReadFile(tmpDir + "/debug.ll")
+	assert.Nil(t, err)
+	assert.Contains(t, string(ir), `!DICompileUnit(language: DW_LANG_Go`)
+	assert.Contains(t, string(ir), `!DIFile(filename: "main.go"`)
+	assert.Contains(t, string(ir), `!DISubprogram(name: "main.add", linkageName: "main_add"`)
+	assert.Contains(t, string(ir), `!DILocalVariable(name: "a", arg: 1`)
+	assert.Contains(t, string(ir), `!DILocalVariable(name: "sum", scope: `)
+	assert.Contains(t, string(ir), `!DILocalVariable(name: "name", scope: `)
+	assert.Contains(t, string(ir), `!DILocalVariable(name: "p", scope: `)
+	assert.Contains(t, string(ir), `!DILocalVariable(name: "q", scope: `)
+	assert.Contains(t, string(ir), `!DILocalVariable(name: "r", scope: `)
+	assert.Contains(t, string(ir), `!DICompositeType(tag: DW_TAG_structure_type, name: "main.point"`)
+	assert.NotContains(t, string(ir), `!DILocalVariable(name: "panic-arg`)
+	assert.Contains(t, string(ir), `!DICompositeType(tag: DW_TAG_structure_type, name: "[]int64", size: 192`)
+	assert.Contains(t, string(ir), `!DILocation(line: 11, scope: `)
+
+	// The debug information is verified by clang when building the executable
+	err = Build("testdata/debug", testGoroot(), tmpDir+"/debug", Options{DebugInfo: true})
+	assert.Nil(t, err)
+
+	output, err := exec.Command(tmpDir + "/debug").CombinedOutput()
+	assert.Nil(t, err)
+	assert.Equal(t, "tre 3 4\n", string(output))
+}
+
+func TestBuildStackTraces(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tre-build-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	err = Build("testdata/stacktrace", testGoroot(), tmpDir+"/stacktrace", Options{})
+	assert.Nil(t, err)
+
+	output, err := exec.Command(tmpDir + "/stacktrace").CombinedOutput()
+	assert.EqualError(t, err, "exit status 1")
+	assert.Equal(t, `runtime panic: index out of range
+
+goroutine 1 [running]:
+main.(*list).get()
+	testdata/stacktrace/main.go:8
+main.lookup()
+	testdata/stacktrace/main.go:12
+main.main.func1()
+	testdata/stacktrace/main.go:21
+main.main()
+	testdata/stacktrace/main.go:23
+`, string(output))
+}
+
+func TestBuildStackTracesThread(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tre-build-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	err = Build("testdata/stacktrace-thread", testGoroot(), tmpDir+"/stacktrace-thread", Options{})
+	assert.Nil(t, err)
+
+	output, err := exec.Command(tmpDir + "/stacktrace-thread").CombinedOutput()
+	assert.EqualError(t, err, "exit status 1")
+	assert.Equal(t, `runtime panic: index out of range
+
+goroutine 1 [running]:
+main.lookup()
+	testdata/stacktrace-thread/main.go:6
+main.worker()
+	testdata/stacktrace-thread/main.go:11
+`, string(output))
+}
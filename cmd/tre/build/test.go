@@ -0,0 +1,172 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/zegl/tre/compiler/compiler"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// ErrTestsFailed is returned by Test when the tests were executed, but at least one of them failed
+var ErrTestsFailed = errors.New("tests failed")
+
+// Test compiles the package at path together with its test files, and runs all
+// TestXxx functions in them. The output of the tests is written to stdout.
+func Test(path, goroot string, stdout io.Writer, opts Options) error {
+	// The tests are executed directly after they have been compiled
+	target := opts.target()
+	if target != compiler.HostTarget() {
+		return fmt.Errorf("can not run tests for %s/%s on %s/%s", target.GOOS, target.GOARCH, runtime.GOOS, runtime.GOARCH)
+	}
+
+	c := compiler.NewCompiler()
+	debug = opts.Debug
+
+	if opts.DebugInfo {
+		c.EnableDebugInfo(path)
+	}
+
+	parsedFiles, err := parsePackage(path, true)
+	if err != nil {
+		return err
+	}
+
+	packageName := "main"
+	for _, file := range parsedFiles {
+		if len(file.Instructions) > 0 {
+			if declarePackage, ok := file.Instructions[0].(*parser.DeclarePackageNode); ok {
+				packageName = declarePackage.PackageName
+				break
+			}
+		}
+	}
+
+	tests := findTests(parsedFiles)
+
+	if packageName == "main" {
+		// The tests are running in the main package, replace the
+		// main function of the package with the generated one
+		for i, file := range parsedFiles {
+			var instructions []parser.Node
+			for _, ins := range file.Instructions {
+				if defineFunc, ok := ins.(*parser.DefineFuncNode); ok && !defineFunc.IsMethod && defineFunc.Name == "main" {
+					continue
+				}
+				instructions = append(instructions, ins)
+			}
+			parsedFiles[i].Instructions = instructions
+		}
+
+		parsedFiles = append(parsedFiles, parseSource(testMainSource("", tests)))
+
+		err = compileFiles(c, parsedFiles, path, goroot, "main", "main")
+		if err != nil {
+			return err
+		}
+	} else {
+		err = compileFiles(c, parsedFiles, path, goroot, packageName, packageName)
+		if err != nil {
+			return err
+		}
+
+		err = compileFiles(c, []parser.FileNode{parseSource(testMainSource(packageName, tests))}, path, goroot, "main", "main")
+		if err != nil {
+			return err
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "tre-test")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	testBinaryPath := tmpDir + "/test-binary"
+
+	err = link(c, testBinaryPath, opts)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(testBinaryPath)
+	cmd.Stdout = stdout
+	cmd.Stderr = stdout
+
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return ErrTestsFailed
+	}
+	return err
+}
+
+// findTests returns the names of all functions on the format "func TestXxx(t *testing.T)"
+func findTests(parsedFiles []parser.FileNode) []string {
+	var tests []string
+
+	for _, file := range parsedFiles {
+		for _, ins := range file.Instructions {
+			defineFunc, ok := ins.(*parser.DefineFuncNode)
+			if !ok || defineFunc.IsMethod || !isTestName(defineFunc.Name) {
+				continue
+			}
+
+			if len(defineFunc.Arguments) != 1 || len(defineFunc.ReturnValues) != 0 {
+				continue
+			}
+
+			ptr, ok := defineFunc.Arguments[0].Type.(*parser.PointerTypeNode)
+			if !ok {
+				continue
+			}
+
+			if t, ok := ptr.ValueType.(*parser.SingleTypeNode); ok && t.PackageName == "testing" && t.TypeName == "T" {
+				tests = append(tests, defineFunc.Name)
+			}
+		}
+	}
+
+	return tests
+}
+
+// isTestName reports whether name is on the format TestXxx, where Xxx does not start with a lower case letter
+func isTestName(name string) bool {
+	if !strings.HasPrefix(name, "Test") {
+		return false
+	}
+	if len(name) == 4 {
+		return true
+	}
+	return name[4] < 'a' || name[4] > 'z'
+}
+
+// testMainSource generates the main package that runs all tests. The tests are
+// called from the package testPackage, or from the main package if testPackage is empty.
+func testMainSource(testPackage string, tests []string) string {
+	var src strings.Builder
+
+	src.WriteString("package main\n\n")
+	src.WriteString("import \"testing\"\n")
+
+	prefix := ""
+	if testPackage != "" {
+		src.WriteString(fmt.Sprintf("import \"%s\"\n", testPackage))
+		prefix = testPackage + "."
+	}
+
+	src.WriteString("\nfunc main() {\n")
+	src.WriteString("\tvar m testing.M\n")
+	for _, test := range tests {
+		src.WriteString(fmt.Sprintf("\tm.Add(\"%s\", %s%s)\n", test, prefix, test))
+	}
+	src.WriteString("\tm.Run()\n")
+	src.WriteString("}\n")
+
+	return src.String()
+}
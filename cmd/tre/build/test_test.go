@@ -0,0 +1,82 @@
+package build
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGoroot() string {
+	_, testFilePath, _, _ := runtime.Caller(0)
+	return filepath.Clean(testFilePath + "/../../../../pkg/")
+}
+
+func TestTestFailingPackage(t *testing.T) {
+	var out bytes.Buffer
+	err := Test("testdata/test-lib", testGoroot(), &out, Options{})
+	assert.Equal(t, ErrTestsFailed, err)
+
+	expected := `=== RUN   TestAdd
+--- PASS: TestAdd
+=== RUN   TestFail
+    Add(1, 2) = 3, want four
+    continued 1 true
+    fatal
+--- FAIL: TestFail
+=== RUN   TestSkip
+    skipping
+--- SKIP: TestSkip
+=== RUN   TestSub
+=== RUN   TestSub/a
+        in a
+    --- PASS: TestSub/a
+=== RUN   TestSub/b
+    --- FAIL: TestSub/b
+--- FAIL: TestSub
+=== RUN   TestFormat
+    got {1} want "a",     3 6869
+--- FAIL: TestFormat
+=== RUN   TestPanic
+runtime panic: index out of range
+
+goroutine 1 [running]:
+lib.TestPanic()
+	testdata/test-lib/lib_test.go:46
+testing.runTest()
+	` + testGoroot() + `/testing/testing.tre:86
+testing.(*M).Run()
+	` + testGoroot() + `/testing/testing.tre:237
+main.main()
+	<autogenerated>:15
+--- FAIL: TestPanic (crashed)
+=== RUN   TestAfterPanic
+    still running
+--- PASS: TestAfterPanic
+FAIL
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestTestMainPackage(t *testing.T) {
+	var out bytes.Buffer
+	err := Test("testdata/test-main", testGoroot(), &out, Options{})
+	assert.Nil(t, err)
+
+	expected := `=== RUN   TestDouble
+--- PASS: TestDouble
+PASS
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestIsTestName(t *testing.T) {
+	assert.True(t, isTestName("Test"))
+	assert.True(t, isTestName("TestFoo"))
+	assert.True(t, isTestName("Test_foo"))
+	assert.False(t, isTestName("Testfoo"))
+	assert.False(t, isTestName("testFoo"))
+	assert.False(t, isTestName("Foo"))
+}
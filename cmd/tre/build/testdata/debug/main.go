@@ -0,0 +1,25 @@
+package main
+
+import "external"
+
+type point struct {
+	x int
+	y int
+}
+
+func add(a int, b int) int {
+	sum := a + b
+	return sum
+}
+
+func main() {
+	name := "tre"
+	nums := []int{1, 2}
+	p := point{x: 1, y: 2}
+	var q point
+	r := &point{x: 3}
+	if r.x > 3 {
+		panic("unreachable")
+	}
+	external.Printf("%s %d %d\n", name, add(nums[0], nums[1]), p.x+q.y+r.x)
+}
@@ -0,0 +1,11 @@
+#include <stdio.h>
+
+long Add(long a, long b);
+long Base(void);
+void Hello(void);
+
+int main() {
+	Hello();
+	printf("%ld %ld\n", Add(1, 2), Base());
+	return 0;
+}
@@ -0,0 +1,23 @@
+package main
+
+import "external"
+
+var base = 100
+
+//export Add
+func add(a int, b int) int {
+	return a + b
+}
+
+//export Base
+func getBase() int {
+	return base
+}
+
+//export Hello
+func hello() {
+	external.Printf("hello from tre\n")
+}
+
+func main() {
+}
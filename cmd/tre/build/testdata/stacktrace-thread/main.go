@@ -0,0 +1,17 @@
+package main
+
+import "external"
+
+func lookup(items []int, i int) int {
+	return items[i]
+}
+
+func worker(i int) {
+	items := []int{1, 2, 3}
+	lookup(items, i)
+}
+
+func main() {
+	thread := external.ThreadCreate(worker, 5)
+	external.ThreadJoin(thread)
+}
@@ -0,0 +1,24 @@
+package main
+
+type list struct {
+	items []int
+}
+
+func (l *list) get(i int) int {
+	return l.items[i]
+}
+
+func lookup(l *list, i int) int {
+	v := l.get(i)
+	return v
+}
+
+func main() {
+	l := &list{items: []int{1, 2, 3}}
+	lookup(l, 1)
+
+	f := func(l *list) {
+		lookup(l, 5)
+	}
+	f(l)
+}
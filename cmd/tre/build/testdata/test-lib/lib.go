@@ -0,0 +1,5 @@
+package lib
+
+func Add(a int, b int) int {
+	return a + b
+}
@@ -0,0 +1,51 @@
+package lib
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Errorf("Add(1, 2) = %d, want %d", Add(1, 2), 3)
+	}
+}
+
+func TestFail(t *testing.T) {
+	t.Errorf("Add(1, 2) = %d, want %s", Add(1, 2), "four")
+	t.Log("continued", 1, true)
+	t.Fatal("fatal")
+	t.Log("not reached")
+}
+
+func TestSkip(t *testing.T) {
+	t.Skip("skipping")
+}
+
+func TestSub(t *testing.T) {
+	t.Run("a", func(t *testing.T) {
+		t.Log("in a")
+	})
+	t.Run("b", func(t *testing.T) {
+		t.FailNow()
+	})
+}
+
+// Not a test, helper functions must not be executed
+func Testhelper(t *testing.T) {
+	t.Fatal("should not run")
+}
+
+type point struct {
+	x int
+}
+
+func TestFormat(t *testing.T) {
+	t.Errorf("got %v want %q, %5d %x", point{x: 1}, "a", 3, []byte("hi"))
+}
+
+func TestPanic(t *testing.T) {
+	var items []int
+	t.Log(items[1])
+}
+
+func TestAfterPanic(t *testing.T) {
+	t.Log("still running")
+}
@@ -0,0 +1,11 @@
+package main
+
+import "external"
+
+func double(a int) int {
+	return a * 2
+}
+
+func main() {
+	external.Printf("should not run\n")
+}
@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestDouble(t *testing.T) {
+	if double(2) != 4 {
+		t.Fatalf("double(2) = %d", double(2))
+	}
+}
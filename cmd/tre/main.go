@@ -9,23 +9,29 @@ import (
 
 	flag "github.com/spf13/pflag"
 	"github.com/zegl/tre/cmd/tre/build"
+	"github.com/zegl/tre/compiler/compiler"
 )
 
 var (
-	debug    bool
-	optimize bool
-	output   string
+	debug     bool
+	debugInfo bool
+	optimize  bool
+	output    string
+	target    string
 )
 
 func init() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] <filename>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s test [options] <package>\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 
 	flag.BoolVarP(&debug, "debug", "d", false, "Emit debug information during compile time")
 	flag.BoolVarP(&optimize, "optimize", "O", false, "Enable clang optimization")
-	flag.StringVarP(&output, "output", "o", "", "Output binary filename")
+	flag.BoolVarP(&debugInfo, "debug-info", "g", false, "Generate DWARF debug information, for debugging with gdb or lldb")
+	flag.StringVar(&target, "target", "", "Target triple to compile for, such as aarch64-linux-gnu (defaults to $GOOS and $GOARCH, or the host platform)")
+	flag.StringVarP(&output, "output", "o", "", "Output filename, the extension selects the format (.ll, .bc, .s, .o, .a or executable)")
 }
 
 func main() {
@@ -40,12 +46,44 @@ func main() {
 	treBinaryPath, _ := os.Executable()
 	goroot := filepath.Clean(treBinaryPath + "/../pkg/")
 
+	opts := build.Options{
+		Debug:     debug,
+		Optimize:  optimize,
+		DebugInfo: debugInfo,
+	}
+
+	var err error
+	opts.Target, err = selectTarget()
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+
+	// Run the tests in a package
+	if flag.Arg(0) == "test" {
+		path := "."
+		if len(flag.Args()) > 1 {
+			path = flag.Arg(1)
+		}
+
+		err := build.Test(path, goroot, os.Stdout, opts)
+		if err == build.ErrTestsFailed {
+			os.Exit(1)
+		}
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
 	if output == "" {
 		basename := filepath.Base(flag.Arg(0))
 		output = strings.TrimSuffix(basename, filepath.Ext(basename))
 	}
 
-	err := build.Build(flag.Arg(0), goroot, output, debug, optimize)
+	err = build.Build(flag.Arg(0), goroot, output, opts)
 	if err != nil {
 		log.Println(err)
 		os.Exit(1)
@@ -53,3 +91,21 @@ func main() {
 
 	os.Exit(0)
 }
+
+// selectTarget returns the platform to compile for. The --target flag has the
+// highest priority, followed by the GOOS and GOARCH environment variables.
+func selectTarget() (compiler.Target, error) {
+	if target != "" {
+		return compiler.ParseTargetTriple(target)
+	}
+
+	t := compiler.HostTarget()
+	if goos := os.Getenv("GOOS"); goos != "" {
+		t.GOOS = goos
+	}
+	if goarch := os.Getenv("GOARCH"); goarch != "" {
+		t.GOARCH = goarch
+	}
+
+	return t, t.Validate()
+}
@@ -1,6 +1,8 @@
 package compiler
 
 import (
+	"fmt"
+
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	irTypes "github.com/llir/llvm/ir/types"
@@ -47,8 +49,7 @@ func (c *Compiler) compileAllocNode(v *parser.AllocNode) {
 				IsVariable: true,
 			})
 		} else {
-			alloc := c.contextBlock.NewAlloca(treType.LLVM())
-			alloc.SetName(name.Var(v.Name[0]))
+			alloc := c.allocVar(v, v.Name[0], treType.LLVM())
 			val = alloc
 			block = c.contextBlock
 
@@ -59,13 +60,8 @@ func (c *Compiler) compileAllocNode(v *parser.AllocNode) {
 			})
 		}
 
-		// Set to zero values
-		// TODO: Make slices less special
-		if sliceType, ok := treType.(*types.Slice); ok {
-			sliceType.SliceZero(block, c.externalFuncs.Malloc.Value.(llvmValue.Named), 2, val)
-		} else {
-			treType.Zero(block, val)
-		}
+		// Set to zero values, slices are nil until something is appended to them
+		treType.Zero(block, val)
 
 		return
 	}
@@ -77,10 +73,20 @@ func (c *Compiler) compileAllocNode(v *parser.AllocNode) {
 		allocPackageVar := c.contextBlock == nil
 		if allocPackageVar {
 			c.contextBlock = c.initGlobalsFunc.Blocks[0]
+
+			// Package vars outlive the init function, values such as &T{} has
+			// to be allocated on the heap
+			v.Escapes = true
 		}
 
-		// Allocate from value
-		val := c.compileValue(valNode)
+		// Allocate from value. Constant expressions are evaluated at compile
+		// time if the variable has a type, such as in "var u uint64 = 1 << 63"
+		var val value.Value
+		if v.Type != nil && c.isUntypedConstant(valNode) {
+			val = c.compileConstantExpression(valNode)
+		} else {
+			val = c.compileValue(valNode)
+		}
 
 		if _, ok := val.Type.(*types.MultiValue); ok {
 			if len(v.Name) != len(val.MultiValues) {
@@ -99,11 +105,35 @@ func (c *Compiler) compileAllocNode(v *parser.AllocNode) {
 			return
 		}
 
+		// The value is converted to the type of the variable, if it has one
+		if v.Type != nil {
+			treType := c.parserTypeToType(v.Type)
+			if _, ok := val.Type.(*types.UntypedConstantNumber); ok {
+				val = value.UntypedConstAs(val, value.Value{Type: treType})
+			}
+			val = c.assignableValue(val, treType)
+
+			// Integers can only be assigned to variables of the same type
+			if intType, ok := treType.(*types.Int); ok {
+				if valType, ok := val.Type.(*types.Int); ok && valType.Name() != intType.Name() {
+					compilePanic(fmt.Sprintf("cannot use %s value as %s value in variable declaration", valType.Name(), intType.Name()))
+				}
+			}
+		}
+
+		if _, ok := val.Type.(*types.UntypedNil); ok {
+			compilePanic("use of untyped nil")
+		}
+
+		// Untyped constants gets the default type (int or float64) when
+		// allocated as a variable
+		val = value.UntypedDefault(val)
+
 		// Single variable allocation
 		llvmVal := val.Value
 
-		// Non-allocation needed pointers
-		if ptrVal, ok := val.Type.(*types.Pointer); ok && ptrVal.IsNonAllocDereference {
+		// Non-allocation needed pointers. Package vars are always stored in globals.
+		if ptrVal, ok := val.Type.(*types.Pointer); ok && ptrVal.IsNonAllocDereference && !allocPackageVar {
 			c.setVar(v.Name[valIndex], value.Value{
 				Type:       val.Type,
 				Value:      llvmVal,
@@ -112,8 +142,10 @@ func (c *Compiler) compileAllocNode(v *parser.AllocNode) {
 			return
 		}
 
-		// Non-allocation needed structs
-		if structVal, ok := val.Type.(*types.Struct); ok && structVal.IsHeapAllocated {
+		// Struct literals that escapes are already allocated on the heap, and
+		// the variable uses the same memory
+		_, isStructLiteral := valNode.(*parser.InitializeStructNode)
+		if isStructLiteral && v.Escapes && !allocPackageVar {
 			c.setVar(v.Name[valIndex], value.Value{
 				Type:       val.Type,
 				Value:      llvmVal,
@@ -132,9 +164,7 @@ func (c *Compiler) compileAllocNode(v *parser.AllocNode) {
 			glob.Init = constant.NewZeroInitializer(llvmVal.Type())
 			allVal = glob
 		} else {
-			alloc := c.contextBlock.NewAlloca(llvmVal.Type())
-			alloc.SetName(name.Var(v.Name[valIndex]))
-			allVal = alloc
+			allVal = c.allocVar(v, v.Name[valIndex], llvmVal.Type())
 		}
 
 		c.contextBlock.NewStore(llvmVal, allVal)
@@ -156,13 +186,31 @@ func (c *Compiler) compileAllocNode(v *parser.AllocNode) {
 	return
 }
 
+// allocVar returns the memory of a new variable of type t. It's allocated on
+// the stack, or on the heap if a pointer to the variable outlives the function.
+func (c *Compiler) allocVar(v *parser.AllocNode, varName string, t irTypes.Type) llvmValue.Value {
+	if v.AddressEscapes {
+		mem := c.contextBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), internal.SizeOf(t))
+		alloc := c.contextBlock.NewBitCast(mem, irTypes.NewPointer(t))
+		alloc.SetName(name.Var(varName))
+		return alloc
+	}
+
+	alloc := c.entryBlockAlloca(t)
+	alloc.SetName(name.Var(varName))
+	return alloc
+}
+
 func (c *Compiler) compileAllocConstNode(v *parser.AllocNode) {
 	for i, varName := range v.Name {
-		cnst := v.Val[i].(*parser.ConstantNode)
-		c.setVar(varName, value.Value{
-			Type:  &types.UntypedConstantNumber{},
-			Value: constant.NewInt(i64.LLVM().(*irTypes.IntType), cnst.Value),
-		})
+		val := c.compileConstantExpression(v.Val[i])
+
+		// Typed constants, such as "const a int32 = 1"
+		if v.Type != nil {
+			val = constantWithType(val, value.Value{Type: c.parserTypeToType(v.Type)})
+		}
+
+		c.setVar(varName, val)
 	}
 }
 
@@ -186,12 +234,18 @@ func (c *Compiler) compileAssignNode(v *parser.AssignNode) {
 		return
 	}
 
+	// Assign the values returned from a function call with multiple return values
+	if len(v.Val) == 1 {
+		c.compileMultiAssign(v.Target, c.compileValue(v.Val[0]))
+		return
+	}
+
 	for i := range v.Target {
 		target := v.Target[i]
 
 		// Assignment to _, do nothing.
 		if nameNode, ok := target.(*parser.NameNode); ok && nameNode.Name == "_" {
-			return
+			continue
 		}
 
 		dst := c.compileValue(target)
@@ -206,18 +260,68 @@ func (c *Compiler) compileAssignNode(v *parser.AssignNode) {
 
 		singleAssignVal := c.compileSingleAssign(dst.Type, dst, v.Val[i])
 
-		tmpStore := c.contextBlock.NewAlloca(llvmType)
+		tmpStore := c.entryBlockAlloca(llvmType)
 		c.contextBlock.NewStore(singleAssignVal, tmpStore)
 		tmpStores[i] = tmpStore
 		realTargets[i] = dst
 	}
 
 	for i := range v.Target {
+		if tmpStores[i] == nil {
+			continue
+		}
 		x := c.contextBlock.NewLoad(pointer.ElemType(tmpStores[i]), tmpStores[i])
 		c.contextBlock.NewStore(x, realTargets[i].Value)
 	}
 }
 
+// compileOperatorAssignNode compiles compound assignments, such as "a[i] += 1"
+// and "*p <<= 2". The address of the target is only evaluated once, so that
+// index expressions and function calls in the target have no repeated side
+// effects.
+func (c *Compiler) compileOperatorAssignNode(v *parser.OperatorAssignNode) {
+	dst := c.compileValue(v.Target)
+	if !dst.IsVariable {
+		compilePanic("Can only assign to variable")
+	}
+
+	// The current value of the target is loaded through a temporary name
+	tmpName := name.Var("operator-assign")
+	c.pushVariablesStack()
+	c.contextBlockVariables[len(c.contextBlockVariables)-1][tmpName] = dst
+	res := c.compileOperatorNode(&parser.OperatorNode{
+		Operator: v.Operator,
+		Left:     &parser.NameNode{Name: tmpName},
+		Right:    v.Val,
+	})
+	c.popVariablesStack()
+
+	c.contextBlock.NewStore(internal.LoadIfVariable(c.contextBlock, res), dst.Value)
+}
+
+// compileMultiAssign assigns the values of a multi value, such as the result of
+// a function call, to the targets
+func (c *Compiler) compileMultiAssign(targets []parser.Node, val value.Value) {
+	if _, ok := val.Type.(*types.MultiValue); !ok || len(val.MultiValues) != len(targets) {
+		compilePanic(fmt.Sprintf("assignment mismatch: %d variables but 1 value", len(targets)))
+	}
+
+	for i, target := range targets {
+		// Assignment to _, do nothing.
+		if nameNode, ok := target.(*parser.NameNode); ok && nameNode.Name == "_" {
+			continue
+		}
+
+		dst := c.compileValue(target)
+		if !dst.IsVariable {
+			compilePanic("Can only assign to variable")
+		}
+
+		converted := c.assignableValue(val.MultiValues[i], dst.Type)
+		c.contextBlock.NewStore(internal.LoadIfVariable(c.contextBlock, converted), dst.Value)
+	}
+}
+
 func (c *Compiler) compileSingleAssign(temporaryDst types.Type, realDst value.Value, val parser.Node) llvmValue.Value {
 	// Push assign type stack
 	// Can be used later when evaluating integer constants
@@ -228,7 +332,7 @@ func (c *Compiler) compileSingleAssign(temporaryDst types.Type, realDst value.Va
 	comVal := c.compileValue(val)
 
 	// Cast to interface if needed
-	comVal = c.valueToInterfaceValue(comVal, temporaryDst)
+	comVal = c.assignableValue(comVal, temporaryDst)
 	llvmV := internal.LoadIfVariable(c.contextBlock, comVal)
 
 	// Pop assigng type stack
@@ -236,3 +340,13 @@ func (c *Compiler) compileSingleAssign(temporaryDst types.Type, realDst value.Va
 
 	return llvmV
 }
+
+// entryBlockAlloca allocates stack space in the entry block of the current
+// function. Allocating where the variable is declared would grow the stack on
+// every iteration of a loop.
+func (c *Compiler) entryBlockAlloca(t irTypes.Type) *ir.InstAlloca {
+	entry := c.contextBlock.Parent.Blocks[0]
+	alloc := ir.NewAlloca(t)
+	entry.Insts = append([]ir.Instruction{alloc}, entry.Insts...)
+	return alloc
+}
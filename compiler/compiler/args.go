@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+)
+
+// The arguments and environment of the program are passed to main by the C
+// runtime, and are saved to globals before the packages are initialized. They
+// can be read with external.Argc(), external.Arg(i) and external.Env(i).
+
+// addArgs adds the globals that the arguments are saved to, and the functions
+// that reads them to the external package
+func (c *Compiler) addArgs() {
+	charPtrPtr := llvmTypes.NewPointer(llvmTypes.I8Ptr)
+
+	argcGlobal := c.module.NewGlobalDef("os-argc", constant.NewInt(llvmTypes.I64, 0))
+	argvGlobal := c.module.NewGlobalDef("os-argv", constant.NewNull(charPtrPtr))
+	envpGlobal := c.module.NewGlobalDef("os-envp", constant.NewNull(charPtrPtr))
+	c.argsGlobals = []*ir.Global{argcGlobal, argvGlobal, envpGlobal}
+
+	strlen := c.module.NewFunc("strlen", llvmTypes.I64, ir.NewParam("s", llvmTypes.I8Ptr))
+
+	// cString converts a null terminated string to a string
+	cString := func(block *ir.Block, s llvmValue.Value) llvmValue.Value {
+		var res llvmValue.Value = constant.NewUndef(types.ModuleStringType.(*llvmTypes.StructType))
+		res = block.NewInsertValue(res, block.NewCall(strlen, s), 0)
+		return block.NewInsertValue(res, s, 1)
+	}
+
+	// Argc() int returns the number of arguments, including the program name
+	argcFn := c.module.NewFunc("os-argc-get", llvmTypes.I64)
+	argcBlock := argcFn.NewBlock(name.Block())
+	argcBlock.NewRet(argcBlock.NewLoad(llvmTypes.I64, argcGlobal))
+	c.defineExternalFunc("Argc", argcFn, types.I64, nil)
+
+	// Arg(i int) string returns the i:th argument, 0 <= i < Argc()
+	argFn := c.module.NewFunc("os-arg", types.ModuleStringType, ir.NewParam("i", llvmTypes.I64))
+	argBlock := argFn.NewBlock(name.Block())
+	argPtr := argBlock.NewGetElementPtr(llvmTypes.I8Ptr, argBlock.NewLoad(charPtrPtr, argvGlobal), argFn.Params[0])
+	argBlock.NewRet(cString(argBlock, argBlock.NewLoad(llvmTypes.I8Ptr, argPtr)))
+	c.defineExternalFunc("Arg", argFn, types.String, []types.Type{types.I64})
+
+	// Env(i int) string returns the i:th "key=value" pair of the environment,
+	// or an empty string if there are no more than i pairs. i must not be
+	// larger than the number of pairs.
+	envFn := c.module.NewFunc("os-env", types.ModuleStringType, ir.NewParam("i", llvmTypes.I64))
+	envBlock := envFn.NewBlock(name.Block())
+	envFoundBlock := envFn.NewBlock(name.Block())
+	envEndBlock := envFn.NewBlock(name.Block())
+	envPtr := envBlock.NewGetElementPtr(llvmTypes.I8Ptr, envBlock.NewLoad(charPtrPtr, envpGlobal), envFn.Params[0])
+	env := envBlock.NewLoad(llvmTypes.I8Ptr, envPtr)
+	envBlock.NewCondBr(envBlock.NewICmp(enum.IPredEQ, env, constant.NewNull(llvmTypes.I8Ptr)), envEndBlock, envFoundBlock)
+	envFoundBlock.NewRet(cString(envFoundBlock, env))
+	envEndBlock.NewRet(c.constantString(""))
+	c.defineExternalFunc("Env", envFn, types.String, []types.Type{types.I64})
+}
+
+// mainParams returns the argc, argv and envp parameters of main
+func mainParams() []*ir.Param {
+	charPtrPtr := llvmTypes.NewPointer(llvmTypes.I8Ptr)
+	return []*ir.Param{
+		ir.NewParam("argc", llvmTypes.I32),
+		ir.NewParam("argv", charPtrPtr),
+		ir.NewParam("envp", charPtrPtr),
+	}
+}
+
+// saveArgs saves the parameters of main to the globals that are read by the
+// functions in the external package
+func (c *Compiler) saveArgs(mainBlock *ir.Block) {
+	argc, argv, envp := c.mainFunc.Params[0], c.mainFunc.Params[1], c.mainFunc.Params[2]
+	mainBlock.NewStore(mainBlock.NewSExt(argc, llvmTypes.I64), c.argsGlobals[0])
+	mainBlock.NewStore(argv, c.argsGlobals[1])
+	mainBlock.NewStore(envp, c.argsGlobals[2])
+}
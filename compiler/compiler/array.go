@@ -41,15 +41,13 @@ func (c *Compiler) compileInitializeArrayWithValues(len uint64, itemType types.T
 		LlvmType: llvmTypes.NewArray(len, itemType.LLVM()),
 	}
 
-	allocArray := c.contextBlock.NewAlloca(arrayType.LLVM())
+	allocArray := c.entryBlockAlloca(arrayType.LLVM())
 	arrayType.Zero(c.contextBlock, allocArray)
 
 	for i, val := range values {
 		dst := c.contextBlock.NewGetElementPtr(pointer.ElemType(allocArray), allocArray, constant.NewInt(llvmTypes.I64, 0), constant.NewInt(llvmTypes.I64, int64(i)))
 		dst.SetName(name.Var("init-arr-value"))
-		c.contextBlock.NewStore(
-			val.Value,
-			dst)
+		c.contextBlock.NewStore(internal.LoadIfVariable(c.contextBlock, val), dst)
 	}
 
 	return value.Value{
@@ -134,7 +132,7 @@ func (c *Compiler) compileLoadArrayElement(v *parser.LoadArrayElement) value.Val
 			// Get backing array
 			arrayValue = c.contextBlock.NewExtractValue(arrayValue, 1)
 			lengthKnownAtRunTime = true
-			retType = types.I8
+			retType = types.U8
 			isLlvmArrayBased = false
 		}
 	}
@@ -3,7 +3,7 @@ package compiler
 import (
 	"github.com/llir/llvm/ir/constant"
 	llvmTypes "github.com/llir/llvm/ir/types"
-	"github.com/zegl/tre/compiler/compiler/internal/pointer"
+	"github.com/zegl/tre/compiler/compiler/internal"
 	"github.com/zegl/tre/compiler/compiler/types"
 	"github.com/zegl/tre/compiler/compiler/value"
 	"github.com/zegl/tre/compiler/parser"
@@ -11,7 +11,7 @@ import (
 
 func (c *Compiler) compileNegateBoolNode(v *parser.NegateNode) value.Value {
 	val := c.compileValue(v.Item)
-	loadedVal := c.contextBlock.NewLoad(pointer.ElemType(val.Value), val.Value)
+	loadedVal := internal.LoadIfVariable(c.contextBlock, val)
 
 	return value.Value{
 		Type:       types.Bool,
@@ -2,7 +2,7 @@ package compiler
 
 import (
 	"fmt"
-	"runtime"
+	"path/filepath"
 	"runtime/debug"
 
 	"github.com/zegl/tre/compiler/compiler/internal"
@@ -16,6 +16,7 @@ import (
 
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
 	llvmTypes "github.com/llir/llvm/ir/types"
 	llvmValue "github.com/llir/llvm/ir/value"
 )
@@ -31,11 +32,26 @@ type Compiler struct {
 
 	// TODO: Replace with currentPackage.Name()
 	currentPackageName string
+	currentPackagePath string
 
 	contextFunc *types.Function
 
+	// Name of the function that is being compiled, such as "main.main"
+	contextFuncName string
+
+	// The file that is being compiled, and the line numbers of its statements
+	contextFilePath  string
+	contextFileLines map[parser.Node]int
+
+	// Import paths of the packages that the current file imports, by name
+	contextFileImports map[string]string
+
+	// Line of the statement that is being compiled
+	contextLine int
+
 	initGlobalsFunc *ir.Func
 	mainFunc        *ir.Func
+	argsGlobals     []*ir.Global // argc, argv and envp, see addArgs
 
 	// Stack of return values pointers, is used both used if a function returns more
 	// than one value (arg pointers), and single stack based returns
@@ -50,6 +66,9 @@ type Compiler struct {
 	contextLoopBreak    []*ir.Block
 	contextLoopContinue []*ir.Block
 
+	// Labels in the current function, by name
+	contextLabels map[string]*label
+
 	// Where a condition should jump when done
 	contextCondAfter []*ir.Block
 
@@ -63,8 +82,30 @@ type Compiler struct {
 
 	stringConstants map[string]*ir.Global
 
-	// runtime.GOOS and runtime.GOARCH
+	// The target platform, in the same format as runtime.GOOS and runtime.GOARCH
 	GOOS, GOARCH string
+
+	// Is nil if debug information is not enabled
+	debug *debugInfo
+
+	stackTrace stackTrace
+
+	typeInfo typeInfo
+
+	// Runtime helpers for strings, see addStringFuncs
+	stringCompareFunc *ir.Func
+	stringConcatFunc  *ir.Func
+	stringCStrFunc    *ir.Func
+
+	// Number of anonymous functions in each function, is used to name them
+	anonFuncCount map[string]int
+
+	// The functions that are called by method values, see methodValueFunc
+	methodValueFuncs map[*types.Method]*ir.Func
+
+	// The functions that are called by method values of interface methods, by
+	// the type of the jump function, see interfaceMethodValueFunc
+	interfaceMethodValueFuncs map[string]*ir.Func
 }
 
 var (
@@ -73,7 +114,17 @@ var (
 	i64 = types.I64
 )
 
+// NewCompiler creates a compiler that generates code for the host platform
 func NewCompiler() *Compiler {
+	return NewCompilerForTarget(HostTarget())
+}
+
+// NewCompilerForTarget creates a compiler that generates code for target
+func NewCompilerForTarget(target Target) *Compiler {
+	if err := target.Validate(); err != nil {
+		panic(err)
+	}
+
 	c := &Compiler{
 		module: ir.NewModule(),
 
@@ -90,42 +141,29 @@ func NewCompiler() *Compiler {
 		contextAssignDest: make([]value.Value, 0),
 
 		stringConstants: make(map[string]*ir.Global),
-	}
 
-	c.createExternalPackage()
-	c.addGlobal()
-	c.pushVariablesStack()
+		anonFuncCount: make(map[string]int),
 
-	// Triple examples:
-	// x86_64-apple-macosx10.13.0
-	// x86_64-pc-linux-gnu
-	var targetTriple [2]string
-
-	switch runtime.GOARCH {
-	case "amd64":
-		targetTriple[0] = "x86_64"
-	case "arm64":
-		targetTriple[0] = "aarch64"
-	default:
-		panic("unsupported GOARCH: " + runtime.GOARCH)
-	}
+		methodValueFuncs:          make(map[*types.Method]*ir.Func),
+		interfaceMethodValueFuncs: make(map[string]*ir.Func),
 
-	switch runtime.GOOS {
-	case "darwin":
-		targetTriple[1] = "apple-macosx10.13.0"
-	case "linux":
-		targetTriple[1] = "pc-linux-gnu"
-	case "windows":
-		targetTriple[1] = "pc-windows"
-	default:
-		panic("unsupported GOOS: " + runtime.GOOS)
+		GOOS:   target.GOOS,
+		GOARCH: target.GOARCH,
 	}
 
-	c.module.TargetTriple = fmt.Sprintf("%s-%s", targetTriple[0], targetTriple[1])
+	c.module.TargetTriple = target.Triple()
+	c.module.DataLayout = target.DataLayout()
 
-	// TODO: Allow cross compilation
-	c.GOOS = runtime.GOOS
-	c.GOARCH = runtime.GOARCH
+	c.createExternalPackage()
+	c.addTypeInfo()
+	c.addStackTrace()
+	c.addGlobal()
+	c.addStringFuncs()
+	c.addReflection()
+	c.addSyscalls()
+	c.addFloatFuncs()
+	c.addSyncFuncs()
+	c.pushVariablesStack()
 
 	return c
 }
@@ -149,16 +187,81 @@ func (c *Compiler) Compile(root parser.PackageNode) (err error) {
 
 	c.currentPackage = NewPkg(root.Name)
 	c.currentPackageName = root.Name
-	c.packages[c.currentPackageName] = c.currentPackage
+	c.currentPackagePath = root.Path
+	if c.currentPackagePath == "" {
+		c.currentPackagePath = root.Name
+	}
+	c.packages[c.currentPackagePath] = c.currentPackage
 
 	for _, fileNode := range root.Files {
+		c.contextFilePath = fileNode.Path
+		c.contextFileLines = fileNode.Lines
+		c.contextFileImports = fileImports(fileNode)
+		c.beginDebugFile(fileNode)
 		c.compile(fileNode.Instructions)
 	}
 
 	return
 }
 
+// HasPackage reports whether the package with this import path has already
+// been compiled
+func (c *Compiler) HasPackage(path string) bool {
+	_, ok := c.packages[path]
+	return ok
+}
+
+// fileImports returns the import paths of the packages that are imported by
+// file, by the name that they are used with
+func fileImports(file parser.FileNode) map[string]string {
+	imports := make(map[string]string)
+	for _, ins := range file.Instructions {
+		if importNode, ok := ins.(*parser.ImportNode); ok {
+			for _, path := range importNode.PackagePaths {
+				imports[filepath.Base(path)] = path
+			}
+		}
+	}
+	return imports
+}
+
+// importedPackage returns the package that is used with pkgName in the
+// current file
+func (c *Compiler) importedPackage(pkgName string) (*pkg, bool) {
+	if path, ok := c.contextFileImports[pkgName]; ok {
+		pkgName = path
+	}
+	p, ok := c.packages[pkgName]
+	return p, ok
+}
+
+// BuildLibrary prepares the module to be linked into a program that is not
+// written in tre (a c-archive). The main function is removed, and the package
+// initialization is instead executed as a constructor when the program starts.
+func (c *Compiler) BuildLibrary() {
+	var funcs []*ir.Func
+	for _, fn := range c.module.Funcs {
+		if fn != c.mainFunc {
+			funcs = append(funcs, fn)
+		}
+	}
+	c.module.Funcs = funcs
+
+	ctorType := llvmTypes.NewStruct(llvmTypes.I32, llvmTypes.NewPointer(c.initGlobalsFunc.Sig), llvmTypes.I8Ptr)
+	ctors := c.module.NewGlobalDef("llvm.global_ctors", constant.NewArray(
+		llvmTypes.NewArray(1, ctorType),
+		constant.NewStruct(ctorType,
+			constant.NewInt(llvmTypes.I32, 65535),
+			c.initGlobalsFunc,
+			constant.NewNull(llvmTypes.I8Ptr),
+		),
+	))
+	ctors.Linkage = enum.LinkageAppending
+}
+
 func (c *Compiler) GetIR() string {
+	c.finalizeTypeInfo()
+	c.finalizeStackTrace()
 	return c.module.String()
 }
 
@@ -183,7 +286,7 @@ func (c *Compiler) addGlobal() {
 	})
 
 	global.DefinePkgType("bool", types.Bool)
-	global.DefinePkgType("int", types.I64) // TODO: Size based on arch
+	global.DefinePkgType("int", types.I64) // All supported targets are 64 bit
 	global.DefinePkgType("int8", types.I8)
 	global.DefinePkgType("uint8", types.U8)
 	global.DefinePkgType("int16", types.I16)
@@ -192,8 +295,19 @@ func (c *Compiler) addGlobal() {
 	global.DefinePkgType("uint32", types.U32)
 	global.DefinePkgType("int64", types.I64)
 	global.DefinePkgType("uint64", types.U64)
+	global.DefinePkgType("uint", types.U64) // All supported targets are 64 bit
 	global.DefinePkgType("uintptr", types.Uintptr)
+	global.DefinePkgType("float32", types.F32)
+	global.DefinePkgType("float64", types.F64)
 	global.DefinePkgType("string", types.String)
+	global.DefinePkgType("byte", types.U8)
+	global.DefinePkgType("rune", types.I32)
+	global.DefinePkgType("error", &types.Interface{
+		SourceName: "error",
+		RequiredMethods: map[string]types.InterfaceMethod{
+			"Error": {ReturnTypes: []types.Type{types.String}},
+		},
+	})
 
 	c.packages["global"] = global
 
@@ -205,13 +319,17 @@ func (c *Compiler) addGlobal() {
 	b.NewRet(nil)
 
 	// main.main function, body will be added later
-	c.mainFunc = c.module.NewFunc("main", types.I32.LLVM())
+	c.addArgs()
+	c.mainFunc = c.module.NewFunc("main", types.I32.LLVM(), mainParams()...)
 	mainBlock := c.mainFunc.NewBlock(name.Block())
+	c.saveArgs(mainBlock)
 	mainBlock.NewCall(c.initGlobalsFunc)
 }
 
 func (c *Compiler) compile(instructions []parser.Node) {
 	for _, i := range instructions {
+		line := c.beginStatement(i)
+
 		switch v := i.(type) {
 		case *parser.ConditionNode:
 			c.compileConditionNode(v)
@@ -227,12 +345,18 @@ func (c *Compiler) compile(instructions []parser.Node) {
 			}
 		case *parser.AssignNode:
 			c.compileAssignNode(v)
+		case *parser.OperatorAssignNode:
+			c.compileOperatorAssignNode(v)
 		case *parser.ForNode:
-			c.compileForNode(v)
+			c.compileForNode(v, nil)
 		case *parser.BreakNode:
 			c.compileBreakNode(v)
 		case *parser.ContinueNode:
 			c.compileContinueNode(v)
+		case *parser.LabelNode:
+			c.compileLabelNode(v)
+		case *parser.GotoNode:
+			c.compileGotoNode(v)
 
 		case *parser.DeclarePackageNode:
 			// TODO: Make use of it
@@ -247,20 +371,59 @@ func (c *Compiler) compile(instructions []parser.Node) {
 			// Add type to module and override the structtype to use the named
 			// type in the module
 			if structType, ok := t.(*types.Struct); ok {
-				structType.Type = c.module.NewTypeDef(v.Name, t.LLVM())
+				structType.SourceName = c.currentPackageName + "." + v.Name
+				structType.Type = c.module.NewTypeDef(structType.SourceName, t.LLVM())
+			}
+
+			if ifaceType, ok := t.(*types.Interface); ok {
+				ifaceType.SourceName = c.currentPackageName + "." + v.Name
+			}
+
+			// Named numeric types, such as "type Duration int64", are copies
+			// of the underlying type, so that methods are not added to it
+			switch tt := t.(type) {
+			case *types.Int:
+				t = tt.Named(c.currentPackageName + "." + v.Name)
+			case *types.Float:
+				t = tt.Named(c.currentPackageName + "." + v.Name)
 			}
 
 			// Add to tre mapping
 			c.currentPackage.DefinePkgType(v.Name, t)
 
 		case *parser.SwitchNode:
-			c.compileSwitchNode(v)
+			c.compileSwitchNode(v, nil)
 
 		default:
 			c.compileValue(v)
 			break
 		}
+
+		c.endStatement(line)
+	}
+}
+
+// beginStatement is called before a statement is compiled, and returns the line
+// of the statement
+func (c *Compiler) beginStatement(node parser.Node) int {
+	// Instructions that does not have a location yet belongs to the outer statement
+	c.setDebugLocations()
+	c.locateCallSites()
+
+	if line, ok := c.contextFileLines[node]; ok {
+		c.contextLine = line
 	}
+
+	return c.contextLine
+}
+
+// endStatement is called after a statement has been compiled, line is the
+// value returned from beginStatement
+func (c *Compiler) endStatement(line int) {
+	// Restore the line after nested statements
+	c.contextLine = line
+	c.setDebugLocations()
+	c.locateCallSites()
 }
 
 func (c *Compiler) compileNameNode(v *parser.NameNode) value.Value {
@@ -269,7 +432,7 @@ func (c *Compiler) compileNameNode(v *parser.NameNode) value.Value {
 
 	if len(v.Package) > 0 {
 		// Imported package?
-		if p, ok := c.packages[v.Package]; ok {
+		if p, ok := c.importedPackage(v.Package); ok {
 			pkg = p
 			inSamePackage = false
 		} else {
@@ -288,11 +451,42 @@ func (c *Compiler) compileNameNode(v *parser.NameNode) value.Value {
 		return pkgVar
 	}
 
+	// nil can be shadowed, and is only used if no variable has that name
+	if v.Name == "nil" && len(v.Package) == 0 {
+		return value.Value{Type: &types.UntypedNil{}}
+	}
+
 	panic(fmt.Sprintf("package %s has no memeber %s", v.Package, v.Name))
 }
 
+// isTypeName reports whether v refers to a type, and not to a variable
+func (c *Compiler) isTypeName(v *parser.NameNode) bool {
+	pkg := c.currentPackage
+	if len(v.Package) > 0 {
+		p, ok := c.importedPackage(v.Package)
+		if !ok {
+			return false
+		}
+		pkg = p
+	} else {
+		for i := len(c.contextBlockVariables) - 1; i >= 0; i-- {
+			if _, ok := c.contextBlockVariables[i][v.Name]; ok {
+				return false
+			}
+		}
+	}
+
+	inSamePackage := len(v.Package) == 0
+	if _, ok := pkg.GetPkgVar(v.Name, inSamePackage); ok {
+		return false
+	}
+	_, ok := pkg.GetPkgType(v.Name, inSamePackage)
+	return ok
+}
+
 func (c *Compiler) setVar(name string, val value.Value) {
 	c.contextBlockVariables[len(c.contextBlockVariables)-1][name] = val
+	c.debugVariable(name, val)
 }
 
 func (c *Compiler) pushVariablesStack() {
@@ -313,13 +507,13 @@ func (c *Compiler) compileValue(node parser.Node) value.Value {
 	case *parser.SubNode:
 		return c.compileSubNode(v)
 	case *parser.NameNode:
-		return c.compileNameNode(v)
+		return c.funcValue(c.compileNameNode(v))
 	case *parser.CallNode:
 		return c.compileCallNode(v)
 	case *parser.TypeCastNode:
 		return c.compileTypeCastNode(v)
 	case *parser.StructLoadElementNode:
-		return c.compileStructLoadElementNode(v)
+		return c.funcValue(c.compileStructLoadElementNode(v))
 	case *parser.LoadArrayElement:
 		return c.compileLoadArrayElement(v)
 	case *parser.GetReferenceNode:
@@ -337,13 +531,17 @@ func (c *Compiler) compileValue(node parser.Node) value.Value {
 			return c.compileSubstring(src, v)
 		}
 
+		if _, ok := src.Type.(*types.Slice); ok {
+			return c.compileSliceSlice(src, v)
+		}
+
 		return c.compileSliceArray(src, v)
 	case *parser.InitializeStructNode:
 		return c.compileInitStructWithValues(v)
 	case *parser.TypeCastInterfaceNode:
 		return c.compileTypeCastInterfaceNode(v)
 	case *parser.DefineFuncNode:
-		return c.compileDefineFuncNode(v)
+		return c.funcValue(c.compileDefineFuncNode(v))
 	case *parser.InitializeArrayNode:
 		return c.compileInitializeArrayNode(v)
 	case *parser.DecrementNode:
@@ -357,10 +555,10 @@ func (c *Compiler) compileValue(node parser.Node) value.Value {
 	panic("compileValue fail: " + fmt.Sprintf("%T: %+v", node, node))
 }
 
+// panic prints message and the stack trace, and exits the program
 func (c *Compiler) panic(block *ir.Block, message string) {
-	globMsg := c.module.NewGlobalDef(strings.NextStringName(), strings.Constant("runtime panic: "+message+"\n"))
-	globMsg.Immutable = true
-	block.NewCall(c.externalFuncs.Printf.Value.(llvmValue.Named), strings.Toi8Ptr(block, globMsg))
+	block.NewCall(c.externalFuncs.Printf.Value.(llvmValue.Named), c.constantCString("runtime panic: "+message+"\n"))
+	c.printPanicTrace(block)
 	block.NewCall(c.externalFuncs.Exit.Value.(llvmValue.Named), constant.NewInt(llvmTypes.I32, 1))
 }
 
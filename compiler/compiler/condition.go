@@ -2,6 +2,7 @@ package compiler
 
 import (
 	"fmt"
+	"math/big"
 
 	"github.com/zegl/tre/compiler/compiler/internal"
 	"github.com/zegl/tre/compiler/compiler/internal/pointer"
@@ -11,6 +12,7 @@ import (
 	"github.com/zegl/tre/compiler/compiler/value"
 	"github.com/zegl/tre/compiler/parser"
 
+	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/enum"
 	llvmTypes "github.com/llir/llvm/ir/types"
@@ -34,10 +36,55 @@ func getConditionLLVMpred(operator parser.Operator) enum.IPred {
 	panic("unknown op: " + string(operator))
 }
 
+// getUnsignedConditionLLVMpred returns the predicate of the comparison
+// operator for unsigned integers
+func getUnsignedConditionLLVMpred(operator parser.Operator) enum.IPred {
+	switch operator {
+	case parser.OP_GT:
+		return enum.IPredUGT
+	case parser.OP_GTEQ:
+		return enum.IPredUGE
+	case parser.OP_LT:
+		return enum.IPredULT
+	case parser.OP_LTEQ:
+		return enum.IPredULE
+	}
+	return getConditionLLVMpred(operator)
+}
+
 func (c *Compiler) compileOperatorNode(v *parser.OperatorNode) value.Value {
+	if v.Operator == parser.OP_LOGICAL_AND || v.Operator == parser.OP_LOGICAL_OR {
+		return c.compileLogicalOperatorNode(v)
+	}
+
 	left := c.compileValue(v.Left)
 	right := c.compileValue(v.Right)
 
+	// Comparisons with nil
+	_, leftIsNil := left.Type.(*types.UntypedNil)
+	_, rightIsNil := right.Type.(*types.UntypedNil)
+	if leftIsNil && rightIsNil {
+		compilePanic("invalid operation: nil " + string(v.Operator) + " nil")
+	}
+	if leftIsNil {
+		return c.compileNilComparison(v.Operator, right)
+	}
+	if rightIsNil {
+		return c.compileNilComparison(v.Operator, left)
+	}
+
+	// Interfaces are compared with the value that they are holding
+	_, leftIsInterface := left.Type.(*types.Interface)
+	_, rightIsInterface := right.Type.(*types.Interface)
+	if leftIsInterface {
+		return c.compileInterfaceComparison(v.Operator, left, c.valueToInterfaceValue(right, left.Type))
+	}
+	if rightIsInterface {
+		return c.compileInterfaceComparison(v.Operator, c.valueToInterfaceValue(left, right.Type), right)
+	}
+
+	isShift := v.Operator == parser.OP_LEFT_SHIFT || v.Operator == parser.OP_RIGHT_SHIFT
+
 	_, rightIsUntyped := right.Type.(*types.UntypedConstantNumber)
 	_, leftIsUntyped := left.Type.(*types.UntypedConstantNumber)
 
@@ -45,54 +92,79 @@ func (c *Compiler) compileOperatorNode(v *parser.OperatorNode) value.Value {
 		right = value.UntypedConstAs(right, left)
 	}
 	if leftIsUntyped && !rightIsUntyped {
-		left = value.UntypedConstAs(left, right)
+		// The shift count does not decide the type of the shifted value
+		if isShift {
+			left = value.UntypedDefault(left)
+			leftIsUntyped = false
+		} else {
+			left = value.UntypedConstAs(left, right)
+		}
+	}
+
+	// Number literals gets the type of the other operand, such as in "f * 2"
+	// where f is a float64
+	_, leftIsConst := left.Value.(constant.Constant)
+	_, rightIsConst := right.Value.(constant.Constant)
+	if leftIsConst && !rightIsConst && !isShift {
+		if cnst, ok := value.ConstantAs(left, right.Type); ok {
+			left = cnst
+		}
+	}
+	if rightIsConst && !leftIsConst {
+		if cnst, ok := value.ConstantAs(right, left.Type); ok {
+			right = cnst
+		}
+	}
+
+	// Integer literals are converted to floats when both operands are
+	// constants, such as in "1.0 / 3". Literals also gets the type of
+	// typed constants, such as in "2 * time.Second".
+	if leftIsConst && rightIsConst {
+		leftIsLiteral, rightIsLiteral := isNumberLiteral(v.Left), isNumberLiteral(v.Right)
+		if leftIsLiteral && !rightIsLiteral && !isShift {
+			if cnst, ok := value.ConstantAs(left, right.Type); ok {
+				left = cnst
+			}
+		}
+		if rightIsLiteral && !leftIsLiteral {
+			if cnst, ok := value.ConstantAs(right, left.Type); ok {
+				right = cnst
+			}
+		}
+
+		_, leftIsFloat := left.Type.(*types.Float)
+		_, rightIsFloat := right.Type.(*types.Float)
+		if leftIsFloat && !rightIsFloat {
+			if cnst, ok := value.ConstantAs(right, left.Type); ok {
+				right = cnst
+			}
+		}
+		if rightIsFloat && !leftIsFloat {
+			if cnst, ok := value.ConstantAs(left, right.Type); ok {
+				left = cnst
+			}
+		}
 	}
 
 	leftLLVM := internal.LoadIfVariable(c.contextBlock, left)
 	rightLLVM := internal.LoadIfVariable(c.contextBlock, right)
 
-	if !leftLLVM.Type().Equal(rightLLVM.Type()) && !rightIsUntyped && !leftIsUntyped {
+	// The shift count can have any integer type
+	if !leftLLVM.Type().Equal(rightLLVM.Type()) && !rightIsUntyped && !leftIsUntyped && !isShift {
 		panic(fmt.Sprintf("Different types in operation: %T and %T (%+v and %+v)", left.Type, right.Type, leftLLVM.Type(), rightLLVM.Type()))
 	}
 
 	switch leftLLVM.Type().Name() {
 	case "string":
 		if v.Operator == parser.OP_ADD {
-			leftLen := c.contextBlock.NewExtractValue(leftLLVM, 0)
-			rightLen := c.contextBlock.NewExtractValue(rightLLVM, 0)
-			sumLen := c.contextBlock.NewAdd(leftLen, rightLen)
-
-			backingArray := c.contextBlock.NewAlloca(i8.LLVM())
-			backingArray.NElems = sumLen
-
-			// Copy left to new backing array
-			c.contextBlock.NewCall(c.externalFuncs.Strcpy.Value.(llvmValue.Named), backingArray, c.contextBlock.NewExtractValue(leftLLVM, 1))
-
-			// Append right to backing array
-			c.contextBlock.NewCall(c.externalFuncs.Strcat.Value.(llvmValue.Named), backingArray, c.contextBlock.NewExtractValue(rightLLVM, 1))
-
-			sType, ok := c.packages["global"].GetPkgType("string", true)
-			if !ok {
-				panic("string type not found")
-			}
-			alloc := c.contextBlock.NewAlloca(sType.LLVM())
-
-			// Save length of the string
-			lenItem := c.contextBlock.NewGetElementPtr(pointer.ElemType(alloc), alloc, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 0))
-			c.contextBlock.NewStore(sumLen, lenItem)
-
-			// Save i8* version of string
-			strItem := c.contextBlock.NewGetElementPtr(pointer.ElemType(alloc), alloc, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 1))
-			c.contextBlock.NewStore(backingArray, strItem)
-
-			return value.Value{
-				Value:      c.contextBlock.NewLoad(pointer.ElemType(alloc), alloc),
-				Type:       types.String,
-				IsVariable: false,
-			}
+			return c.stringConcat(leftLLVM, rightLLVM)
 		}
 
-		panic("string does not implement operation " + v.Operator)
+		return c.compileStringComparison(v.Operator, leftLLVM, rightLLVM)
+	}
+
+	if _, ok := left.Type.(*types.Float); ok {
+		return c.compileFloatOperator(v.Operator, left.Type, leftLLVM, rightLLVM)
 	}
 
 	var opRes llvmValue.Value
@@ -104,12 +176,8 @@ func (c *Compiler) compileOperatorNode(v *parser.OperatorNode) value.Value {
 		opRes = c.contextBlock.NewSub(leftLLVM, rightLLVM)
 	case parser.OP_MUL:
 		opRes = c.contextBlock.NewMul(leftLLVM, rightLLVM)
-	case parser.OP_DIV:
-		if left.Type.IsSigned() {
-			opRes = c.contextBlock.NewSDiv(leftLLVM, rightLLVM) // SDiv == Signed Division
-		} else {
-			opRes = c.contextBlock.NewUDiv(leftLLVM, rightLLVM) // SDiv == Signed Division
-		}
+	case parser.OP_DIV, parser.OP_REMAINDER:
+		opRes = c.compileIntDivision(v.Operator, left.Type, leftLLVM, rightLLVM)
 	case parser.OP_BIT_AND:
 		opRes = c.contextBlock.NewAnd(leftLLVM, rightLLVM)
 	case parser.OP_BIT_OR:
@@ -119,15 +187,17 @@ func (c *Compiler) compileOperatorNode(v *parser.OperatorNode) value.Value {
 	case parser.OP_BIT_CLEAR:
 		not := c.contextBlock.NewXor(rightLLVM, constant.NewInt(rightLLVM.Type().(*llvmTypes.IntType), -1))
 		opRes = c.contextBlock.NewAnd(leftLLVM, not)
-	case parser.OP_LEFT_SHIFT:
-		opRes = c.contextBlock.NewShl(leftLLVM, rightLLVM)
-	case parser.OP_RIGHT_SHIFT:
-		opRes = c.contextBlock.NewLShr(leftLLVM, rightLLVM)
+	case parser.OP_LEFT_SHIFT, parser.OP_RIGHT_SHIFT:
+		opRes = c.compileShift(v.Operator, left.Type, leftLLVM, right.Type, rightLLVM)
 	default:
 		// Boolean operations
+		pred := getConditionLLVMpred(v.Operator)
+		if intType, ok := left.Type.(*types.Int); ok && !intType.IsSigned() {
+			pred = getUnsignedConditionLLVMpred(v.Operator)
+		}
 		return value.Value{
 			Type:       types.Bool,
-			Value:      c.contextBlock.NewICmp(getConditionLLVMpred(v.Operator), leftLLVM, rightLLVM),
+			Value:      c.contextBlock.NewICmp(pred, leftLLVM, rightLLVM),
 			IsVariable: false,
 		}
 	}
@@ -139,10 +209,132 @@ func (c *Compiler) compileOperatorNode(v *parser.OperatorNode) value.Value {
 	}
 }
 
+// compileLogicalOperatorNode compiles && and ||. The right operand is only
+// evaluated if the left operand does not decide the result, such as in
+// "p != nil && p.x > 0".
+func (c *Compiler) compileLogicalOperatorNode(v *parser.OperatorNode) value.Value {
+	left := internal.LoadIfVariable(c.contextBlock, c.compileValue(v.Left))
+	leftBlock := c.contextBlock
+
+	rightBlock := c.contextBlock.Parent.NewBlock(name.Block() + "-logical-right")
+	afterBlock := c.contextBlock.Parent.NewBlock(name.Block() + "-logical-after")
+
+	// The result when the right operand is skipped
+	var shortCircuit constant.Constant
+	if v.Operator == parser.OP_LOGICAL_AND {
+		shortCircuit = constant.False
+		leftBlock.NewCondBr(left, rightBlock, afterBlock)
+	} else {
+		shortCircuit = constant.True
+		leftBlock.NewCondBr(left, afterBlock, rightBlock)
+	}
+
+	// Compiling the right operand can create new blocks, the phi uses the
+	// block that it ended in
+	c.contextBlock = rightBlock
+	right := internal.LoadIfVariable(c.contextBlock, c.compileValue(v.Right))
+	rightEndBlock := c.contextBlock
+	rightEndBlock.NewBr(afterBlock)
+
+	c.contextBlock = afterBlock
+	res := afterBlock.NewPhi(ir.NewIncoming(shortCircuit, leftBlock), ir.NewIncoming(right, rightEndBlock))
+
+	return value.Value{
+		Type:       types.Bool,
+		Value:      res,
+		IsVariable: false,
+	}
+}
+
+// compileNilComparison compares val with nil, operator is either == or !=
+func (c *Compiler) compileNilComparison(operator parser.Operator, val value.Value) value.Value {
+	if operator != parser.OP_EQ && operator != parser.OP_NEQ {
+		compilePanic(fmt.Sprintf("invalid operation: operator %s not defined on nil", operator))
+	}
+
+	llvmVal := internal.LoadIfVariable(c.contextBlock, val)
+
+	var compare llvmValue.Value
+	switch val.Type.(type) {
+	case *types.Interface:
+		// A nil interface does not have a type
+		compare = c.contextBlock.NewExtractValue(llvmVal, 1)
+	case *types.Slice:
+		// A nil slice does not have a backing array
+		compare = c.contextBlock.NewExtractValue(llvmVal, 3)
+	case *types.Pointer:
+		compare = llvmVal
+	case *types.Function:
+		// A nil function value does not have a function
+		compare = c.contextBlock.NewExtractValue(llvmVal, 0)
+	default:
+		compilePanic(fmt.Sprintf("invalid operation: mismatched types %s and nil", val.Type.Name()))
+	}
+
+	return value.Value{
+		Type:       types.Bool,
+		Value:      c.contextBlock.NewICmp(getConditionLLVMpred(operator), compare, constant.NewZeroInitializer(compare.Type())),
+		IsVariable: false,
+	}
+}
+
+// compileInterfaceComparison compares two interface values of the same type.
+// They are equal if they are holding values of the same type, that are equal.
+func (c *Compiler) compileInterfaceComparison(operator parser.Operator, left, right value.Value) value.Value {
+	if operator != parser.OP_EQ && operator != parser.OP_NEQ {
+		compilePanic(fmt.Sprintf("invalid operation: operator %s not defined on interface", operator))
+	}
+
+	leftLLVM := internal.LoadIfVariable(c.contextBlock, left)
+	rightLLVM := internal.LoadIfVariable(c.contextBlock, right)
+
+	var res llvmValue.Value = c.contextBlock.NewCall(c.typeInfo.equalFunc,
+		c.contextBlock.NewExtractValue(leftLLVM, 0),
+		c.contextBlock.NewExtractValue(leftLLVM, 1),
+		c.contextBlock.NewExtractValue(rightLLVM, 0),
+		c.contextBlock.NewExtractValue(rightLLVM, 1),
+	)
+
+	if operator == parser.OP_NEQ {
+		res = c.contextBlock.NewXor(res, constant.True)
+	}
+
+	return value.Value{
+		Type:       types.Bool,
+		Value:      res,
+		IsVariable: false,
+	}
+}
+
 func (c *Compiler) compileSubNode(v *parser.SubNode) value.Value {
 	right := c.compileValue(v.Item)
 	rVal := internal.LoadIfVariable(c.contextBlock, right)
 
+	// Negated constants are still constants, so that they can be converted
+	// to the type of the other operand, such as in "-1 / f"
+	if cnst, ok := rVal.(*constant.Int); ok {
+		return value.Value{
+			Value:      &constant.Int{Typ: cnst.Typ, X: new(big.Int).Neg(cnst.X)},
+			Type:       right.Type,
+			IsVariable: false,
+		}
+	}
+	if cnst, ok := rVal.(*constant.Float); ok {
+		return value.Value{
+			Value:      &constant.Float{Typ: cnst.Typ, X: new(big.Float).Neg(cnst.X)},
+			Type:       right.Type,
+			IsVariable: false,
+		}
+	}
+
+	if _, ok := right.Type.(*types.Float); ok {
+		return value.Value{
+			Value:      c.contextBlock.NewFNeg(rVal),
+			Type:       right.Type,
+			IsVariable: false,
+		}
+	}
+
 	res := c.contextBlock.NewSub(
 		constant.NewInt(rVal.Type().(*llvmTypes.IntType), 0),
 		rVal,
@@ -159,6 +351,11 @@ func (c *Compiler) compileConditionNode(v *parser.ConditionNode) {
 
 	c.pushVariablesStack()
 
+	// Variables from the init statement are also available in the else branches
+	if v.Init != nil {
+		c.compile([]parser.Node{v.Init})
+	}
+
 	cond := c.compileOperatorNode(v.Cond)
 
 	afterBlock := c.contextBlock.Parent.NewBlock(name.Block() + "-after")
@@ -178,8 +375,8 @@ func (c *Compiler) compileConditionNode(v *parser.ConditionNode) {
 	c.compile(v.True)
 
 	// Jump to after-block if no terminator has been set (such as a return statement)
-	if trueBlock.Term == nil {
-		trueBlock.NewBr(afterBlock)
+	if c.contextBlock.Term == nil {
+		c.contextBlock.NewBr(afterBlock)
 	}
 
 	if len(v.False) > 0 {
@@ -187,8 +384,8 @@ func (c *Compiler) compileConditionNode(v *parser.ConditionNode) {
 		c.compile(v.False)
 
 		// Jump to after-block if no terminator has been set (such as a return statement)
-		if falseBlock.Term == nil {
-			falseBlock.NewBr(afterBlock)
+		if c.contextBlock.Term == nil {
+			c.contextBlock.NewBr(afterBlock)
 		}
 	}
 
@@ -234,3 +431,16 @@ func (c *Compiler) compileIncrementNode(v *parser.IncrementNode) value.Value {
 func (c *Compiler) compileGroupNode(v *parser.GroupNode) value.Value {
 	return c.compileValue(v.Item)
 }
+
+// isNumberLiteral reports whether node is a number literal, such as 1 or -2.5
+func isNumberLiteral(node parser.Node) bool {
+	switch n := node.(type) {
+	case *parser.ConstantNode:
+		return n.Type == parser.NUMBER || n.Type == parser.FLOAT
+	case *parser.SubNode:
+		return isNumberLiteral(n.Item)
+	case *parser.GroupNode:
+		return isNumberLiteral(n.Item)
+	}
+	return false
+}
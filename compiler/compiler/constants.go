@@ -1,6 +1,9 @@
 package compiler
 
 import (
+	"fmt"
+	"math/big"
+
 	"github.com/zegl/tre/compiler/compiler/internal/pointer"
 	"github.com/zegl/tre/compiler/compiler/strings"
 	"github.com/zegl/tre/compiler/compiler/types"
@@ -10,6 +13,7 @@ import (
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
 )
 
 func (c *Compiler) compileConstantNode(v *parser.ConstantNode) value.Value {
@@ -29,29 +33,43 @@ func (c *Compiler) compileConstantNode(v *parser.ConstantNode) value.Value {
 			intType = t
 		}
 
+		// Numbers can also be used as floats, such as in "var f float64 = 1"
+		if t, ok := wantedType.(*types.Float); ok {
+			return value.Value{
+				Value:      t.Constant(float64(v.Value)),
+				Type:       t,
+				IsVariable: false,
+			}
+		}
+
 		return value.Value{
 			Value:      constant.NewInt(intType.Type, v.Value),
 			Type:       intType,
 			IsVariable: false,
 		}
 
-	case parser.STRING:
-		var constString *ir.Global
+	case parser.FLOAT:
+		floatType := types.F64
+		if len(c.contextAssignDest) > 0 {
+			if t, ok := c.contextAssignDest[len(c.contextAssignDest)-1].Type.(*types.Float); ok {
+				floatType = t
+			}
+		}
 
-		// Reuse the *ir.Global if it has already been created
-		if reusedConst, ok := c.stringConstants[v.ValueStr]; ok {
-			constString = reusedConst
-		} else {
-			constString = c.module.NewGlobalDef(strings.NextStringName(), strings.Constant(v.ValueStr))
-			constString.Immutable = true
-			c.stringConstants[v.ValueStr] = constString
+		return value.Value{
+			Value:      floatType.Constant(v.ValueFloat),
+			Type:       floatType,
+			IsVariable: false,
 		}
 
+	case parser.STRING:
+		constString := c.stringGlobal(v.ValueStr)
+
 		sType, ok := c.packages["global"].GetPkgType("string", true)
 		if !ok {
 			panic("string type not found")
 		}
-		alloc := c.contextBlock.NewAlloca(sType.LLVM())
+		alloc := c.entryBlockAlloca(sType.LLVM())
 
 		// Save length of the string
 		lenItem := c.contextBlock.NewGetElementPtr(pointer.ElemType(alloc), alloc, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 0))
@@ -78,3 +96,187 @@ func (c *Compiler) compileConstantNode(v *parser.ConstantNode) value.Value {
 		panic("Unknown constant Type")
 	}
 }
+
+// stringGlobal returns the global that holds the bytes of a string constant.
+// The global is reused if the same string has been used before.
+func (c *Compiler) stringGlobal(str string) *ir.Global {
+	if reusedConst, ok := c.stringConstants[str]; ok {
+		return reusedConst
+	}
+	constString := c.module.NewGlobalDef(strings.NextStringName(), strings.Constant(str))
+	constString.Immutable = true
+	c.stringConstants[str] = constString
+	return constString
+}
+
+// compileConstantExpression evaluates the value of a constant declaration,
+// such as "1 << 63" or "-Pi / 2". Integers are evaluated without overflow,
+// and the result is an untyped constant number.
+func (c *Compiler) compileConstantExpression(node parser.Node) value.Value {
+	untyped := func(v llvmValue.Value) value.Value {
+		return value.Value{Type: &types.UntypedConstantNumber{}, Value: v}
+	}
+
+	switch v := node.(type) {
+	case *parser.ConstantNode:
+		switch v.Type {
+		case parser.NUMBER:
+			return untyped(constant.NewInt(llvmTypes.I64, v.Value))
+		case parser.FLOAT:
+			return untyped(constant.NewFloat(llvmTypes.Double, v.ValueFloat))
+		case parser.BOOL:
+			return value.Value{Type: types.Bool, Value: constant.NewInt(llvmTypes.I1, v.Value)}
+		case parser.STRING:
+			glob := c.stringGlobal(v.ValueStr)
+			ptr := constant.NewGetElementPtr(glob.ContentType, glob, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 0))
+			str := constant.NewStruct(types.ModuleStringType.(*llvmTypes.StructType), constant.NewInt(llvmTypes.I64, int64(len(v.ValueStr))), ptr)
+			return value.Value{Type: types.String, Value: str}
+		}
+
+	case *parser.GroupNode:
+		return c.compileConstantExpression(v.Item)
+
+	case *parser.SubNode:
+		item := c.compileConstantExpression(v.Item)
+		switch cnst := item.Value.(type) {
+		case *constant.Int:
+			return constantWithType(untyped(&constant.Int{Typ: cnst.Typ, X: new(big.Int).Neg(cnst.X)}), item)
+		case *constant.Float:
+			return constantWithType(untyped(&constant.Float{Typ: cnst.Typ, X: new(big.Float).Neg(cnst.X)}), item)
+		}
+
+	case *parser.NameNode:
+		// Other constants
+		val := c.compileValue(v)
+		if _, ok := val.Value.(constant.Constant); ok && !val.IsVariable {
+			return val
+		}
+
+	case *parser.OperatorNode:
+		leftVal := c.compileConstantExpression(v.Left)
+		rightVal := c.compileConstantExpression(v.Right)
+		left, right := leftVal.Value, rightVal.Value
+
+		// The result has the type of the typed operand, if there is one. The
+		// type of a shift is the type of the left operand.
+		typedVal := leftVal
+		if _, ok := leftVal.Type.(*types.UntypedConstantNumber); ok && v.Operator != parser.OP_LEFT_SHIFT && v.Operator != parser.OP_RIGHT_SHIFT {
+			typedVal = rightVal
+		}
+
+		leftInt, leftIsInt := left.(*constant.Int)
+		rightInt, rightIsInt := right.(*constant.Int)
+		if leftIsInt && rightIsInt {
+			x, y := leftInt.X, rightInt.X
+			res := new(big.Int)
+			switch v.Operator {
+			case parser.OP_ADD:
+				res.Add(x, y)
+			case parser.OP_SUB:
+				res.Sub(x, y)
+			case parser.OP_MUL:
+				res.Mul(x, y)
+			case parser.OP_DIV:
+				if y.Sign() == 0 {
+					compilePanic("invalid operation: division by zero")
+				}
+				res.Quo(x, y)
+			case parser.OP_REMAINDER:
+				if y.Sign() == 0 {
+					compilePanic("invalid operation: division by zero")
+				}
+				res.Rem(x, y)
+			case parser.OP_BIT_AND:
+				res.And(x, y)
+			case parser.OP_BIT_OR:
+				res.Or(x, y)
+			case parser.OP_BIT_XOR:
+				res.Xor(x, y)
+			case parser.OP_BIT_CLEAR:
+				res.AndNot(x, y)
+			case parser.OP_LEFT_SHIFT:
+				res.Lsh(x, uint(y.Uint64()))
+			case parser.OP_RIGHT_SHIFT:
+				res.Rsh(x, uint(y.Uint64()))
+			default:
+				compilePanic(fmt.Sprintf("invalid operation: operator %s in constant expression", v.Operator))
+			}
+			return constantWithType(untyped(&constant.Int{Typ: llvmTypes.I64, X: res}), typedVal)
+		}
+
+		// Integers are converted to floats if any of the operands is a float
+		x, xOk := constantFloat(left)
+		y, yOk := constantFloat(right)
+		if xOk && yOk {
+			res := new(big.Float)
+			switch v.Operator {
+			case parser.OP_ADD:
+				res.Add(x, y)
+			case parser.OP_SUB:
+				res.Sub(x, y)
+			case parser.OP_MUL:
+				res.Mul(x, y)
+			case parser.OP_DIV:
+				if y.Sign() == 0 {
+					compilePanic("invalid operation: division by zero")
+				}
+				res.Quo(x, y)
+			default:
+				compilePanic(fmt.Sprintf("invalid operation: operator %s not defined on untyped float", v.Operator))
+			}
+			return constantWithType(untyped(&constant.Float{Typ: llvmTypes.Double, X: res}), typedVal)
+		}
+	}
+
+	compilePanic(fmt.Sprintf("%s is not constant", node))
+	return value.Value{}
+}
+
+// isUntypedConstant returns true if node is a constant expression of untyped
+// numbers, that can be evaluated with compileConstantExpression
+func (c *Compiler) isUntypedConstant(node parser.Node) bool {
+	switch v := node.(type) {
+	case *parser.ConstantNode:
+		return v.Type == parser.NUMBER || v.Type == parser.FLOAT
+	case *parser.GroupNode:
+		return c.isUntypedConstant(v.Item)
+	case *parser.SubNode:
+		return c.isUntypedConstant(v.Item)
+	case *parser.NameNode:
+		_, ok := c.compileValue(v).Type.(*types.UntypedConstantNumber)
+		return ok
+	case *parser.OperatorNode:
+		switch v.Operator {
+		case parser.OP_GT, parser.OP_GTEQ, parser.OP_LT, parser.OP_LTEQ, parser.OP_EQ, parser.OP_NEQ,
+			parser.OP_LOGICAL_AND, parser.OP_LOGICAL_OR:
+			return false
+		}
+		return c.isUntypedConstant(v.Left) && c.isUntypedConstant(v.Right)
+	}
+	return false
+}
+
+// constantWithType converts the untyped constant val to the type of typed,
+// if typed is a typed constant, such as in "2 * time.Second"
+func constantWithType(val, typed value.Value) value.Value {
+	switch typed.Type.(type) {
+	case *types.Int, *types.Float:
+		res, ok := value.ConstantAs(val, typed.Type)
+		if !ok {
+			compilePanic(fmt.Sprintf("constant %s overflows %s", val.Value.Ident(), typed.Type.Name()))
+		}
+		return res
+	}
+	return val
+}
+
+// constantFloat returns the value of an integer or float constant as a float
+func constantFloat(v llvmValue.Value) (*big.Float, bool) {
+	switch cnst := v.(type) {
+	case *constant.Int:
+		return new(big.Float).SetInt(cnst.X), true
+	case *constant.Float:
+		return cnst.X, true
+	}
+	return nil, false
+}
@@ -0,0 +1,580 @@
+package compiler
+
+import (
+	"go/token"
+	"path/filepath"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	llvmTypes "github.com/llir/llvm/ir/types"
+
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/compiler/value"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// debugInfo is the state used when generating DWARF debug information
+type debugInfo struct {
+	compileUnit *metadata.DICompileUnit
+
+	files map[string]*metadata.DIFile
+
+	// The file that is currently being compiled
+	file *metadata.DIFile
+
+	// The function that is currently being compiled
+	scope debugScope
+
+	// Types that have already been described, basic types are stored by name
+	basicTypes  map[string]metadata.Field
+	structTypes map[*types.Struct]metadata.Field
+
+	declareFunc *ir.Func
+	valueFunc   *ir.Func
+	expression  *metadata.DIExpression
+
+	// derefExpression describes a variable that is stored in the memory that
+	// the value points to
+	derefExpression *metadata.DIExpression
+}
+
+// debugScope is the debug information of a function
+type debugScope struct {
+	fn         *ir.Func
+	subprogram *metadata.DISubprogram
+
+	// Locations in the function, one per line
+	locations map[int]*metadata.DILocation
+
+	// The number of instructions in each block that have been given a
+	// location, and the number of blocks that existed the last time
+	located       map[*ir.Block]int
+	locatedBlocks int
+}
+
+// EnableDebugInfo makes the compiler generate DWARF debug information, such as
+// line numbers and variables. path is the path to the main package.
+func (c *Compiler) EnableDebugInfo(path string) {
+	metadataType := llvmTypes.Metadata
+	declareFunc := c.module.NewFunc("llvm.dbg.declare", llvmTypes.Void,
+		ir.NewParam("", metadataType), ir.NewParam("", metadataType), ir.NewParam("", metadataType))
+	valueFunc := c.module.NewFunc("llvm.dbg.value", llvmTypes.Void,
+		ir.NewParam("", metadataType), ir.NewParam("", metadataType), ir.NewParam("", metadataType))
+
+	c.debug = &debugInfo{
+		files:       make(map[string]*metadata.DIFile),
+		basicTypes:  make(map[string]metadata.Field),
+		structTypes: make(map[*types.Struct]metadata.Field),
+		declareFunc: declareFunc,
+		valueFunc:   valueFunc,
+	}
+
+	c.debug.compileUnit = &metadata.DICompileUnit{
+		Distinct:     true,
+		Language:     enum.DwarfLangGo,
+		File:         c.debugFile(path),
+		Producer:     "tre",
+		EmissionKind: enum.EmissionKindFullDebug,
+	}
+	c.debugDef(c.debug.compileUnit)
+
+	c.debug.expression = &metadata.DIExpression{}
+	c.debugDef(c.debug.expression)
+
+	c.debug.derefExpression = &metadata.DIExpression{Fields: []metadata.DIExpressionField{enum.DwarfOpDeref}}
+	c.debugDef(c.debug.derefExpression)
+
+	c.module.NamedMetadataDefs["llvm.dbg.cu"] = &metadata.NamedDef{
+		Name:  "llvm.dbg.cu",
+		Nodes: []metadata.Node{c.debug.compileUnit},
+	}
+
+	// 2 is the "Warning" behaviour, when linking modules with different values
+	moduleFlag := func(name string, val int64) *metadata.Tuple {
+		return c.debugDef(&metadata.Tuple{
+			Fields: []metadata.Field{
+				constant.NewInt(llvmTypes.I32, 2),
+				&metadata.String{Value: name},
+				constant.NewInt(llvmTypes.I32, val),
+			},
+		}).(*metadata.Tuple)
+	}
+
+	c.module.NamedMetadataDefs["llvm.module.flags"] = &metadata.NamedDef{
+		Name: "llvm.module.flags",
+		Nodes: []metadata.Node{
+			moduleFlag("Dwarf Version", 4),
+			moduleFlag("Debug Info Version", 3),
+		},
+	}
+}
+
+// debugDef adds md to the module, it will be assigned an ID when the IR is generated
+func (c *Compiler) debugDef(md metadata.Definition) metadata.Definition {
+	md.SetID(-1)
+	c.module.MetadataDefs = append(c.module.MetadataDefs, md)
+	return md
+}
+
+func (c *Compiler) debugFile(path string) *metadata.DIFile {
+	if f, ok := c.debug.files[path]; ok {
+		return f
+	}
+
+	f := &metadata.DIFile{Filename: "<autogenerated>"}
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		f.Filename = filepath.Base(path)
+		f.Directory = filepath.Dir(path)
+	}
+
+	c.debugDef(f)
+	c.debug.files[path] = f
+	return f
+}
+
+// beginDebugFile is called before the statements in fileNode are compiled
+func (c *Compiler) beginDebugFile(fileNode parser.FileNode) {
+	if c.debug == nil {
+		return
+	}
+
+	c.debug.file = c.debugFile(fileNode.Path)
+}
+
+// beginDebugFunc creates the debug information of a function, and makes it the
+// current scope. The previous scope is returned, and is restored by endDebugFunc.
+func (c *Compiler) beginDebugFunc(funcName string, fn *ir.Func, funcType *types.Function) debugScope {
+	if c.debug == nil {
+		return debugScope{}
+	}
+
+	c.setDebugLocations()
+
+	// The first type is the return type, followed by the parameter types
+	var signature []metadata.Field
+	if _, ok := funcType.LlvmReturnType.(*types.VoidType); ok {
+		signature = append(signature, &metadata.NullLit{})
+	} else {
+		signature = append(signature, c.debugType(funcType.LlvmReturnType))
+	}
+	for _, argType := range funcType.ArgumentTypes {
+		signature = append(signature, c.debugType(argType))
+	}
+
+	subprogram := &metadata.DISubprogram{
+		Distinct:    true,
+		Name:        funcName,
+		LinkageName: fn.Name(),
+		Scope:       c.debug.file,
+		File:        c.debug.file,
+		Line:        int64(c.contextLine),
+		ScopeLine:   int64(c.contextLine),
+		Type: c.debugDef(&metadata.DISubroutineType{
+			Types: c.debugDef(&metadata.Tuple{Fields: signature}).(*metadata.Tuple),
+		}),
+		SPFlags: enum.DISPFlagDefinition,
+		Unit:    c.debug.compileUnit,
+	}
+	c.debugDef(subprogram)
+
+	fn.Metadata = append(fn.Metadata, &metadata.Attachment{Name: "dbg", Node: subprogram})
+
+	prev := c.debug.scope
+	c.debug.scope = debugScope{
+		fn:         fn,
+		subprogram: subprogram,
+		locations:  make(map[int]*metadata.DILocation),
+		located:    make(map[*ir.Block]int),
+	}
+
+	return prev
+}
+
+// endDebugFunc is called when the function has been compiled, prev is the scope
+// returned by beginDebugFunc
+func (c *Compiler) endDebugFunc(prev debugScope) {
+	if c.debug == nil {
+		return
+	}
+
+	c.setDebugLocations()
+
+	// Instructions that was added to other blocks than the current block after
+	// their statement, such as branches to the end of a loop
+	loc := c.debugLocation()
+	for _, block := range c.debug.scope.fn.Blocks {
+		c.setBlockDebugLocations(block, loc)
+	}
+
+	c.debug.scope = prev
+}
+
+// setDebugLocations attaches the line of the current statement to the
+// instructions that has been added since the last statement. Instructions
+// are added to the current block, or to blocks that are new since then.
+func (c *Compiler) setDebugLocations() {
+	if c.debug == nil {
+		return
+	}
+
+	scope := c.debug.scope
+
+	// Not in a function, such as when initializing package variables
+	if scope.fn == nil {
+		return
+	}
+
+	loc := c.debugLocation()
+
+	if c.contextBlock != nil && c.contextBlock.Parent == scope.fn {
+		c.setBlockDebugLocations(c.contextBlock, loc)
+	}
+
+	for _, block := range scope.fn.Blocks[scope.locatedBlocks:] {
+		c.setBlockDebugLocations(block, loc)
+	}
+	c.debug.scope.locatedBlocks = len(scope.fn.Blocks)
+}
+
+// debugLocation returns the location of the current line in the current function
+func (c *Compiler) debugLocation() *metadata.DILocation {
+	scope := c.debug.scope
+
+	loc, ok := scope.locations[c.contextLine]
+	if !ok {
+		loc = &metadata.DILocation{
+			Line:  int64(c.contextLine),
+			Scope: scope.subprogram,
+		}
+		c.debugDef(loc)
+		scope.locations[c.contextLine] = loc
+	}
+
+	return loc
+}
+
+// setBlockDebugLocations attaches loc to the instructions that has been
+// appended to the block since the last time. Allocas that are inserted at the
+// start of the entry block are not given a location.
+func (c *Compiler) setBlockDebugLocations(block *ir.Block, loc *metadata.DILocation) {
+	located := min(c.debug.scope.located[block], len(block.Insts))
+	for _, inst := range block.Insts[located:] {
+		setDebugLocation(inst, loc)
+	}
+	c.debug.scope.located[block] = len(block.Insts)
+
+	if block.Term != nil {
+		setDebugLocation(block.Term, loc)
+	}
+}
+
+// setDebugLocation sets the !dbg attachment of inst, if it does not have one
+func setDebugLocation(inst interface{}, loc *metadata.DILocation) {
+	md := instMetadata(inst)
+	if md == nil || len(*md) > 0 {
+		return
+	}
+	*md = ir.Metadata{{Name: "dbg", Node: loc}}
+}
+
+// debugVariable describes a variable or parameter in the current function.
+// Variables that the compiler has created, such as "panic-arg-1", are not
+// described.
+func (c *Compiler) debugVariable(varName string, val value.Value) {
+	if c.debug == nil || c.debug.scope.fn == nil || varName == "_" || !token.IsIdentifier(varName) {
+		return
+	}
+
+	variable := &metadata.DILocalVariable{
+		Scope: c.debug.scope.subprogram,
+		Name:  varName,
+		File:  c.debug.file,
+		Line:  int64(c.contextLine),
+	}
+
+	var intrinsic *ir.Func
+	expression := c.debug.expression
+
+	_, isPointer := val.Type.(*types.Pointer)
+	llvmType := val.Value.Type()
+
+	switch v := val.Value.(type) {
+	case *ir.Param:
+		if val.IsVariable {
+			return
+		}
+		for i, param := range c.debug.scope.fn.Params {
+			if param == v {
+				variable.Arg = uint64(i + 1)
+			}
+		}
+		if variable.Arg == 0 {
+			return
+		}
+		intrinsic = c.debug.valueFunc
+
+	default:
+		alloca, isAlloca := v.(*ir.InstAlloca)
+
+		switch {
+		// Variables on the stack
+		case val.IsVariable && isAlloca && alloca.ElemType.Equal(val.Type.LLVM()):
+			variable.Arg = c.debugParamIndex(alloca)
+			intrinsic = c.debug.declareFunc
+
+		// Variables on the heap, such as structs that escapes
+		case val.IsVariable && llvmType.Equal(llvmTypes.NewPointer(val.Type.LLVM())):
+			expression = c.debug.derefExpression
+			intrinsic = c.debug.valueFunc
+
+		// Pointers that are not stored in a variable of their own, such as "r := &T{}"
+		case !val.IsVariable && isPointer && llvmType.Equal(val.Type.LLVM()):
+			intrinsic = c.debug.valueFunc
+
+		default:
+			return
+		}
+	}
+
+	variable.Type = c.debugType(val.Type)
+	c.debugDef(variable)
+
+	c.contextBlock.NewCall(intrinsic,
+		&metadata.Value{Value: val.Value},
+		&metadata.Value{Value: variable},
+		&metadata.Value{Value: expression},
+	)
+}
+
+// debugParamIndex returns the position of the parameter that is stored in
+// alloc, or 0 if alloc does not hold a parameter
+func (c *Compiler) debugParamIndex(alloc *ir.InstAlloca) uint64 {
+	for _, inst := range c.contextBlock.Insts {
+		store, ok := inst.(*ir.InstStore)
+		if !ok || store.Dst != alloc {
+			continue
+		}
+		for i, param := range c.debug.scope.fn.Params {
+			if store.Src == param {
+				return uint64(i + 1)
+			}
+		}
+	}
+	return 0
+}
+
+// debugType returns the debug information type of t
+func (c *Compiler) debugType(t types.Type) metadata.Field {
+	switch t.(type) {
+	case *types.Int, *types.BoolType, *types.StringType:
+		if f, ok := c.debug.basicTypes[t.Name()]; ok {
+			return f
+		}
+		f := c.newDebugType(t)
+		c.debug.basicTypes[t.Name()] = f
+		return f
+	}
+
+	return c.newDebugType(t)
+}
+
+func (c *Compiler) newDebugType(t types.Type) metadata.Field {
+	size, align := debugSizeAlign(t.LLVM())
+
+	switch tt := t.(type) {
+	case *types.Int:
+		encoding := enum.DwarfAttEncodingUnsigned
+		if tt.Signed {
+			encoding = enum.DwarfAttEncodingSigned
+		}
+		return c.debugDef(&metadata.DIBasicType{
+			Tag:      enum.DwarfTagBaseType,
+			Name:     tt.TypeName,
+			Size:     size,
+			Encoding: encoding,
+		})
+
+	case *types.BoolType:
+		return c.debugDef(&metadata.DIBasicType{
+			Tag:      enum.DwarfTagBaseType,
+			Name:     "bool",
+			Size:     size,
+			Encoding: enum.DwarfAttEncodingBoolean,
+		})
+
+	case *types.StringType:
+		return c.debugStruct("string", t.LLVM(), []string{"len", "ptr"}, []metadata.Field{
+			c.debugType(types.I64),
+			c.debugPointer(c.debugType(types.U8)),
+		})
+
+	case *types.Slice:
+		return c.debugStruct("[]"+tt.Type.Name(), t.LLVM(), []string{"len", "cap", "offset", "array"}, []metadata.Field{
+			c.debugType(types.I32),
+			c.debugType(types.I32),
+			c.debugType(types.I32),
+			c.debugPointer(c.debugType(tt.Type)),
+		})
+
+	case *types.Pointer:
+		return c.debugPointer(c.debugType(tt.Type))
+
+	case *types.Array:
+		return c.debugDef(&metadata.DICompositeType{
+			Tag:      enum.DwarfTagArrayType,
+			BaseType: c.debugType(tt.Type),
+			Size:     size,
+			Align:    align,
+			Elements: c.debugDef(&metadata.Tuple{
+				Fields: []metadata.Field{
+					c.debugDef(&metadata.DISubrange{Count: metadata.IntLit(tt.Len)}),
+				},
+			}).(*metadata.Tuple),
+		})
+
+	case *types.Struct:
+		// Structs can refer to themselves via pointers, the type is created
+		// before the members are added
+		if f, ok := c.debug.structTypes[tt]; ok {
+			return f
+		}
+
+		// Named types, such as "type foo struct {}", are named by the LLVM type
+		structName := tt.SourceName
+		if structName == "" {
+			structName = tt.Type.Name()
+		}
+
+		composite := &metadata.DICompositeType{
+			Tag:   enum.DwarfTagStructureType,
+			Name:  structName,
+			Size:  size,
+			Align: align,
+		}
+		c.debugDef(composite)
+		c.debug.structTypes[tt] = composite
+
+		memberNames := make([]string, len(tt.MemberIndexes))
+		for memberName, index := range tt.MemberIndexes {
+			memberNames[index] = memberName
+		}
+
+		memberTypes := make([]metadata.Field, len(memberNames))
+		for i, memberName := range memberNames {
+			memberTypes[i] = c.debugType(tt.Members[memberName])
+		}
+
+		composite.Elements = c.debugMembers(composite, t.LLVM(), memberNames, memberTypes)
+		return composite
+	}
+
+	// Types without a detailed description, such as interfaces and functions
+	return c.debugStruct(t.Name(), t.LLVM(), nil, nil)
+}
+
+func (c *Compiler) debugPointer(elem metadata.Field) metadata.Field {
+	return c.debugDef(&metadata.DIDerivedType{
+		Tag:      enum.DwarfTagPointerType,
+		BaseType: elem,
+		Size:     64,
+	})
+}
+
+// debugStruct creates a struct type, the fields are described by the
+// LLVM type t, with the names and debug types in memberNames and memberTypes
+func (c *Compiler) debugStruct(typeName string, t llvmTypes.Type, memberNames []string, memberTypes []metadata.Field) metadata.Field {
+	size, align := debugSizeAlign(t)
+
+	composite := &metadata.DICompositeType{
+		Tag:   enum.DwarfTagStructureType,
+		Name:  typeName,
+		Size:  size,
+		Align: align,
+	}
+	c.debugDef(composite)
+
+	composite.Elements = c.debugMembers(composite, t, memberNames, memberTypes)
+	return composite
+}
+
+func (c *Compiler) debugMembers(scope *metadata.DICompositeType, t llvmTypes.Type, memberNames []string, memberTypes []metadata.Field) *metadata.Tuple {
+	elements := &metadata.Tuple{}
+
+	structType, ok := t.(*llvmTypes.StructType)
+	if !ok {
+		return c.debugDef(elements).(*metadata.Tuple)
+	}
+
+	offsets := debugFieldOffsets(structType)
+
+	for i, memberName := range memberNames {
+		size, align := debugSizeAlign(structType.Fields[i])
+		elements.Fields = append(elements.Fields, c.debugDef(&metadata.DIDerivedType{
+			Tag:      enum.DwarfTagMember,
+			Name:     memberName,
+			Scope:    scope,
+			BaseType: memberTypes[i],
+			Size:     size,
+			Align:    align,
+			Offset:   offsets[i],
+		}))
+	}
+
+	return c.debugDef(elements).(*metadata.Tuple)
+}
+
+// debugSizeAlign returns the size and alignment in bits of an LLVM type on a
+// 64 bit target
+func debugSizeAlign(t llvmTypes.Type) (size, align uint64) {
+	switch tt := t.(type) {
+	case *llvmTypes.IntType:
+		size = 8
+		for size < tt.BitSize {
+			size *= 2
+		}
+		return size, size
+	case *llvmTypes.ArrayType:
+		size, align = debugSizeAlign(tt.ElemType)
+		return size * tt.Len, align
+	case *llvmTypes.StructType:
+		offsets := debugFieldOffsets(tt)
+		align = 8
+		for i, field := range tt.Fields {
+			fieldSize, fieldAlign := debugSizeAlign(field)
+			if fieldAlign > align {
+				align = fieldAlign
+			}
+			size = offsets[i] + fieldSize
+		}
+		return alignTo(size, align), align
+	}
+
+	// Pointers, and anything else
+	return 64, 64
+}
+
+// debugFieldOffsets returns the offset in bits of each field in t
+func debugFieldOffsets(t *llvmTypes.StructType) []uint64 {
+	offsets := make([]uint64, len(t.Fields))
+	var offset uint64
+
+	for i, field := range t.Fields {
+		size, align := debugSizeAlign(field)
+		if !t.Packed {
+			offset = alignTo(offset, align)
+		}
+		offsets[i] = offset
+		offset += size
+	}
+
+	return offsets
+}
+
+func alignTo(n, align uint64) uint64 {
+	return (n + align - 1) / align * align
+}
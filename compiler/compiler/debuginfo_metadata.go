@@ -0,0 +1,143 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir"
+)
+
+// instMetadata returns the metadata attachments of the instruction or the
+// terminator inst, or nil if it has none
+func instMetadata(inst interface{}) *ir.Metadata {
+	switch i := inst.(type) {
+	case *ir.InstExtractValue:
+		return &i.Metadata
+	case *ir.InstInsertValue:
+		return &i.Metadata
+	case *ir.InstAdd:
+		return &i.Metadata
+	case *ir.InstFAdd:
+		return &i.Metadata
+	case *ir.InstSub:
+		return &i.Metadata
+	case *ir.InstFSub:
+		return &i.Metadata
+	case *ir.InstMul:
+		return &i.Metadata
+	case *ir.InstFMul:
+		return &i.Metadata
+	case *ir.InstUDiv:
+		return &i.Metadata
+	case *ir.InstSDiv:
+		return &i.Metadata
+	case *ir.InstFDiv:
+		return &i.Metadata
+	case *ir.InstURem:
+		return &i.Metadata
+	case *ir.InstSRem:
+		return &i.Metadata
+	case *ir.InstFRem:
+		return &i.Metadata
+	case *ir.InstShl:
+		return &i.Metadata
+	case *ir.InstLShr:
+		return &i.Metadata
+	case *ir.InstAShr:
+		return &i.Metadata
+	case *ir.InstAnd:
+		return &i.Metadata
+	case *ir.InstOr:
+		return &i.Metadata
+	case *ir.InstXor:
+		return &i.Metadata
+	case *ir.InstTrunc:
+		return &i.Metadata
+	case *ir.InstZExt:
+		return &i.Metadata
+	case *ir.InstSExt:
+		return &i.Metadata
+	case *ir.InstFPTrunc:
+		return &i.Metadata
+	case *ir.InstFPExt:
+		return &i.Metadata
+	case *ir.InstFPToUI:
+		return &i.Metadata
+	case *ir.InstFPToSI:
+		return &i.Metadata
+	case *ir.InstUIToFP:
+		return &i.Metadata
+	case *ir.InstSIToFP:
+		return &i.Metadata
+	case *ir.InstPtrToInt:
+		return &i.Metadata
+	case *ir.InstIntToPtr:
+		return &i.Metadata
+	case *ir.InstBitCast:
+		return &i.Metadata
+	case *ir.InstAddrSpaceCast:
+		return &i.Metadata
+	case *ir.InstAlloca:
+		return &i.Metadata
+	case *ir.InstLoad:
+		return &i.Metadata
+	case *ir.InstStore:
+		return &i.Metadata
+	case *ir.InstFence:
+		return &i.Metadata
+	case *ir.InstCmpXchg:
+		return &i.Metadata
+	case *ir.InstAtomicRMW:
+		return &i.Metadata
+	case *ir.InstGetElementPtr:
+		return &i.Metadata
+	case *ir.InstICmp:
+		return &i.Metadata
+	case *ir.InstFCmp:
+		return &i.Metadata
+	case *ir.InstPhi:
+		return &i.Metadata
+	case *ir.InstSelect:
+		return &i.Metadata
+	case *ir.InstCall:
+		return &i.Metadata
+	case *ir.InstVAArg:
+		return &i.Metadata
+	case *ir.InstLandingPad:
+		return &i.Metadata
+	case *ir.InstCatchPad:
+		return &i.Metadata
+	case *ir.InstCleanupPad:
+		return &i.Metadata
+	case *ir.InstFNeg:
+		return &i.Metadata
+	case *ir.InstExtractElement:
+		return &i.Metadata
+	case *ir.InstInsertElement:
+		return &i.Metadata
+	case *ir.InstShuffleVector:
+		return &i.Metadata
+	case *ir.TermRet:
+		return &i.Metadata
+	case *ir.TermBr:
+		return &i.Metadata
+	case *ir.TermCondBr:
+		return &i.Metadata
+	case *ir.TermSwitch:
+		return &i.Metadata
+	case *ir.TermIndirectBr:
+		return &i.Metadata
+	case *ir.TermInvoke:
+		return &i.Metadata
+	case *ir.TermCallBr:
+		return &i.Metadata
+	case *ir.TermResume:
+		return &i.Metadata
+	case *ir.TermCatchSwitch:
+		return &i.Metadata
+	case *ir.TermCatchRet:
+		return &i.Metadata
+	case *ir.TermCleanupRet:
+		return &i.Metadata
+	case *ir.TermUnreachable:
+		return &i.Metadata
+	}
+	return nil
+}
@@ -10,11 +10,15 @@ import (
 
 // ExternalFuncs and the "external" package contains a mapping to glibc functions.
 // These are used to make bootstrapping of the language easier. The end goal is to not depend on glibc.
+// The package also contains functions that are generated by the compiler, such as AssignInterface.
 type ExternalFuncs struct {
 	Printf  value.Value
 	Malloc  value.Value
+	Free    value.Value
+	Calloc  value.Value
 	Realloc value.Value
 	Memcpy  value.Value
+	Memcmp  value.Value
 	Strcat  value.Value
 	Strcpy  value.Value
 	Strncpy value.Value
@@ -27,9 +31,26 @@ func (c *Compiler) createExternalPackage() {
 
 	setExternal := func(internalName string, fn *ir.Func, variadic bool) value.Value {
 		fn.Sig.Variadic = variadic
+
+		// Integer and float return values can be used from tre code
+		var returnType types.Type = types.Void
+		switch retType := fn.Sig.RetType.(type) {
+		case *llvmTypes.IntType:
+			switch retType.BitSize {
+			case 32:
+				returnType = i32
+			case 64:
+				returnType = i64
+			}
+		case *llvmTypes.FloatType:
+			if retType.Kind == llvmTypes.FloatKindDouble {
+				returnType = types.F64
+			}
+		}
+
 		val := value.Value{
 			Type: &types.Function{
-				LlvmReturnType: types.Void,
+				LlvmReturnType: returnType,
 				FuncType:       fn.Type(),
 				IsExternal:     true,
 			},
@@ -49,6 +70,17 @@ func (c *Compiler) createExternalPackage() {
 		ir.NewParam("", i64.LLVM()),
 	), false)
 
+	c.externalFuncs.Free = setExternal("free", c.module.NewFunc("free",
+		llvmTypes.Void,
+		ir.NewParam("", llvmTypes.NewPointer(i8.LLVM())),
+	), false)
+
+	c.externalFuncs.Calloc = setExternal("calloc", c.module.NewFunc("calloc",
+		llvmTypes.NewPointer(i8.LLVM()),
+		ir.NewParam("", i64.LLVM()),
+		ir.NewParam("", i64.LLVM()),
+	), false)
+
 	c.externalFuncs.Realloc = setExternal("realloc", c.module.NewFunc("realloc",
 		llvmTypes.NewPointer(i8.LLVM()),
 		ir.NewParam("", llvmTypes.NewPointer(i8.LLVM())),
@@ -62,6 +94,13 @@ func (c *Compiler) createExternalPackage() {
 		ir.NewParam("n", i64.LLVM()),
 	), false)
 
+	c.externalFuncs.Memcmp = setExternal("memcmp", c.module.NewFunc("memcmp",
+		i32.LLVM(),
+		ir.NewParam("s1", llvmTypes.NewPointer(i8.LLVM())),
+		ir.NewParam("s2", llvmTypes.NewPointer(i8.LLVM())),
+		ir.NewParam("n", i64.LLVM()),
+	), false)
+
 	c.externalFuncs.Strcat = setExternal("strcat", c.module.NewFunc("strcat",
 		llvmTypes.NewPointer(i8.LLVM()),
 		ir.NewParam("", llvmTypes.NewPointer(i8.LLVM())),
@@ -87,10 +126,90 @@ func (c *Compiler) createExternalPackage() {
 		ir.NewParam("", i64.LLVM()),
 	), false)
 
-	c.externalFuncs.Exit = setExternal("exit", c.module.NewFunc("exit",
+	c.externalFuncs.Exit = setExternal("Exit", c.module.NewFunc("exit",
 		llvmTypes.Void,
 		ir.NewParam("", i32.LLVM()),
 	), false)
 
+	// Process management, is used by the testing package to run tests in isolation
+	setExternal("Fork", c.module.NewFunc("fork",
+		i32.LLVM(),
+	), false)
+
+	setExternal("Waitpid", c.module.NewFunc("waitpid",
+		i32.LLVM(),
+		ir.NewParam("pid", i32.LLVM()),
+		ir.NewParam("status", llvmTypes.NewPointer(i32.LLVM())),
+		ir.NewParam("options", i32.LLVM()),
+	), false)
+
+	setExternal("Fflush", c.module.NewFunc("fflush",
+		i32.LLVM(),
+		ir.NewParam("stream", llvmTypes.NewPointer(i8.LLVM())),
+	), false)
+
+	// LLVM intrinsics, such as external.Sqrt(x), are lowered to instructions
+	// when the target has them, or to calls to libm
+	f64 := types.F64.LLVM()
+	floatIntrinsics := []struct {
+		name      string
+		intrinsic string
+		numArgs   int
+	}{
+		{"Sqrt", "llvm.sqrt.f64", 1},
+		{"Fabs", "llvm.fabs.f64", 1},
+		{"Floor", "llvm.floor.f64", 1},
+		{"Ceil", "llvm.ceil.f64", 1},
+		{"Trunc", "llvm.trunc.f64", 1},
+		{"Round", "llvm.round.f64", 1},
+		{"Exp", "llvm.exp.f64", 1},
+		{"Log", "llvm.log.f64", 1},
+		{"Log2", "llvm.log2.f64", 1},
+		{"Log10", "llvm.log10.f64", 1},
+		{"Sin", "llvm.sin.f64", 1},
+		{"Cos", "llvm.cos.f64", 1},
+		{"Pow", "llvm.pow.f64", 2},
+		{"Copysign", "llvm.copysign.f64", 2},
+	}
+	for _, intrinsic := range floatIntrinsics {
+		var params []*ir.Param
+		var argTypes []types.Type
+		for i := 0; i < intrinsic.numArgs; i++ {
+			params = append(params, ir.NewParam("", f64))
+			argTypes = append(argTypes, types.F64)
+		}
+		fn := setExternal(intrinsic.name, c.module.NewFunc(intrinsic.intrinsic, f64, params...), false)
+
+		// Constant arguments are converted to float64
+		fn.Type.(*types.Function).ArgumentTypes = argTypes
+	}
+
+	// Bit counting, the second argument of Ctlz and Cttz is true if the
+	// result is undefined for 0
+	setExternal("Ctpop", c.module.NewFunc("llvm.ctpop.i64", i64.LLVM(), ir.NewParam("", i64.LLVM())), false)
+	setExternal("Ctlz", c.module.NewFunc("llvm.ctlz.i64", i64.LLVM(), ir.NewParam("", i64.LLVM()), ir.NewParam("", llvmTypes.I1)), false)
+	setExternal("Cttz", c.module.NewFunc("llvm.cttz.i64", i64.LLVM(), ir.NewParam("", i64.LLVM()), ir.NewParam("", llvmTypes.I1)), false)
+	setExternal("Bswap", c.module.NewFunc("llvm.bswap.i64", i64.LLVM(), ir.NewParam("", i64.LLVM())), false)
+	setExternal("Bitreverse", c.module.NewFunc("llvm.bitreverse.i64", i64.LLVM(), ir.NewParam("", i64.LLVM())), false)
+
 	c.packages["external"] = external
 }
+
+// defineExternalFunc makes fn available as external.funcName, with the types
+// of its arguments and return value. Void functions have no return values.
+func (c *Compiler) defineExternalFunc(funcName string, fn *ir.Func, returnType types.Type, argTypes []types.Type) {
+	var returnTypes []types.Type
+	if returnType != types.Void {
+		returnTypes = []types.Type{returnType}
+	}
+
+	c.packages["external"].DefinePkgVar(funcName, value.Value{
+		Type: &types.Function{
+			FuncType:       fn.Type(),
+			LlvmReturnType: returnType,
+			ReturnTypes:    returnTypes,
+			ArgumentTypes:  argTypes,
+		},
+		Value: fn,
+	})
+}
@@ -0,0 +1,167 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/internal"
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/compiler/value"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// Comparisons of floats are ordered, they are false if any of the operands is
+// NaN. Except for !=, that is true if any of the operands is NaN.
+var floatPredicates = map[parser.Operator]enum.FPred{
+	parser.OP_GT:   enum.FPredOGT,
+	parser.OP_GTEQ: enum.FPredOGE,
+	parser.OP_LT:   enum.FPredOLT,
+	parser.OP_LTEQ: enum.FPredOLE,
+	parser.OP_EQ:   enum.FPredOEQ,
+	parser.OP_NEQ:  enum.FPredUNE,
+}
+
+// compileFloatOperator compiles an arithmetic operation or a comparison where
+// both operands are floats of the type t
+func (c *Compiler) compileFloatOperator(operator parser.Operator, t types.Type, left, right llvmValue.Value) value.Value {
+	var res llvmValue.Value
+
+	switch operator {
+	case parser.OP_ADD:
+		res = c.contextBlock.NewFAdd(left, right)
+	case parser.OP_SUB:
+		res = c.contextBlock.NewFSub(left, right)
+	case parser.OP_MUL:
+		res = c.contextBlock.NewFMul(left, right)
+	case parser.OP_DIV:
+		res = c.contextBlock.NewFDiv(left, right)
+	default:
+		pred, ok := floatPredicates[operator]
+		if !ok {
+			compilePanic(fmt.Sprintf("invalid operation: operator %s not defined on %s", operator, t.Name()))
+		}
+		return value.Value{
+			Type:       types.Bool,
+			Value:      c.contextBlock.NewFCmp(pred, left, right),
+			IsVariable: false,
+		}
+	}
+
+	return value.Value{
+		Value:      res,
+		Type:       t,
+		IsVariable: false,
+	}
+}
+
+// compileFloatConversion converts between floats and integers, or between
+// floats of different sizes. Floats are truncated towards zero when
+// converted to integers.
+func (c *Compiler) compileFloatConversion(val value.Value, targetType types.Type) value.Value {
+	llvmVal := internal.LoadIfVariable(c.contextBlock, val)
+
+	var res llvmValue.Value
+
+	switch target := targetType.(type) {
+	case *types.Float:
+		switch from := val.Type.(type) {
+		case *types.Float:
+			switch {
+			case from.TypeSize < target.TypeSize:
+				res = c.contextBlock.NewFPExt(llvmVal, target.Type)
+			case from.TypeSize > target.TypeSize:
+				res = c.contextBlock.NewFPTrunc(llvmVal, target.Type)
+			default:
+				res = llvmVal
+			}
+		case *types.Int:
+			if from.IsSigned() {
+				res = c.contextBlock.NewSIToFP(llvmVal, target.Type)
+			} else {
+				res = c.contextBlock.NewUIToFP(llvmVal, target.Type)
+			}
+		default:
+			compilePanic(fmt.Sprintf("cannot convert %s to %s", val.Type.Name(), target.Name()))
+		}
+
+	case *types.Int:
+		if target.IsSigned() {
+			res = c.contextBlock.NewFPToSI(llvmVal, target.Type)
+		} else {
+			res = c.contextBlock.NewFPToUI(llvmVal, target.Type)
+		}
+
+	default:
+		compilePanic(fmt.Sprintf("cannot convert %s to %s", val.Type.Name(), targetType.Name()))
+	}
+
+	return value.Value{
+		Value:      res,
+		Type:       targetType,
+		IsVariable: false,
+	}
+}
+
+// addFloatFuncs adds the functions that are used by the math and strconv
+// packages to inspect, format and parse floats to the external package
+func (c *Compiler) addFloatFuncs() {
+	f64 := types.F64.LLVM()
+	i8Ptr := llvmTypes.I8Ptr
+
+	snprintf := c.module.NewFunc("snprintf", llvmTypes.I32, ir.NewParam("s", i8Ptr), ir.NewParam("n", llvmTypes.I64), ir.NewParam("format", i8Ptr))
+	snprintf.Sig.Variadic = true
+	strtod := c.module.NewFunc("strtod", f64, ir.NewParam("s", i8Ptr), ir.NewParam("end", llvmTypes.NewPointer(i8Ptr)))
+
+	// Float64bits(f float64) uint64 returns the IEEE 754 representation of f
+	bitsFn := c.module.NewFunc("float-bits", llvmTypes.I64, ir.NewParam("f", f64))
+	bitsBlock := bitsFn.NewBlock(name.Block())
+	bitsBlock.NewRet(bitsBlock.NewBitCast(bitsFn.Params[0], llvmTypes.I64))
+	c.defineExternalFunc("Float64bits", bitsFn, types.U64, []types.Type{types.F64})
+
+	// Float64frombits(b uint64) float64 is the inverse of Float64bits
+	fromBitsFn := c.module.NewFunc("float-frombits", f64, ir.NewParam("b", llvmTypes.I64))
+	fromBitsBlock := fromBitsFn.NewBlock(name.Block())
+	fromBitsBlock.NewRet(fromBitsBlock.NewBitCast(fromBitsFn.Params[0], f64))
+	c.defineExternalFunc("Float64frombits", fromBitsFn, types.F64, []types.Type{types.U64})
+
+	// FormatFloat(f float64, verb byte, prec int) string formats f with the
+	// printf verb 'e', 'f' or 'g' and prec digits of precision
+	formatFn := c.module.NewFunc("float-format", types.String.LLVM(),
+		ir.NewParam("f", f64), ir.NewParam("verb", llvmTypes.I8), ir.NewParam("prec", llvmTypes.I64))
+	formatBlock := formatFn.NewBlock(name.Block())
+	formatType := llvmTypes.NewArray(5, llvmTypes.I8)
+	format := formatBlock.NewAlloca(formatType)
+	formatBlock.NewStore(constant.NewCharArrayFromString("%.*e\x00"), format)
+	verbPtr := formatBlock.NewGetElementPtr(formatType, format, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 3))
+	formatBlock.NewStore(formatFn.Params[1], verbPtr)
+	formatPtr := formatBlock.NewBitCast(format, i8Ptr)
+	prec := formatBlock.NewTrunc(formatFn.Params[2], llvmTypes.I32)
+
+	// The first call calculates the length of the result
+	length := formatBlock.NewSExt(formatBlock.NewCall(snprintf, constant.NewNull(i8Ptr), constant.NewInt(llvmTypes.I64, 0), formatPtr, prec, formatFn.Params[0]), llvmTypes.I64)
+	bufSize := formatBlock.NewAdd(length, constant.NewInt(llvmTypes.I64, 1))
+	buf := formatBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), bufSize)
+	formatBlock.NewCall(snprintf, buf, bufSize, formatPtr, prec, formatFn.Params[0])
+	var res llvmValue.Value = constant.NewUndef(types.ModuleStringType.(*llvmTypes.StructType))
+	res = formatBlock.NewInsertValue(res, length, 0)
+	res = formatBlock.NewInsertValue(res, buf, 1)
+	formatBlock.NewRet(res)
+	c.defineExternalFunc("FormatFloat", formatFn, types.String, []types.Type{types.F64, types.U8, types.I64})
+
+	// ParseFloat(s string) float64 parses the float in s. The syntax of s is
+	// not checked, the result is 0 if s does not start with a number.
+	parseFn := c.module.NewFunc("float-parse", f64, ir.NewParam("s", types.String.LLVM()))
+	parseBlock := parseFn.NewBlock(name.Block())
+	cStr := parseBlock.NewCall(c.externalFuncs.Strndup.Value.(llvmValue.Named),
+		parseBlock.NewExtractValue(parseFn.Params[0], 1), parseBlock.NewExtractValue(parseFn.Params[0], 0))
+	parsed := parseBlock.NewCall(strtod, cStr, constant.NewNull(llvmTypes.NewPointer(i8Ptr)))
+	parseBlock.NewCall(c.externalFuncs.Free.Value.(llvmValue.Named), cStr)
+	parseBlock.NewRet(parsed)
+	c.defineExternalFunc("ParseFloat", parseFn, types.F64, []types.Type{types.String})
+}
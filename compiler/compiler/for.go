@@ -6,20 +6,23 @@ import (
 	"github.com/zegl/tre/compiler/parser"
 )
 
-func (c *Compiler) compileForNode(v *parser.ForNode) {
+// compileForNode compiles a for loop, l is the label of the loop or nil
+func (c *Compiler) compileForNode(v *parser.ForNode, l *label) {
 	if v.IsThreeTypeFor {
-		c.compileForThreeType(v)
+		c.compileForThreeType(v, l)
 		return
 	}
 
-	c.compileForRange(v)
+	c.compileForRange(v, l)
 }
 
-func (c *Compiler) compileForThreeType(v *parser.ForNode) {
+func (c *Compiler) compileForThreeType(v *parser.ForNode, l *label) {
 	// TODO: create a new context-block for code running inside the for loop
-	c.compile([]parser.Node{
-		v.BeforeLoop,
-	})
+	if v.BeforeLoop != nil {
+		c.compile([]parser.Node{
+			v.BeforeLoop,
+		})
+	}
 
 	// Check condition block
 	checkCondBlock := c.contextBlock.Parent.NewBlock(name.Block() + "-cond")
@@ -34,6 +37,10 @@ func (c *Compiler) compileForThreeType(v *parser.ForNode) {
 	// Push the break and continue stacks
 	c.contextLoopBreak = append(c.contextLoopBreak, afterLoopBlock)
 	c.contextLoopContinue = append(c.contextLoopContinue, loopAfterBodyBlock)
+	if l != nil {
+		l.breakBlock = afterLoopBlock
+		l.continueBlock = loopAfterBodyBlock
+	}
 
 	// Jump from BeforeLoop to checkCond
 	c.contextBlock.NewBr(checkCondBlock)
@@ -50,7 +57,9 @@ func (c *Compiler) compileForThreeType(v *parser.ForNode) {
 
 	// After body block
 	c.contextBlock = loopAfterBodyBlock
-	c.compile([]parser.Node{v.AfterIteration})
+	if v.AfterIteration != nil {
+		c.compile([]parser.Node{v.AfterIteration})
+	}
 	c.contextBlock.NewBr(checkCondBlock)
 
 	// Set context to the new block after the loop
@@ -61,7 +70,7 @@ func (c *Compiler) compileForThreeType(v *parser.ForNode) {
 	c.contextLoopContinue = c.contextLoopContinue[0 : len(c.contextLoopContinue)-1]
 }
 
-func (c *Compiler) compileForRange(v *parser.ForNode) {
+func (c *Compiler) compileForRange(v *parser.ForNode, l *label) {
 	// A for range that iterates over a slice is just syntactic sugar
 	// for k, v := range a
 	// for k := 0; k < len(a); k++ { v := a[k] }
@@ -119,16 +128,11 @@ func (c *Compiler) compileForRange(v *parser.ForNode) {
 	}
 	modifiedBlock = append(modifiedBlock, v.Block...)
 
-	typeCastedKey := &parser.TypeCastNode{
-		Type: &parser.SingleTypeNode{SourceName: "int32", TypeName: "int32"},
-		Val:  &parser.NameNode{Name: forKeyName},
-	}
-
 	c.compileForThreeType(&parser.ForNode{
 		BeforeLoop: &parser.AllocNode{Name: []string{forKeyName}, Val: []parser.Node{&parser.ConstantNode{Type: parser.NUMBER, Value: 0}}},
 
 		Condition: &parser.OperatorNode{
-			Left:     typeCastedKey,
+			Left:     &parser.NameNode{Name: forKeyName},
 			Operator: parser.OP_LT,
 			Right: &parser.NameNode{
 				Name: forItemLenName,
@@ -145,13 +149,37 @@ func (c *Compiler) compileForRange(v *parser.ForNode) {
 		},
 
 		Block: modifiedBlock,
-	})
+	}, l)
 }
 
 func (c *Compiler) compileBreakNode(v *parser.BreakNode) {
-	c.contextBlock.NewBr(c.contextLoopBreak[len(c.contextLoopBreak)-1])
+	if v.Label != "" {
+		l, ok := c.contextLabels[v.Label]
+		if !ok || l.breakBlock == nil {
+			compilePanic(fmt.Sprintf("invalid break label %s", v.Label))
+		}
+		c.contextBlock.NewBr(l.breakBlock)
+	} else {
+		if len(c.contextLoopBreak) == 0 {
+			compilePanic("break is not in a loop or switch")
+		}
+		c.contextBlock.NewBr(c.contextLoopBreak[len(c.contextLoopBreak)-1])
+	}
+	c.unreachableAfterJump()
 }
 
 func (c *Compiler) compileContinueNode(v *parser.ContinueNode) {
-	c.contextBlock.NewBr(c.contextLoopContinue[len(c.contextLoopContinue)-1])
+	if v.Label != "" {
+		l, ok := c.contextLabels[v.Label]
+		if !ok || l.continueBlock == nil {
+			compilePanic(fmt.Sprintf("invalid continue label %s", v.Label))
+		}
+		c.contextBlock.NewBr(l.continueBlock)
+	} else {
+		if len(c.contextLoopContinue) == 0 {
+			compilePanic("continue is not in a loop")
+		}
+		c.contextBlock.NewBr(c.contextLoopContinue[len(c.contextLoopContinue)-1])
+	}
+	c.unreachableAfterJump()
 }
@@ -66,7 +66,6 @@ func (c *Compiler) funcType(params, returnTypes []parser.TypeNode) (retType type
 		}
 
 		// Add return values to the start
-		treParams = append(treReturnTypes, treParams...)
 		llvmParams = append(llvmReturnTypesParams, llvmParams...)
 
 		argumentReturnValuesCount = len(returnTypes)
@@ -94,11 +93,19 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 		}, v.Arguments...)
 
 		// Change the name of our function
-		compiledName = c.currentPackageName + "_method_" + v.MethodOnType.TypeName + "_" + v.Name
+		compiledName = c.currentPackagePath + "_method_" + v.MethodOnType.TypeName + "_" + v.Name
 	} else if v.IsNamed {
-		compiledName = c.currentPackageName + "_" + v.Name
+		compiledName = c.currentPackagePath + "_" + v.Name
 	} else {
-		compiledName = c.currentPackageName + "_" + name.AnonFunc()
+		compiledName = c.currentPackagePath + "_" + name.AnonFunc()
+	}
+
+	// Exported functions ("//export Name") are callable from C with the exported name
+	if v.Export != "" {
+		if v.IsMethod {
+			compilePanic("methods can not be exported: " + v.Name)
+		}
+		compiledName = v.Export
 	}
 
 	argTypes := make([]parser.TypeNode, len(v.Arguments))
@@ -155,7 +162,7 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 		}
 
 		// Make this method available in interfaces via a jump function
-		typesFunc.JumpFunction = c.compileInterfaceMethodJump(fn)
+		typesFunc.JumpFunction = c.compileInterfaceMethodJump(fn, argumentReturnValuesCount)
 	} else if v.IsNamed {
 		c.currentPackage.DefinePkgVar(v.Name, value.Value{
 			Type:  typesFunc,
@@ -163,18 +170,26 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 		})
 	}
 
+	funcName := c.qualifiedFuncName(v)
+
 	prevContextFunc := c.contextFunc
+	prevContextFuncName := c.contextFuncName
 	prevContextBlock := c.contextBlock
+	prevContextLabels := c.contextLabels
+	prevDebugScope := c.beginDebugFunc(funcName, fn, typesFunc)
+	prevCallSites := c.beginCallSites(funcName, fn)
 
 	c.contextFunc = typesFunc
+	c.contextFuncName = funcName
 	c.contextBlock = entry
+	c.contextLabels = map[string]*label{}
 	c.pushVariablesStack()
 
 	// Push to the return values stack
 	if argumentReturnValuesCount > 0 {
 		var retVals []value.Value
 
-		for i, retType := range treParams[:argumentReturnValuesCount] {
+		for i, retType := range treReturnTypes {
 			retVals = append(retVals, value.Value{
 				Value:      llvmParams[i],
 				Type:       retType,
@@ -201,8 +216,8 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 			dataType = treParams[i-argumentReturnValuesCount]
 		}
 
-		// Structs needs to be pointer-allocated
-		if _, ok := param.Type().(*llvmTypes.StructType); ok {
+		// Arguments are pointer-allocated so that they can be assigned to
+		if i >= argumentReturnValuesCount {
 			paramPtr := entry.NewAlloca(dataType.LLVM())
 			paramPtr.SetName(name.Var("paramPtr"))
 			entry.NewStore(param, paramPtr)
@@ -216,6 +231,9 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 			continue
 		}
 
+		// Named return values starts at their zero values
+		dataType.Zero(entry, param)
+
 		c.setVar(paramName, value.Value{
 			Value:      param,
 			Type:       dataType,
@@ -227,7 +245,8 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 	// Add to variable block
 	if len(v.ReturnValues) == 1 {
 		r := v.ReturnValues[0]
-		all := c.contextBlock.NewAlloca(funcRetType.LLVM())
+		all := c.entryBlockAlloca(funcRetType.LLVM())
+		funcRetType.Zero(entry, all)
 		retVar := value.Value{
 			Value:      all,
 			Type:       funcRetType,
@@ -237,7 +256,9 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 		c.contextFuncRetVals = append(c.contextFuncRetVals, []value.Value{retVar})
 	}
 
+	checkGotos(v.Body)
 	c.compile(v.Body)
+	c.checkLabels()
 
 	// Return void if there is no return type explicitly set
 	if len(v.ReturnValues) == 0 {
@@ -252,8 +273,13 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 		c.contextBlock.NewRet(constant.NewInt(llvmTypes.I32, 0))
 	}
 
+	c.endDebugFunc(prevDebugScope)
+	c.endCallSites(prevCallSites)
+
 	c.contextFunc = prevContextFunc
+	c.contextFuncName = prevContextFuncName
 	c.contextBlock = prevContextBlock
+	c.contextLabels = prevContextLabels
 
 	c.popVariablesStack()
 
@@ -263,37 +289,41 @@ func (c *Compiler) compileDefineFuncNode(v *parser.DefineFuncNode) value.Value {
 	}
 }
 
-func (c *Compiler) compileInterfaceMethodJump(targetFunc *ir.Func) *ir.Func {
+// compileInterfaceMethodJump creates the function that is used when the method
+// is called via an interface. The receiver is passed as an *i8, and is the
+// parameter after the pointers to the return values of multi-value methods.
+func (c *Compiler) compileInterfaceMethodJump(targetFunc *ir.Func, receiverIndex int) *ir.Func {
 	// Copy parameter types so that we can modify them
 	params := make([]*ir.Param, len(targetFunc.Sig.Params))
 	for i, p := range targetFunc.Params {
 		params[i] = ir.NewParam("", p.Type())
 	}
 
-	originalType := targetFunc.Params[0].Type()
+	originalType := targetFunc.Params[receiverIndex].Type()
 	_, isPointerType := originalType.(*llvmTypes.PointerType)
 	if !isPointerType {
 		originalType = llvmTypes.NewPointer(originalType)
 	}
 
-	// Replace the first parameter type with an *i8
+	// Replace the receiver parameter type with an *i8
 	// Will be bitcasted later to the target type
-	params[0] = ir.NewParam("unsafe-ptr", llvmTypes.NewPointer(llvmTypes.I8))
+	params[receiverIndex] = ir.NewParam("unsafe-ptr", llvmTypes.NewPointer(llvmTypes.I8))
 
 	fn := c.module.NewFunc(targetFunc.Name()+"_jump", targetFunc.Sig.RetType, params...)
 	block := fn.NewBlock(name.Block())
 
-	var bitcasted llvmValue.Value = block.NewBitCast(params[0], originalType)
+	var bitcasted llvmValue.Value = block.NewBitCast(params[receiverIndex], originalType)
 
 	// TODO: Don't do this if the method has a pointer receiver
 	if !isPointerType {
 		bitcasted = block.NewLoad(pointer.ElemType(bitcasted), bitcasted)
 	}
 
-	callArgs := []llvmValue.Value{bitcasted}
-	for _, p := range params[1:] {
-		callArgs = append(callArgs, p)
+	callArgs := make([]llvmValue.Value, len(params))
+	for i, p := range params {
+		callArgs[i] = p
 	}
+	callArgs[receiverIndex] = bitcasted
 
 	resVal := block.NewCall(targetFunc, callArgs...)
 
@@ -307,13 +337,35 @@ func (c *Compiler) compileInterfaceMethodJump(targetFunc *ir.Func) *ir.Func {
 }
 
 func (c *Compiler) compileReturnNode(v *parser.ReturnNode) {
+	// Structs that are created in the return statement outlives the function,
+	// and are allocated on the heap
+	c.contextAlloc = append(c.contextAlloc, &parser.AllocNode{Escapes: true})
+	defer func() {
+		c.contextAlloc = c.contextAlloc[0 : len(c.contextAlloc)-1]
+	}()
+
 	// Single variable return
 	if len(v.Vals) == 1 {
 		// Set value and jump to return block
 		val := c.compileValue(v.Vals[0])
 
+		// Returning the result of a call with multiple return values, such
+		// as "return f()"
+		if multi, ok := val.Type.(*types.MultiValue); ok {
+			retVals := c.contextFuncRetVals[len(c.contextFuncRetVals)-1]
+			if len(multi.Types) != len(retVals) {
+				compilePanic("wrong number of return values")
+			}
+			for i, multiVal := range val.MultiValues {
+				multiVal = c.assignableValue(multiVal, c.contextFunc.ReturnTypes[i])
+				c.contextBlock.NewStore(internal.LoadIfVariable(c.contextBlock, multiVal), retVals[i].Value)
+			}
+			c.contextBlock.NewRet(nil)
+			return
+		}
+
 		// Type cast if necessary
-		val = c.valueToInterfaceValue(val, c.contextFunc.LlvmReturnType)
+		val = c.assignableValue(val, c.contextFunc.LlvmReturnType)
 
 		if val.IsVariable {
 			c.contextBlock.NewRet(c.contextBlock.NewLoad(pointer.ElemType(val.Value), val.Value))
@@ -329,8 +381,11 @@ func (c *Compiler) compileReturnNode(v *parser.ReturnNode) {
 		for i, val := range v.Vals {
 			compVal := c.compileValue(val)
 
-			// TODO: Type cast if necessary
-			// compVal = c.valueToInterfaceValue(compVal, c.contextFunc.ReturnType)
+			// Type cast if necessary
+			compVal = c.assignableValue(compVal, c.contextFunc.ReturnTypes[i])
+			if _, ok := compVal.Type.(*types.UntypedConstantNumber); ok {
+				compVal = value.UntypedConstAs(compVal, value.Value{Type: c.contextFunc.ReturnTypes[i]})
+			}
 
 			retVal := internal.LoadIfVariable(c.contextBlock, compVal)
 
@@ -345,7 +400,7 @@ func (c *Compiler) compileReturnNode(v *parser.ReturnNode) {
 	}
 
 	// Naked return, func has one named return variable
-	if len(v.Vals) == 0 {
+	if len(v.Vals) == 0 && len(c.contextFunc.ReturnTypes) > 0 {
 		retVals := c.contextFuncRetVals[len(c.contextFuncRetVals)-1]
 		if len(retVals) == 1 {
 			val := internal.LoadIfVariable(c.contextBlock, retVals[0])
@@ -371,20 +426,37 @@ func (c *Compiler) compileCallNode(v *parser.CallNode) value.Value {
 			return c.capFuncCall(v)
 		case "append":
 			return c.appendFuncCall(v)
+		case "make":
+			return c.makeFuncCall(v)
 		case "print":
 			return c.printFuncCall(v)
+		case "panic":
+			return c.panicFuncCall(v)
+		}
+
+		// Conversions to named types, such as time.Duration(x), looks like
+		// function calls to the parser
+		if len(v.Arguments) == 1 && c.isTypeName(name) {
+			return c.compileTypeCastNode(&parser.TypeCastNode{
+				Type: &parser.SingleTypeNode{PackageName: name.Package, TypeName: name.Name},
+				Val:  v.Arguments[0],
+			})
 		}
 	}
 
 	var fnType *types.Function
 	var fn llvmValue.Named
 
-	funcByVal := c.compileValue(v.Function)
+	// Is set when calling a function value, instead of calling fn directly
+	var funcVal llvmValue.Value
+
+	funcByVal := c.compileCallee(v.Function)
 	if checkIfFunc, ok := funcByVal.Type.(*types.Function); ok {
 		fnType = checkIfFunc
-		fn = funcByVal.Value.(llvmValue.Named)
-		if funcByVal.IsVariable {
-			fn = c.contextBlock.NewLoad(pointer.ElemType(fn), fn)
+		if direct, ok := funcByVal.Value.(*ir.Func); ok && !funcByVal.IsVariable {
+			fn = direct
+		} else {
+			funcVal = internal.LoadIfVariable(c.contextBlock, funcByVal)
 		}
 	} else if checkIfMethod, ok := funcByVal.Type.(*types.Method); ok {
 		fnType = checkIfMethod.Function
@@ -413,7 +485,7 @@ func (c *Compiler) compileCallNode(v *parser.CallNode) value.Value {
 		args = methodCallArgs
 
 		var returnType types.Type
-		if len(ifaceMethod.ReturnTypes) > 0 {
+		if len(ifaceMethod.ReturnTypes) == 1 {
 			returnType = ifaceMethod.ReturnTypes[0]
 		} else {
 			returnType = types.Void
@@ -423,6 +495,7 @@ func (c *Compiler) compileCallNode(v *parser.CallNode) value.Value {
 			// TODO: We probably need to add more fields here?
 			FuncType:       ifaceMethod.LlvmJumpFunction.Type(),
 			LlvmReturnType: returnType,
+			ReturnTypes:    ifaceMethod.ReturnTypes,
 		}
 		fn = ifaceMethod.LlvmJumpFunction
 	} else {
@@ -465,7 +538,7 @@ func (c *Compiler) compileCallNode(v *parser.CallNode) value.Value {
 
 		// Convert type to interface type if needed
 		if len(fnType.ArgumentTypes) > i {
-			v = c.valueToInterfaceValue(v, fnType.ArgumentTypes[i])
+			v = c.assignableValue(v, fnType.ArgumentTypes[i])
 		}
 
 		val := internal.LoadIfVariable(c.contextBlock, v)
@@ -473,11 +546,11 @@ func (c *Compiler) compileCallNode(v *parser.CallNode) value.Value {
 		// Convert strings and arrays to i8* when calling external functions
 		if fnType.IsExternal {
 			if v.Type.Name() == "string" {
-				llvmArgs[i] = c.contextBlock.NewExtractValue(val, 1)
+				llvmArgs[i] = c.cString(c.contextBlock, val)
 				continue
 			}
 
-			if v.Type.Name() == "array" {
+			if _, ok := v.Type.(*types.Array); ok {
 				llvmArgs[i] = c.contextBlock.NewExtractValue(val, 1)
 				continue
 			}
@@ -493,7 +566,7 @@ func (c *Compiler) compileCallNode(v *parser.CallNode) value.Value {
 		var retValAllocas []llvmValue.Value
 
 		for _, retType := range fnType.ReturnTypes {
-			alloca := c.contextBlock.NewAlloca(retType.LLVM())
+			alloca := c.entryBlockAlloca(retType.LLVM())
 			retValAllocas = append(retValAllocas, alloca)
 
 			multiValues = append(multiValues, value.Value{
@@ -507,7 +580,12 @@ func (c *Compiler) compileCallNode(v *parser.CallNode) value.Value {
 		llvmArgs = append(retValAllocas, llvmArgs...)
 	}
 
-	funcCallRes := c.contextBlock.NewCall(fn, llvmArgs...)
+	var funcCallRes llvmValue.Value
+	if funcVal != nil {
+		funcCallRes = c.callFuncValue(fnType, funcVal, llvmArgs)
+	} else {
+		funcCallRes = c.contextBlock.NewCall(fn, llvmArgs...)
+	}
 
 	// 0 or 1 return variables
 	if len(fnType.ReturnTypes) < 2 {
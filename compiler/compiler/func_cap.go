@@ -1,7 +1,8 @@
 package compiler
 
 import (
-	"github.com/zegl/tre/compiler/compiler/internal/pointer"
+	"github.com/zegl/tre/compiler/compiler/internal"
+	"github.com/zegl/tre/compiler/compiler/types"
 	"github.com/zegl/tre/compiler/compiler/value"
 	"github.com/zegl/tre/compiler/parser"
 )
@@ -9,12 +10,12 @@ import (
 func (c *Compiler) capFuncCall(v *parser.CallNode) value.Value {
 	arg := c.compileValue(v.Arguments[0])
 
-	if arg.Type.Name() == "slice" {
-		val := arg.Value
-		val = c.contextBlock.NewLoad(pointer.ElemType(val), val)
+	if _, ok := arg.Type.(*types.Slice); ok {
+		val := internal.LoadIfVariable(c.contextBlock, arg)
 
+		// The capacity is stored as an i32, but cap() returns an int
 		return value.Value{
-			Value:      c.contextBlock.NewExtractValue(val, 1),
+			Value:      c.contextBlock.NewSExt(c.contextBlock.NewExtractValue(val, 1), i64.LLVM()),
 			Type:       i64,
 			IsVariable: false,
 		}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/zegl/tre/compiler/compiler/internal"
 	"github.com/zegl/tre/compiler/compiler/internal/pointer"
+	"github.com/zegl/tre/compiler/compiler/types"
 	"github.com/zegl/tre/compiler/compiler/value"
 	"github.com/zegl/tre/compiler/parser"
 )
@@ -25,34 +26,34 @@ func (c *Compiler) lenFuncCall(v *parser.CallNode) value.Value {
 
 		return value.Value{
 			Value:      c.contextBlock.NewCall(f.Value.(llvmValue.Named), val),
-			Type:       f.Type,
+			Type:       i64,
 			IsVariable: false,
 		}
 	}
 
-	if arg.Type.Name() == "array" {
+	if _, ok := arg.Type.(*types.Array); ok {
 		if ptrType, ok := arg.Value.Type().(*llvmTypes.PointerType); ok {
 			if arrayType, ok := ptrType.ElemType.(*llvmTypes.ArrayType); ok {
 				return value.Value{
-					Value:      constant.NewInt(llvmTypes.I32, int64(arrayType.Len)),
-					Type:       i32,
+					Value:      constant.NewInt(llvmTypes.I64, int64(arrayType.Len)),
+					Type:       i64,
 					IsVariable: false,
 				}
 			}
 		}
 	}
 
-	if arg.Type.Name() == "slice" {
-		val := arg.Value
-		val = c.contextBlock.NewLoad(pointer.ElemType(val), val)
+	if _, ok := arg.Type.(*types.Slice); ok {
+		val := internal.LoadIfVariable(c.contextBlock, arg)
 
 		if _, ok := val.Type().(*llvmTypes.PointerType); ok {
 			val = c.contextBlock.NewLoad(pointer.ElemType(val), val)
 		}
 
+		// The length is stored as an i32, but len() returns an int
 		return value.Value{
-			Value:      c.contextBlock.NewExtractValue(val, 0),
-			Type:       i32,
+			Value:      c.contextBlock.NewSExt(c.contextBlock.NewExtractValue(val, 0), i64.LLVM()),
+			Type:       i64,
 			IsVariable: false,
 		}
 	}
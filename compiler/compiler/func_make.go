@@ -0,0 +1,66 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/compiler/value"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// makeFuncCall compiles make([]T, len) and make([]T, len, cap). The items of
+// the slice are set to their zero values.
+func (c *Compiler) makeFuncCall(v *parser.CallNode) value.Value {
+	if len(v.Arguments) < 2 || len(v.Arguments) > 3 {
+		compilePanic("make() takes a type, a length and an optional capacity")
+	}
+
+	typeNode, ok := v.Arguments[0].(*parser.SliceTypeNode)
+	if !ok {
+		compilePanic("make() only supports slices")
+	}
+
+	sliceType := c.parserTypeToType(typeNode).(*types.Slice)
+
+	length := c.stringIndex(c.compileValue(v.Arguments[1]))
+	capacity := length
+	if len(v.Arguments) == 3 {
+		capacity = c.stringIndex(c.compileValue(v.Arguments[2]))
+	}
+
+	outOfRangeBlock := c.contextBlock.Parent.NewBlock(name.Block())
+	c.panic(outOfRangeBlock, "makeslice: len out of range")
+	outOfRangeBlock.NewUnreachable()
+
+	safeBlock := c.contextBlock.Parent.NewBlock(name.Block())
+
+	// Make sure that 0 <= len <= cap
+	lenIsPositive := c.contextBlock.NewICmp(enum.IPredSGE, length, constant.NewInt(llvmTypes.I64, 0))
+	lenIsInCap := c.contextBlock.NewICmp(enum.IPredSLE, length, capacity)
+	c.contextBlock.NewCondBr(c.contextBlock.NewAnd(lenIsPositive, lenIsInCap), safeBlock, outOfRangeBlock)
+	c.contextBlock = safeBlock
+
+	// calloc returns zeroed memory, at least one item is allocated so that
+	// the backing array is never null
+	allocCap := safeBlock.NewSelect(
+		safeBlock.NewICmp(enum.IPredSGT, capacity, constant.NewInt(llvmTypes.I64, 0)),
+		capacity,
+		constant.NewInt(llvmTypes.I64, 1),
+	)
+	backing := safeBlock.NewCall(c.externalFuncs.Calloc.Value.(llvmValue.Named), allocCap, constant.NewInt(llvmTypes.I64, sliceType.Type.Size()))
+
+	var res llvmValue.Value = constant.NewUndef(sliceType.LLVM().(*llvmTypes.StructType))
+	res = safeBlock.NewInsertValue(res, safeBlock.NewTrunc(length, llvmTypes.I32), 0)
+	res = safeBlock.NewInsertValue(res, safeBlock.NewTrunc(capacity, llvmTypes.I32), 1)
+	res = safeBlock.NewInsertValue(res, constant.NewInt(llvmTypes.I32, 0), 2)
+	res = safeBlock.NewInsertValue(res, safeBlock.NewBitCast(backing, llvmTypes.NewPointer(sliceType.Type.LLVM())), 3)
+
+	return value.Value{
+		Value: res,
+		Type:  sliceType,
+	}
+}
@@ -0,0 +1,69 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir/constant"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/internal"
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/compiler/value"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// panicFuncCall compiles the builtin panic(). The value is printed together
+// with a stack trace, and the program exits, like with runtime panics.
+func (c *Compiler) panicFuncCall(v *parser.CallNode) value.Value {
+	if len(v.Arguments) != 1 {
+		compilePanic("panic() takes exactly one argument")
+	}
+
+	// Save the value in a variable, so that methods can be called on it
+	argName := name.Var("panic-arg")
+	c.compileAllocNode(&parser.AllocNode{
+		Name: []string{argName},
+		Val:  []parser.Node{v.Arguments[0]},
+	})
+	arg := c.compileValue(&parser.NameNode{Name: argName})
+
+	printf := c.externalFuncs.Printf.Value.(llvmValue.Named)
+
+	switch t := arg.Type.(type) {
+	case *types.StringType:
+		str := c.cString(c.contextBlock, internal.LoadIfVariable(c.contextBlock, arg))
+		c.contextBlock.NewCall(printf, c.constantCString("panic: %s\n"), str)
+
+	case *types.Int:
+		val := internal.LoadIfVariable(c.contextBlock, arg)
+		if t.Type.BitSize < 64 {
+			if t.IsSigned() {
+				val = c.contextBlock.NewSExt(val, llvmTypes.I64)
+			} else {
+				val = c.contextBlock.NewZExt(val, llvmTypes.I64)
+			}
+		}
+		c.contextBlock.NewCall(printf, c.constantCString("panic: %lld\n"), val)
+
+	case *types.Interface:
+		if _, ok := t.RequiredMethods["Error"]; !ok {
+			compilePanic("panic() only supports strings, integers and errors")
+		}
+		msg := c.compileValue(&parser.CallNode{
+			Function: &parser.StructLoadElementNode{
+				Struct:      &parser.NameNode{Name: argName},
+				ElementName: "Error",
+			},
+		})
+		str := c.cString(c.contextBlock, internal.LoadIfVariable(c.contextBlock, msg))
+		c.contextBlock.NewCall(printf, c.constantCString("panic: %s\n"), str)
+
+	default:
+		compilePanic("panic() only supports strings, integers and errors")
+	}
+
+	c.printPanicTrace(c.contextBlock)
+	c.contextBlock.NewCall(c.externalFuncs.Exit.Value.(llvmValue.Named), constant.NewInt(llvmTypes.I32, 1))
+
+	return value.Value{Type: types.Void}
+}
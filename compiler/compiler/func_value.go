@@ -0,0 +1,339 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/internal"
+	"github.com/zegl/tre/compiler/compiler/internal/pointer"
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/compiler/value"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// compileCallee compiles the function of a call. Functions and methods are
+// called directly, and are not converted to function values.
+func (c *Compiler) compileCallee(node parser.Node) value.Value {
+	switch v := node.(type) {
+	case *parser.NameNode:
+		return c.compileNameNode(v)
+	case *parser.StructLoadElementNode:
+		return c.compileStructLoadElementNode(v)
+	case *parser.DefineFuncNode:
+		return c.compileDefineFuncNode(v)
+	}
+	return c.compileValue(node)
+}
+
+// funcValue converts functions and methods that are used as values, such as
+// in "f := strconv.Itoa" or "f := obj.Method", to function values
+func (c *Compiler) funcValue(v value.Value) value.Value {
+	switch t := v.Type.(type) {
+	case *types.Function:
+		if fn, ok := v.Value.(*ir.Func); ok && !v.IsVariable {
+			return value.Value{
+				Type:  t,
+				Value: newFuncValue(fn, constant.NewNull(llvmTypes.I8Ptr)),
+			}
+		}
+	case *types.Method:
+		return c.compileMethodValue(v, t)
+	case *types.InterfaceMethod:
+		return c.compileInterfaceMethodValue(v, t)
+	}
+	return v
+}
+
+// newFuncValue creates a function value of fn with the context ctx
+func newFuncValue(fn, ctx constant.Constant) constant.Constant {
+	return constant.NewStruct(types.FuncValue(), constant.NewBitCast(fn, llvmTypes.I8Ptr), ctx)
+}
+
+// contextIndex returns the index of the parameter with the receiver or the
+// context, it's after the pointers to the return values of functions with
+// multiple return values
+func contextIndex(fnType *types.Function) int {
+	if len(fnType.ReturnTypes) > 1 {
+		return len(fnType.ReturnTypes)
+	}
+	return 0
+}
+
+// spliceParams returns the pointer type of the LLVM function of fnType, where
+// remove parameters at the context index are replaced by the insert parameters
+func spliceParams(fnType *types.Function, remove int, insert ...llvmTypes.Type) *llvmTypes.PointerType {
+	sig := fnType.FuncType.(*llvmTypes.PointerType).ElemType.(*llvmTypes.FuncType)
+	i := contextIndex(fnType)
+
+	params := append([]llvmTypes.Type{}, sig.Params[:i]...)
+	params = append(params, insert...)
+	params = append(params, sig.Params[i+remove:]...)
+
+	return llvmTypes.NewPointer(llvmTypes.NewFunc(sig.RetType, params...))
+}
+
+// compileMethodValue binds the receiver of the method to a function value,
+// such as in "f := obj.Method". Value receivers are copied when the method
+// value is created.
+func (c *Compiler) compileMethodValue(v value.Value, method *types.Method) value.Value {
+	// v points to the receiver, see compileStructLoadElementNode
+	recv := v.Value
+	if !method.PointerReceiver {
+		recv = c.contextBlock.NewLoad(pointer.ElemType(recv), recv)
+	}
+
+	// The context is a pointer to the receiver, and is allocated on the heap
+	// as the method value can outlive the current function
+	ctx := c.contextBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), internal.SizeOf(recv.Type()))
+	c.contextBlock.NewStore(recv, c.contextBlock.NewBitCast(ctx, llvmTypes.NewPointer(recv.Type())))
+
+	fn := c.methodValueFunc(method)
+	var funcVal llvmValue.Value = newFuncValue(fn, constant.NewNull(llvmTypes.I8Ptr))
+	funcVal = c.contextBlock.NewInsertValue(funcVal, ctx, 1)
+
+	return value.Value{
+		Type: &types.Function{
+			FuncType:       spliceParams(method.Function, 1),
+			LlvmReturnType: method.Function.LlvmReturnType,
+			ReturnTypes:    method.Function.ReturnTypes,
+			IsVariadic:     method.Function.IsVariadic,
+			ArgumentTypes:  method.Function.ArgumentTypes[1:],
+		},
+		Value: funcVal,
+	}
+}
+
+// methodValueFunc returns the function that method values of the method
+// calls. It has the signature of the method, with the receiver replaced by
+// the context, that points to the receiver.
+func (c *Compiler) methodValueFunc(method *types.Method) *ir.Func {
+	if fn, ok := c.methodValueFuncs[method]; ok {
+		return fn
+	}
+
+	target := method.LlvmFunction.(*ir.Func)
+	recvIndex := contextIndex(method.Function)
+	recvType := target.Params[recvIndex].Type()
+
+	params := make([]*ir.Param, len(target.Params))
+	for i, p := range target.Params {
+		params[i] = ir.NewParam("", p.Type())
+	}
+	params[recvIndex] = ir.NewParam("ctx", llvmTypes.I8Ptr)
+
+	fn := c.module.NewFunc(target.Name()+"_value", target.Sig.RetType, params...)
+	block := fn.NewBlock(name.Block())
+
+	args := make([]llvmValue.Value, len(params))
+	for i, p := range params {
+		args[i] = p
+	}
+	recvPtr := block.NewBitCast(params[recvIndex], llvmTypes.NewPointer(recvType))
+	args[recvIndex] = block.NewLoad(recvType, recvPtr)
+
+	res := block.NewCall(target, args...)
+	if _, ok := target.Sig.RetType.(*llvmTypes.VoidType); ok {
+		block.NewRet(nil)
+	} else {
+		block.NewRet(res)
+	}
+
+	c.methodValueFuncs[method] = fn
+	return fn
+}
+
+// interfaceMethodType returns the type of the jump function of an interface
+// method, the receiver is passed as an *i8
+func interfaceMethodType(method *types.InterfaceMethod) *types.Function {
+	var returnType types.Type = types.Void
+	if len(method.ReturnTypes) == 1 {
+		returnType = method.ReturnTypes[0]
+	}
+
+	return &types.Function{
+		FuncType:       method.LlvmJumpFunction.Type(),
+		LlvmReturnType: returnType,
+		ReturnTypes:    method.ReturnTypes,
+		ArgumentTypes:  method.ArgumentTypes,
+	}
+}
+
+// compileInterfaceMethodValue binds the value in the interface to a function
+// value, such as in "f := iface.Method". The method is looked up in the method
+// table when the method value is created.
+func (c *Compiler) compileInterfaceMethodValue(v value.Value, method *types.InterfaceMethod) value.Value {
+	// v points to the interface, see compileStructLoadElementNode
+	data := c.contextBlock.NewLoad(llvmTypes.I8Ptr, c.contextBlock.NewGetElementPtr(pointer.ElemType(v.Value), v.Value,
+		constant.NewInt(llvmTypes.I32, 0),
+		constant.NewInt(llvmTypes.I32, 0),
+	))
+
+	// The context holds the jump function and the value, and is allocated on
+	// the heap as the method value can outlive the current function
+	ctxType := interfaceMethodValueContext()
+	ctx := c.contextBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), internal.SizeOf(ctxType))
+	ctxPtr := c.contextBlock.NewBitCast(ctx, llvmTypes.NewPointer(ctxType))
+	var ctxVal llvmValue.Value = constant.NewZeroInitializer(ctxType)
+	ctxVal = c.contextBlock.NewInsertValue(ctxVal, c.contextBlock.NewBitCast(method.LlvmJumpFunction, llvmTypes.I8Ptr), 0)
+	ctxVal = c.contextBlock.NewInsertValue(ctxVal, data, 1)
+	c.contextBlock.NewStore(ctxVal, ctxPtr)
+
+	jumpType := interfaceMethodType(method)
+	fn := c.interfaceMethodValueFunc(jumpType)
+	var funcVal llvmValue.Value = newFuncValue(fn, constant.NewNull(llvmTypes.I8Ptr))
+	funcVal = c.contextBlock.NewInsertValue(funcVal, ctx, 1)
+
+	return value.Value{
+		Type: &types.Function{
+			FuncType:       spliceParams(jumpType, 1),
+			LlvmReturnType: jumpType.LlvmReturnType,
+			ReturnTypes:    jumpType.ReturnTypes,
+			ArgumentTypes:  jumpType.ArgumentTypes,
+		},
+		Value: funcVal,
+	}
+}
+
+// interfaceMethodValueContext returns the type of the context of method values
+// of interface methods, { jump function, value }
+func interfaceMethodValueContext() *llvmTypes.StructType {
+	return llvmTypes.NewStruct(llvmTypes.I8Ptr, llvmTypes.I8Ptr)
+}
+
+// interfaceMethodValueFunc returns the function that method values of
+// interface methods with the type jumpType calls. It calls the jump function
+// in the context with the value in the context as the receiver.
+func (c *Compiler) interfaceMethodValueFunc(jumpType *types.Function) *ir.Func {
+	sig := jumpType.FuncType.(*llvmTypes.PointerType).ElemType.(*llvmTypes.FuncType)
+	if fn, ok := c.interfaceMethodValueFuncs[sig.String()]; ok {
+		return fn
+	}
+
+	recvIndex := contextIndex(jumpType)
+
+	params := make([]*ir.Param, len(sig.Params))
+	for i, p := range sig.Params {
+		params[i] = ir.NewParam("", p)
+	}
+	params[recvIndex] = ir.NewParam("ctx", llvmTypes.I8Ptr)
+
+	fn := c.module.NewFunc(name.Var("interface-method-value"), sig.RetType, params...)
+	block := fn.NewBlock(name.Block())
+
+	ctxType := interfaceMethodValueContext()
+	ctx := block.NewLoad(ctxType, block.NewBitCast(params[recvIndex], llvmTypes.NewPointer(ctxType)))
+	target := block.NewBitCast(block.NewExtractValue(ctx, 0), jumpType.FuncType)
+
+	args := make([]llvmValue.Value, len(params))
+	for i, p := range params {
+		args[i] = p
+	}
+	args[recvIndex] = block.NewExtractValue(ctx, 1)
+
+	res := block.NewCall(target, args...)
+	if _, ok := sig.RetType.(*llvmTypes.VoidType); ok {
+		block.NewRet(nil)
+	} else {
+		block.NewRet(res)
+	}
+
+	c.interfaceMethodValueFuncs[sig.String()] = fn
+	return fn
+}
+
+// methodExpressionReceiver returns the receiver type of method expressions,
+// T in "T.Method", and *T in "(*T).Method"
+func (c *Compiler) methodExpressionReceiver(node parser.Node) (types.Type, bool) {
+	switch v := node.(type) {
+	case *parser.NameNode:
+		if c.isTypeName(v) {
+			return c.parserTypeToType(&parser.SingleTypeNode{PackageName: v.Package, TypeName: v.Name}), true
+		}
+	case *parser.GroupNode:
+		if deref, ok := v.Item.(*parser.DereferenceNode); ok {
+			if typeName, ok := deref.Item.(*parser.NameNode); ok && c.isTypeName(typeName) {
+				return c.parserTypeToType(&parser.PointerTypeNode{
+					ValueType: &parser.SingleTypeNode{PackageName: typeName.Package, TypeName: typeName.Name},
+				}), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// compileMethodExpression compiles "T.Method" and "(*T).Method", to function
+// values that take the receiver as the first argument
+func (c *Compiler) compileMethodExpression(recvType types.Type, methodName string) value.Value {
+	methodOnType := recvType
+	ptr, isPointer := recvType.(*types.Pointer)
+	if isPointer {
+		methodOnType = ptr.Type
+	}
+
+	method, ok := methodOnType.GetMethod(methodName)
+	if !ok {
+		compilePanic(fmt.Sprintf("%s has no method %s", recvType.Name(), methodName))
+	}
+	if method.PointerReceiver && !isPointer {
+		compilePanic(fmt.Sprintf("invalid method expression %s.%s, the method has a pointer receiver", recvType.Name(), methodName))
+	}
+
+	fn := method.LlvmFunction.(*ir.Func)
+	fnType := method.Function
+
+	// The method has a value receiver, the jump function that is used by
+	// interfaces loads the receiver from the pointer
+	if isPointer && !method.PointerReceiver {
+		fn = method.Function.JumpFunction
+		fnType = &types.Function{
+			FuncType:       spliceParams(method.Function, 1, recvType.LLVM()),
+			LlvmReturnType: method.Function.LlvmReturnType,
+			ReturnTypes:    method.Function.ReturnTypes,
+			IsVariadic:     method.Function.IsVariadic,
+			ArgumentTypes:  append([]types.Type{recvType}, method.Function.ArgumentTypes[1:]...),
+		}
+	}
+
+	return value.Value{
+		Type:  fnType,
+		Value: newFuncValue(fn, constant.NewNull(llvmTypes.I8Ptr)),
+	}
+}
+
+// callFuncValue calls the function value funcVal of the type fnType. The
+// context is passed before the arguments if it's not nil.
+func (c *Compiler) callFuncValue(fnType *types.Function, funcVal llvmValue.Value, args []llvmValue.Value) llvmValue.Value {
+	fn := c.contextBlock.NewExtractValue(funcVal, 0)
+	ctx := c.contextBlock.NewExtractValue(funcVal, 1)
+
+	callBlock := c.contextBlock.Parent.NewBlock(name.Block() + "-call-func")
+	callWithContextBlock := c.contextBlock.Parent.NewBlock(name.Block() + "-call-func-context")
+	afterBlock := c.contextBlock.Parent.NewBlock(name.Block() + "-after-call")
+
+	hasContext := c.contextBlock.NewICmp(enum.IPredNE, ctx, constant.NewNull(llvmTypes.I8Ptr))
+	c.contextBlock.NewCondBr(hasContext, callWithContextBlock, callBlock)
+
+	res := callBlock.NewCall(callBlock.NewBitCast(fn, fnType.FuncType), args...)
+	callBlock.NewBr(afterBlock)
+
+	i := contextIndex(fnType)
+	contextArgs := append([]llvmValue.Value{}, args[:i]...)
+	contextArgs = append(contextArgs, ctx)
+	contextArgs = append(contextArgs, args[i:]...)
+	contextFn := callWithContextBlock.NewBitCast(fn, spliceParams(fnType, 0, llvmTypes.I8Ptr))
+	contextRes := callWithContextBlock.NewCall(contextFn, contextArgs...)
+	callWithContextBlock.NewBr(afterBlock)
+
+	c.contextBlock = afterBlock
+
+	if _, ok := res.Type().(*llvmTypes.VoidType); ok {
+		return res
+	}
+	return afterBlock.NewPhi(ir.NewIncoming(res, callBlock), ir.NewIncoming(contextRes, callWithContextBlock))
+}
@@ -0,0 +1,131 @@
+package compiler
+
+import (
+	"math/big"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// compileIntDivision compiles x / y and x % y, where both operands are
+// integers of the type t. The quotient is truncated towards zero, and the
+// remainder has the same sign as x. Division by zero causes a runtime panic.
+//
+// The most negative signed integer divided by -1 overflows, and is undefined
+// behaviour in LLVM. In Go the quotient wraps around to x, and the remainder
+// is 0.
+func (c *Compiler) compileIntDivision(operator parser.Operator, t types.Type, x, y llvmValue.Value) llvmValue.Value {
+	intType := x.Type().(*llvmTypes.IntType)
+
+	if cnst, ok := y.(*constant.Int); ok {
+		if cnst.X.Sign() == 0 {
+			compilePanic("invalid operation: division by zero")
+		}
+	} else {
+		divideByZero := c.contextBlock.Parent.NewBlock(name.Block() + "-divide-by-zero")
+		c.panic(divideByZero, "integer divide by zero")
+		divideByZero.NewUnreachable()
+
+		safeBlock := c.contextBlock.Parent.NewBlock(name.Block())
+		isZero := c.contextBlock.NewICmp(enum.IPredEQ, y, constant.NewInt(intType, 0))
+		c.contextBlock.NewCondBr(isZero, divideByZero, safeBlock)
+		c.contextBlock = safeBlock
+	}
+
+	if !t.IsSigned() {
+		if operator == parser.OP_REMAINDER {
+			return c.contextBlock.NewURem(x, y)
+		}
+		return c.contextBlock.NewUDiv(x, y)
+	}
+
+	// Divide by 1 instead of -1, x % 1 is 0, and x / 1 is negated afterwards
+	minusOne := constant.NewInt(intType, -1)
+	isMinusOne := c.contextBlock.NewICmp(enum.IPredEQ, y, minusOne)
+	safeY := c.contextBlock.NewSelect(isMinusOne, constant.NewInt(intType, 1), y)
+
+	if operator == parser.OP_REMAINDER {
+		return c.contextBlock.NewSRem(x, safeY)
+	}
+
+	quotient := c.contextBlock.NewSDiv(x, safeY)
+	negated := c.contextBlock.NewSub(constant.NewInt(intType, 0), x)
+	return c.contextBlock.NewSelect(isMinusOne, negated, quotient)
+}
+
+// compileShift compiles x << count and x >> count, where x is an integer of
+// the type t. Signed integers are shifted to the right arithmetically, the
+// sign bit is copied into the vacated bits.
+//
+// The count can be of any integer type. Shifting by the size of x or more
+// shifts out all bits, the result is 0, or -1 if a negative signed integer
+// is shifted to the right. A negative count causes a runtime panic.
+func (c *Compiler) compileShift(operator parser.Operator, t types.Type, x llvmValue.Value, countType types.Type, count llvmValue.Value) llvmValue.Value {
+	intType := x.Type().(*llvmTypes.IntType)
+	countIntType, ok := count.Type().(*llvmTypes.IntType)
+	if !ok {
+		compilePanic("invalid operation: shift count type " + countType.Name() + ", must be integer")
+	}
+	bitSize := int64(intType.BitSize)
+
+	shift := func(x, count llvmValue.Value) llvmValue.Value {
+		switch {
+		case operator == parser.OP_LEFT_SHIFT:
+			return c.contextBlock.NewShl(x, count)
+		case t.IsSigned():
+			return c.contextBlock.NewAShr(x, count)
+		default:
+			return c.contextBlock.NewLShr(x, count)
+		}
+	}
+
+	// The result of constant counts is known at compile time
+	if cnst, ok := count.(*constant.Int); ok {
+		if cnst.X.Sign() < 0 {
+			compilePanic("invalid operation: negative shift count " + cnst.X.String())
+		}
+		if cnst.X.Cmp(big.NewInt(bitSize)) < 0 {
+			return shift(x, constant.NewInt(intType, cnst.X.Int64()))
+		}
+		if operator == parser.OP_RIGHT_SHIFT && t.IsSigned() {
+			return shift(x, constant.NewInt(intType, bitSize-1))
+		}
+		return constant.NewInt(intType, 0)
+	}
+
+	if countType.IsSigned() {
+		negativeShift := c.contextBlock.Parent.NewBlock(name.Block() + "-negative-shift")
+		c.panic(negativeShift, "negative shift amount")
+		negativeShift.NewUnreachable()
+
+		safeBlock := c.contextBlock.Parent.NewBlock(name.Block())
+		isNegative := c.contextBlock.NewICmp(enum.IPredSLT, count, constant.NewInt(countIntType, 0))
+		c.contextBlock.NewCondBr(isNegative, negativeShift, safeBlock)
+		c.contextBlock = safeBlock
+	}
+
+	// The count is compared with the size before it's converted to the type
+	// of x, a wider count could otherwise be truncated to a small count
+	tooLarge := c.contextBlock.NewICmp(enum.IPredUGE, count, constant.NewInt(countIntType, bitSize))
+
+	switch {
+	case countIntType.BitSize > intType.BitSize:
+		count = c.contextBlock.NewTrunc(count, intType)
+	case countIntType.BitSize < intType.BitSize:
+		count = c.contextBlock.NewZExt(count, intType)
+	}
+
+	// Shifting right by size-1 fills all bits with the sign bit
+	if operator == parser.OP_RIGHT_SHIFT && t.IsSigned() {
+		count = c.contextBlock.NewSelect(tooLarge, constant.NewInt(intType, bitSize-1), count)
+		return shift(x, count)
+	}
+
+	return c.contextBlock.NewSelect(tooLarge, constant.NewInt(intType, 0), shift(x, count))
+}
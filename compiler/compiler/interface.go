@@ -3,14 +3,46 @@ package compiler
 import (
 	"fmt"
 
-	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	llvmTypes "github.com/llir/llvm/ir/types"
-	"github.com/zegl/tre/compiler/compiler/internal/pointer"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/internal"
 	"github.com/zegl/tre/compiler/compiler/types"
 	"github.com/zegl/tre/compiler/compiler/value"
 )
 
+// assignableValue converts v to the type of the variable, argument or return
+// value that it's assigned to. Untyped nil becomes the zero value of the target
+// type, constant numbers gets the type of the target, and values are wrapped
+// in interfaces if needed.
+func (c *Compiler) assignableValue(v value.Value, targetType types.Type) value.Value {
+	if _, ok := v.Type.(*types.UntypedNil); ok {
+		return c.nilValue(targetType)
+	}
+
+	// Constant numbers, such as literals, gets the type of the target
+	if cnst, ok := value.ConstantAs(v, targetType); ok {
+		return cnst
+	}
+
+	return c.valueToInterfaceValue(v, targetType)
+}
+
+// nilValue returns nil as a value of targetType
+func (c *Compiler) nilValue(targetType types.Type) value.Value {
+	switch targetType.(type) {
+	case *types.Interface, *types.Pointer, *types.Slice, *types.Function:
+		return value.Value{
+			Type:  targetType,
+			Value: constant.NewZeroInitializer(targetType.LLVM()),
+		}
+	}
+
+	compilePanic(fmt.Sprintf("cannot use nil as %s value", targetType.Name()))
+	return value.Value{}
+}
+
 func (c *Compiler) valueToInterfaceValue(v value.Value, targetType types.Type) value.Value {
 
 	// Don't do anything if the target is not an interface
@@ -19,65 +51,70 @@ func (c *Compiler) valueToInterfaceValue(v value.Value, targetType types.Type) v
 		return v
 	}
 
-	// Don't do anything if the src already is an interface
-	if _, sourceIsInterface := v.Type.(*types.Interface); sourceIsInterface {
-		return v
+	// Convert from one interface type to another
+	if srcIface, sourceIsInterface := v.Type.(*types.Interface); sourceIsInterface {
+		if interfaceKey(srcIface) == interfaceKey(iface) {
+			return v
+		}
+		return c.interfaceToInterfaceValue(v, iface)
 	}
 
-	val := v.Value
+	// Untyped constants are stored with their default type
+	v = value.UntypedDefault(v)
+
+	llvmVal := internal.LoadIfVariable(c.contextBlock, v)
 
-	// Convert to pointer variable
-	if !v.IsVariable {
-		ptrAlloca := c.contextBlock.NewAlloca(v.Type.LLVM())
-		c.contextBlock.NewStore(val, ptrAlloca)
-		val = ptrAlloca
+	// Pointers are stored directly in the interface. Other values are copied
+	// to the heap, so that the interface can outlive the function that created it.
+	var data llvmValue.Value
+	if _, isPointer := v.Type.(*types.Pointer); isPointer {
+		data = c.contextBlock.NewBitCast(llvmVal, llvmTypes.I8Ptr)
+	} else {
+		data = c.contextBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), internal.SizeOf(llvmVal.Type()))
+		c.contextBlock.NewStore(llvmVal, c.contextBlock.NewBitCast(data, llvmTypes.NewPointer(llvmVal.Type())))
 	}
 
-	ifaceStruct := c.contextBlock.NewAlloca(targetType.LLVM())
+	backingTypeID := c.registerInterfaceType(v.Type)
 
-	dataPtr := c.contextBlock.NewGetElementPtr(pointer.ElemType(ifaceStruct), ifaceStruct, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 0))
-	bitcastedVal := c.contextBlock.NewBitCast(val, llvmTypes.NewPointer(llvmTypes.I8))
-	c.contextBlock.NewStore(bitcastedVal, dataPtr)
+	var table constant.Constant = constant.NewNull(llvmTypes.NewPointer(iface.JumpTable()))
+	if len(iface.RequiredMethods) > 0 {
+		tableGlobal, ok := c.interfaceJumpTable(iface, backingTypeID)
+		if !ok {
+			compilePanic(fmt.Sprintf("%s can not be used as %s, is missing methods", v.Type.Name(), targetType.Name()))
+		}
+		table = tableGlobal
+	}
 
-	dataTypePtr := c.contextBlock.NewGetElementPtr(pointer.ElemType(ifaceStruct), ifaceStruct, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 1))
+	return c.newInterfaceValue(iface, data, constant.NewInt(llvmTypes.I32, backingTypeID), table)
+}
 
-	backingTypID := getTypeID(v.Type.Name())
-	c.contextBlock.NewStore(constant.NewInt(llvmTypes.I32, backingTypID), dataTypePtr)
+// interfaceToInterfaceValue converts the interface value v to iface, the jump
+// table for iface is looked up at runtime based on the type of the value in v
+func (c *Compiler) interfaceToInterfaceValue(v value.Value, iface *types.Interface) value.Value {
+	ifaceVal := internal.LoadIfVariable(c.contextBlock, v)
+	data := c.contextBlock.NewExtractValue(ifaceVal, 0)
+	typeID := c.contextBlock.NewExtractValue(ifaceVal, 1)
 
-	// Create interface jump table if needed
-	var funcTableAlloca *ir.InstAlloca
+	var table llvmValue.Value = constant.NewNull(llvmTypes.NewPointer(iface.JumpTable()))
 	if len(iface.RequiredMethods) > 0 {
-		funcTablePtr := c.contextBlock.NewGetElementPtr(pointer.ElemType(ifaceStruct), ifaceStruct,
-			constant.NewInt(llvmTypes.I32, 0),
-			constant.NewInt(llvmTypes.I32, 2),
-		)
-		funcTableAlloca = c.contextBlock.NewAlloca(iface.JumpTable())
-		c.contextBlock.NewStore(funcTableAlloca, funcTablePtr)
+		table = c.contextBlock.NewCall(c.interfaceTableFunc(iface), typeID)
 	}
 
-	useFuncsFromType := v.Type
-
-	// Pointer receiver methods are added to their "parent" type
-	if ptrType, ok := useFuncsFromType.(*types.Pointer); ok {
-		useFuncsFromType = ptrType.Type
-	}
+	return c.newInterfaceValue(iface, data, typeID, table)
+}
 
-	// Add methods to the iface table
-	for methodIndex, methodName := range iface.SortedRequiredMethods() {
-		functionPointer := c.contextBlock.NewGetElementPtr(pointer.ElemType(funcTableAlloca), funcTableAlloca,
-			constant.NewInt(llvmTypes.I32, 0),
-			constant.NewInt(llvmTypes.I32, int64(methodIndex)),
-		)
+// newInterfaceValue creates a variable of the interface type iface
+func (c *Compiler) newInterfaceValue(iface *types.Interface, data, typeID, table llvmValue.Value) value.Value {
+	var ifaceVal llvmValue.Value = constant.NewUndef(iface.LLVM().(*llvmTypes.StructType))
+	ifaceVal = c.contextBlock.NewInsertValue(ifaceVal, data, 0)
+	ifaceVal = c.contextBlock.NewInsertValue(ifaceVal, typeID, 1)
+	ifaceVal = c.contextBlock.NewInsertValue(ifaceVal, table, 2)
 
-		m, ok := useFuncsFromType.GetMethod(methodName)
-		if !ok {
-			panic(fmt.Sprintf("%s can not be used as %s, is missing %s method", useFuncsFromType.Name(), targetType.Name(), methodName))
-		}
-		c.contextBlock.NewStore(m.Function.JumpFunction, functionPointer)
-	}
+	ifaceStruct := c.entryBlockAlloca(iface.LLVM())
+	c.contextBlock.NewStore(ifaceVal, ifaceStruct)
 
 	return value.Value{
-		Type:       targetType,
+		Type:       iface,
 		Value:      ifaceStruct,
 		IsVariable: true,
 	}
@@ -0,0 +1,13 @@
+package internal
+
+import (
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+)
+
+// SizeOf returns the size of t in bytes (including padding) as an i64 constant.
+// The size is the offset of the second element in an array of t's starting at null.
+func SizeOf(t types.Type) constant.Constant {
+	second := constant.NewGetElementPtr(t, constant.NewNull(types.NewPointer(t)), constant.NewInt(types.I32, 1))
+	return constant.NewPtrToInt(second, types.I64)
+}
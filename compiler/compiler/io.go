@@ -9,6 +9,6 @@ import (
 
 func (c *Compiler) printFuncCall(v *parser.CallNode) value.Value {
 	arg := c.compileValue(v.Arguments[0])
-	syscall.Print(c.contextBlock, arg, c.GOOS)
+	syscall.Print(c.contextBlock, arg, c.GOOS, c.GOARCH)
 	return value.Value{Type: types.Void}
 }
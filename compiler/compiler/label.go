@@ -0,0 +1,191 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// label is a labeled statement in the current function
+type label struct {
+	// The start of the labeled statement, is jumped to by goto. The block is
+	// created when the label is first used, goto can be used before the label.
+	block   *ir.Block
+	defined bool
+
+	// Is set while compiling a labeled for loop or switch, and is jumped to
+	// by "break Label" and "continue Label"
+	breakBlock    *ir.Block
+	continueBlock *ir.Block
+}
+
+// label returns the label with the name labelName in the current function
+func (c *Compiler) label(labelName string) *label {
+	if l, ok := c.contextLabels[labelName]; ok {
+		return l
+	}
+
+	l := &label{block: c.contextBlock.Parent.NewBlock(name.Block() + "-label-" + labelName)}
+	c.contextLabels[labelName] = l
+	return l
+}
+
+func (c *Compiler) compileLabelNode(v *parser.LabelNode) {
+	l := c.label(v.Label)
+	if l.defined {
+		compilePanic(fmt.Sprintf("label %s already defined", v.Label))
+	}
+	l.defined = true
+
+	// Continue into the labeled statement, unless the previous statement has
+	// returned. The terminator can otherwise be a placeholder that is replaced.
+	if _, isRet := c.contextBlock.Term.(*ir.TermRet); !isRet {
+		c.contextBlock.NewBr(l.block)
+	}
+	c.contextBlock = l.block
+
+	switch s := v.Statement.(type) {
+	case nil:
+	case *parser.ForNode:
+		c.compileForNode(s, l)
+	case *parser.SwitchNode:
+		c.compileSwitchNode(s, l)
+	default:
+		c.compile([]parser.Node{s})
+	}
+
+	// break and continue can only use the label inside of the statement
+	l.breakBlock = nil
+	l.continueBlock = nil
+}
+
+func (c *Compiler) compileGotoNode(v *parser.GotoNode) {
+	c.contextBlock.NewBr(c.label(v.Label).block)
+	c.unreachableAfterJump()
+}
+
+// jumpPosition is the position of a statement in the function body, by the
+// statement lists (blocks) that it's nested in, from the outermost one
+type jumpPosition []blockIndex
+
+// blockIndex is the index of a statement in a statement list
+type blockIndex struct {
+	block int
+	index int
+}
+
+// jumpChecker finds the gotos, labels and variable declarations in a function
+// body, to check the gotos before the function is compiled
+type jumpChecker struct {
+	blocks int
+	labels map[string]jumpPosition
+	gotos  []gotoStatement
+
+	// The indexes of the variable declarations in each block
+	declarations map[int][]int
+}
+
+type gotoStatement struct {
+	label string
+	pos   jumpPosition
+}
+
+// checkGotos panics if a goto in body jumps into a block, or over a variable
+// declaration, as the variable would be in scope at the label without being
+// initialized
+func checkGotos(body []parser.Node) {
+	j := &jumpChecker{
+		labels:       map[string]jumpPosition{},
+		declarations: map[int][]int{},
+	}
+	j.walkBlock(body, nil)
+
+	for _, g := range j.gotos {
+		labelPos, ok := j.labels[g.label]
+		if !ok {
+			// Is reported by checkLabels
+			continue
+		}
+		label := labelPos[len(labelPos)-1]
+
+		// The goto has to be in the block of the label, or in a block nested
+		// in it
+		from := -1
+		for _, pos := range g.pos {
+			if pos.block == label.block {
+				from = pos.index
+			}
+		}
+		if from == -1 {
+			compilePanic(fmt.Sprintf("goto %s jumps into block", g.label))
+		}
+
+		for _, declIndex := range j.declarations[label.block] {
+			if declIndex > from && declIndex < label.index {
+				compilePanic(fmt.Sprintf("goto %s jumps over variable declaration", g.label))
+			}
+		}
+	}
+}
+
+func (j *jumpChecker) walkBlock(stmts []parser.Node, parent jumpPosition) {
+	block := j.blocks
+	j.blocks++
+
+	for i, stmt := range stmts {
+		pos := append(append(jumpPosition{}, parent...), blockIndex{block: block, index: i})
+		j.walkStatement(stmt, pos)
+	}
+}
+
+func (j *jumpChecker) walkStatement(stmt parser.Node, pos jumpPosition) {
+	block := pos[len(pos)-1]
+
+	switch s := stmt.(type) {
+	case *parser.AllocNode:
+		if !s.IsConst {
+			j.declarations[block.block] = append(j.declarations[block.block], block.index)
+		}
+	case *parser.AllocGroup:
+		for _, alloc := range s.Allocs {
+			j.walkStatement(alloc, pos)
+		}
+	case *parser.LabelNode:
+		j.labels[s.Label] = pos
+		if s.Statement != nil {
+			j.walkStatement(s.Statement, pos)
+		}
+	case *parser.GotoNode:
+		j.gotos = append(j.gotos, gotoStatement{label: s.Label, pos: pos})
+	case *parser.ConditionNode:
+		j.walkBlock(s.True, pos)
+		j.walkBlock(s.False, pos)
+	case *parser.ForNode:
+		j.walkBlock(s.Block, pos)
+	case *parser.SwitchNode:
+		for _, switchCase := range s.Cases {
+			j.walkBlock(switchCase.Body, pos)
+		}
+		j.walkBlock(s.DefaultBody, pos)
+	}
+}
+
+// checkLabels panics if a label that is used by goto is not defined in the
+// current function
+func (c *Compiler) checkLabels() {
+	for labelName, l := range c.contextLabels {
+		if !l.defined {
+			compilePanic(fmt.Sprintf("label %s not defined", labelName))
+		}
+	}
+}
+
+// unreachableAfterJump continues in a new block after break, continue and goto.
+// The block is not jumped to, so that statements after the jump are never
+// executed.
+func (c *Compiler) unreachableAfterJump() {
+	c.contextBlock = c.contextBlock.Parent.NewBlock(name.Block() + "-unreachable")
+}
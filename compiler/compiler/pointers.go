@@ -2,6 +2,9 @@ package compiler
 
 import (
 	"fmt"
+
+	llvmTypes "github.com/llir/llvm/ir/types"
+
 	"github.com/zegl/tre/compiler/compiler/internal/pointer"
 	"github.com/zegl/tre/compiler/compiler/name"
 
@@ -14,32 +17,20 @@ func (c *Compiler) compileGetReferenceNode(v *parser.GetReferenceNode) value.Val
 	val := c.compileValue(v.Item)
 
 	// Case where allocation is not necessary, as all LLVM values are pointers by default
+	// Pointer variables are pointing to the memory that is holding the pointer
 	if val.IsVariable {
-		if _, ok := val.Type.(*types.Pointer); !ok {
-			return value.Value{
-				Type: &types.Pointer{
-					Type:                  val.Type,
-					IsNonAllocDereference: true,
-				},
-				Value:      val.Value,
-				IsVariable: false,
-			}
-		}
-
-		if structType, ok := val.Type.(*types.Struct); ok && structType.IsHeapAllocated {
-			return value.Value{
-				Type: &types.Pointer{
-					Type:                  val.Type,
-					IsNonAllocDereference: true,
-				},
-				Value:      val.Value,
-				IsVariable: false,
-			}
+		return value.Value{
+			Type: &types.Pointer{
+				Type:                  val.Type,
+				IsNonAllocDereference: true,
+			},
+			Value:      val.Value,
+			IsVariable: false,
 		}
 	}
 
 	// One extra allocation is neccesary
-	newSrc := c.contextBlock.NewAlloca(val.Type.LLVM())
+	newSrc := c.entryBlockAlloca(val.Type.LLVM())
 	newSrc.SetName(name.Var("reference-alloca"))
 	c.contextBlock.NewStore(val.Value, newSrc)
 
@@ -65,8 +56,15 @@ func (c *Compiler) compileDereferenceNode(v *parser.DereferenceNode) value.Value
 			}
 		}
 
+		// Pointer variables, such as "var p *int", are holding the address in
+		// memory that has to be loaded first
+		ptr := val.Value
+		if val.IsVariable && ptr.Type().Equal(llvmTypes.NewPointer(ptrVal.LLVM())) {
+			ptr = c.contextBlock.NewLoad(ptrVal.LLVM(), ptr)
+		}
+
 		return value.Value{
-			Value:      c.contextBlock.NewLoad(pointer.ElemType(val.Value), val.Value),
+			Value:      c.contextBlock.NewLoad(pointer.ElemType(ptr), ptr),
 			Type:       ptrVal.Type,
 			IsVariable: false,
 		}
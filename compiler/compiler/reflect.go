@@ -0,0 +1,482 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/compiler/value"
+)
+
+// The external package has functions to inspect the value in an interface at
+// runtime, such as external.TypeKind(a) and external.Field(a, i). They are used
+// by packages like fmt, that needs to print values of any type. Like the other
+// type information functions, they are generated when the compilation is done.
+
+// Kinds of types, as returned by external.TypeKind. They are available as
+// external.KindBool and so on, see kindNames.
+const (
+	kindInvalid = iota // nil
+	kindBool
+	kindInt
+	kindUint
+	kindString
+	kindPointer
+	kindStruct
+	kindSlice
+	kindArray
+	kindFunc
+	kindFloat
+)
+
+var kindNames = map[string]int64{
+	"KindInvalid": kindInvalid,
+	"KindBool":    kindBool,
+	"KindInt":     kindInt,
+	"KindUint":    kindUint,
+	"KindString":  kindString,
+	"KindPointer": kindPointer,
+	"KindStruct":  kindStruct,
+	"KindSlice":   kindSlice,
+	"KindArray":   kindArray,
+	"KindFunc":    kindFunc,
+	"KindFloat":   kindFloat,
+}
+
+type reflectFunc struct {
+	fn *ir.Func
+
+	// gen generates the implementation of fn for a type. data is the pointer
+	// that is stored in the interface. Returns false if there is nothing to
+	// generate for the type, the default result is then used.
+	gen func(block *ir.Block, data llvmValue.Value, t types.Type) bool
+
+	defaultResult llvmValue.Value
+}
+
+// addReflection adds the functions that inspects values in interfaces to the
+// external package
+func (c *Compiler) addReflection() {
+	emptyIface := &types.Interface{}
+
+	for kindName, kind := range kindNames {
+		c.packages["external"].DefinePkgVar(kindName, value.Value{
+			Type:  types.I64,
+			Value: constant.NewInt(llvmTypes.I64, kind),
+		})
+	}
+
+	add := func(funcName string, returnType types.Type, argTypes []types.Type, defaultResult llvmValue.Value, gen func(*ir.Block, llvmValue.Value, types.Type) bool) {
+		params := []*ir.Param{ir.NewParam("a", emptyIface.LLVM())}
+		for _, argType := range argTypes[1:] {
+			params = append(params, ir.NewParam(name.Var("arg"), argType.LLVM()))
+		}
+
+		fn := c.module.NewFunc("reflect-"+strings.ToLower(funcName), returnType.LLVM(), params...)
+
+		c.defineExternalFunc(funcName, fn, returnType, argTypes)
+
+		c.typeInfo.reflectFuncs = append(c.typeInfo.reflectFuncs, &reflectFunc{fn: fn, gen: gen, defaultResult: defaultResult})
+	}
+
+	ifaceArg := []types.Type{emptyIface}
+	ifaceIntArgs := []types.Type{emptyIface, types.I64}
+	zero := constant.NewInt(llvmTypes.I64, 0)
+	emptyString := c.constantString("")
+	nilIface := constant.NewZeroInitializer(emptyIface.LLVM())
+
+	// TypeName(a interface{}) string returns the name of the type, such as
+	// "int" or "*main.Foo". A nil interface is named "<nil>".
+	add("TypeName", types.String, ifaceArg, c.constantString("<nil>"), func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		block.NewRet(c.constantString(goTypeName(t)))
+		return true
+	})
+
+	// TypeKind(a interface{}) int returns the kind of the type, see kindInvalid
+	add("TypeKind", types.I64, ifaceArg, zero, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		block.NewRet(constant.NewInt(llvmTypes.I64, typeKind(t)))
+		return true
+	})
+
+	// NumField(a interface{}) int returns the number of fields in a struct
+	add("NumField", types.I64, ifaceArg, zero, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		structType, ok := t.(*types.Struct)
+		if !ok {
+			return false
+		}
+		block.NewRet(constant.NewInt(llvmTypes.I64, int64(len(structType.MemberIndexes))))
+		return true
+	})
+
+	// FieldName(a interface{}, i int) string returns the name of the i:th field
+	add("FieldName", types.String, ifaceIntArgs, emptyString, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		structType, ok := t.(*types.Struct)
+		if !ok {
+			return false
+		}
+		c.reflectFieldSwitch(block, structType, emptyString, func(fieldBlock *ir.Block, fieldName string, index int) {
+			fieldBlock.NewRet(c.constantString(fieldName))
+		})
+		return true
+	})
+
+	// Field(a interface{}, i int) interface{} returns the value of the i:th field
+	add("Field", emptyIface, ifaceIntArgs, nilIface, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		structType, ok := t.(*types.Struct)
+		if !ok {
+			return false
+		}
+		structPtr := block.NewBitCast(data, llvmTypes.NewPointer(structType.LLVM()))
+		c.reflectFieldSwitch(block, structType, nilIface, func(fieldBlock *ir.Block, fieldName string, index int) {
+			fieldPtr := fieldBlock.NewGetElementPtr(structType.LLVM(), structPtr,
+				constant.NewInt(llvmTypes.I32, 0),
+				constant.NewInt(llvmTypes.I32, int64(index)),
+			)
+			fieldBlock.NewRet(c.reflectInterface(fieldBlock, fieldPtr, structType.Members[fieldName]))
+		})
+		return true
+	})
+
+	// Len(a interface{}) int returns the length of a string, slice or array
+	add("Len", types.I64, ifaceArg, zero, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		switch tt := t.(type) {
+		case *types.StringType, *types.Slice:
+			length := block.NewExtractValue(c.reflectLoad(block, data, t), 0)
+			if length.Type().Equal(llvmTypes.I64) {
+				block.NewRet(length)
+			} else {
+				block.NewRet(block.NewSExt(length, llvmTypes.I64))
+			}
+		case *types.Array:
+			block.NewRet(constant.NewInt(llvmTypes.I64, int64(tt.Len)))
+		default:
+			return false
+		}
+		return true
+	})
+
+	// Index(a interface{}, i int) interface{} returns the i:th item in a slice
+	// or array, i must be within the bounds
+	add("Index", emptyIface, ifaceIntArgs, nilIface, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		index := block.Parent.Params[1]
+
+		switch tt := t.(type) {
+		case *types.Slice:
+			slice := c.reflectLoad(block, data, t)
+			offset := block.NewSExt(block.NewExtractValue(slice, 2), llvmTypes.I64)
+			backing := block.NewExtractValue(slice, 3)
+			itemPtr := block.NewGetElementPtr(tt.Type.LLVM(), backing, block.NewAdd(offset, index))
+			block.NewRet(c.reflectInterface(block, itemPtr, tt.Type))
+		case *types.Array:
+			arrayPtr := block.NewBitCast(data, llvmTypes.NewPointer(tt.LLVM()))
+			itemPtr := block.NewGetElementPtr(tt.LLVM(), arrayPtr, constant.NewInt(llvmTypes.I64, 0), index)
+			block.NewRet(c.reflectInterface(block, itemPtr, tt.Type))
+		default:
+			return false
+		}
+		return true
+	})
+
+	// Swap(a interface{}, i, j int) swaps the i:th and j:th items in a slice,
+	// i and j must be within the bounds
+	add("Swap", types.Void, []types.Type{emptyIface, types.I64, types.I64}, nil, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		sliceType, ok := t.(*types.Slice)
+		if !ok {
+			return false
+		}
+
+		slice := c.reflectLoad(block, data, t)
+		offset := block.NewSExt(block.NewExtractValue(slice, 2), llvmTypes.I64)
+		backing := block.NewExtractValue(slice, 3)
+		iPtr := block.NewGetElementPtr(sliceType.Type.LLVM(), backing, block.NewAdd(offset, block.Parent.Params[1]))
+		jPtr := block.NewGetElementPtr(sliceType.Type.LLVM(), backing, block.NewAdd(offset, block.Parent.Params[2]))
+		iVal := block.NewLoad(sliceType.Type.LLVM(), iPtr)
+		jVal := block.NewLoad(sliceType.Type.LLVM(), jPtr)
+		block.NewStore(jVal, iPtr)
+		block.NewStore(iVal, jPtr)
+		block.NewRet(nil)
+		return true
+	})
+
+	// Elem(a interface{}) interface{} returns the value that a pointer points
+	// to, the pointer must not be nil
+	add("Elem", emptyIface, ifaceArg, nilIface, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		ptrType, ok := t.(*types.Pointer)
+		if !ok {
+			return false
+		}
+		elemPtr := block.NewBitCast(data, llvmTypes.NewPointer(ptrType.Type.LLVM()))
+		block.NewRet(c.reflectInterface(block, elemPtr, ptrType.Type))
+		return true
+	})
+
+	// ValueInt(a interface{}) int returns the value of an integer or a bool.
+	// Unsigned integers are zero extended.
+	add("ValueInt", types.I64, ifaceArg, zero, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		switch t.(type) {
+		case *types.Int, *types.BoolType:
+		default:
+			return false
+		}
+
+		val := c.reflectLoad(block, data, t)
+		switch {
+		case val.Type().Equal(llvmTypes.I64):
+			block.NewRet(val)
+		case t.IsSigned():
+			block.NewRet(block.NewSExt(val, llvmTypes.I64))
+		default:
+			block.NewRet(block.NewZExt(val, llvmTypes.I64))
+		}
+		return true
+	})
+
+	// ValueFloat(a interface{}) float64 returns the value of a float
+	add("ValueFloat", types.F64, ifaceArg, constant.NewFloat(llvmTypes.Double, 0), func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		floatType, ok := t.(*types.Float)
+		if !ok {
+			return false
+		}
+		val := c.reflectLoad(block, data, t)
+		if floatType.TypeSize < types.F64.TypeSize {
+			val = block.NewFPExt(val, llvmTypes.Double)
+		}
+		block.NewRet(val)
+		return true
+	})
+
+	// ValueString(a interface{}) string returns the value of a string
+	add("ValueString", types.String, ifaceArg, emptyString, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		if _, ok := t.(*types.StringType); !ok {
+			return false
+		}
+		block.NewRet(c.reflectLoad(block, data, t))
+		return true
+	})
+
+	// ValuePointer(a interface{}) uintptr returns the address of a pointer, or
+	// of the backing array of a slice, or of a function
+	add("ValuePointer", types.Uintptr, ifaceArg, zero, func(block *ir.Block, data llvmValue.Value, t types.Type) bool {
+		switch t.(type) {
+		case *types.Pointer:
+			block.NewRet(block.NewPtrToInt(data, llvmTypes.I64))
+		case *types.Slice:
+			block.NewRet(block.NewPtrToInt(block.NewExtractValue(c.reflectLoad(block, data, t), 3), llvmTypes.I64))
+		case *types.Function:
+			block.NewRet(block.NewPtrToInt(block.NewExtractValue(c.reflectLoad(block, data, t), 0), llvmTypes.I64))
+		default:
+			return false
+		}
+		return true
+	})
+}
+
+// reflectLoad loads the value of type t that is stored in an interface
+func (c *Compiler) reflectLoad(block *ir.Block, data llvmValue.Value, t types.Type) llvmValue.Value {
+	if _, ok := t.(*types.Pointer); ok {
+		return block.NewBitCast(data, t.LLVM())
+	}
+	return block.NewLoad(t.LLVM(), block.NewBitCast(data, llvmTypes.NewPointer(t.LLVM())))
+}
+
+// reflectInterface returns an empty interface with the value of type t that ptr
+// points to. The interface is referring to the same memory as ptr.
+func (c *Compiler) reflectInterface(block *ir.Block, ptr llvmValue.Value, t types.Type) llvmValue.Value {
+	emptyIface := &types.Interface{}
+
+	var data, typeID llvmValue.Value
+	switch t.(type) {
+	case *types.Interface:
+		iface := block.NewLoad(t.LLVM(), ptr)
+		data = block.NewExtractValue(iface, 0)
+		typeID = block.NewExtractValue(iface, 1)
+	case *types.Pointer:
+		data = block.NewBitCast(block.NewLoad(t.LLVM(), ptr), llvmTypes.I8Ptr)
+		typeID = constant.NewInt(llvmTypes.I32, c.registerInterfaceType(t))
+	default:
+		data = block.NewBitCast(ptr, llvmTypes.I8Ptr)
+		typeID = constant.NewInt(llvmTypes.I32, c.registerInterfaceType(t))
+	}
+
+	var res llvmValue.Value = constant.NewUndef(emptyIface.LLVM().(*llvmTypes.StructType))
+	res = block.NewInsertValue(res, data, 0)
+	res = block.NewInsertValue(res, typeID, 1)
+	res = block.NewInsertValue(res, constant.NewNull(llvmTypes.NewPointer(emptyIface.JumpTable())), 2)
+	return res
+}
+
+// reflectFieldSwitch switches on the field index argument, gen generates the
+// code for each field. defaultResult is returned if the index is out of range.
+func (c *Compiler) reflectFieldSwitch(block *ir.Block, structType *types.Struct, defaultResult llvmValue.Value, gen func(fieldBlock *ir.Block, fieldName string, index int)) {
+	defaultBlock := block.Parent.NewBlock(name.Block())
+	defaultBlock.NewRet(defaultResult)
+
+	var cases []*ir.Case
+	for _, fieldName := range structFieldNames(structType) {
+		index := structType.MemberIndexes[fieldName]
+		fieldBlock := block.Parent.NewBlock(name.Block())
+		gen(fieldBlock, fieldName, index)
+		cases = append(cases, ir.NewCase(constant.NewInt(llvmTypes.I64, int64(index)), fieldBlock))
+	}
+
+	block.NewSwitch(block.Parent.Params[1], defaultBlock, cases...)
+}
+
+// finalizeReflection generates the bodies of the reflection functions
+func (c *Compiler) finalizeReflection(typeIDs []int64) {
+	for _, rf := range c.typeInfo.reflectFuncs {
+		fn := rf.fn
+		fn.Blocks = nil
+
+		entry := fn.NewBlock(name.Block())
+		defaultBlock := fn.NewBlock(name.Block())
+		defaultBlock.NewRet(rf.defaultResult)
+
+		data := entry.NewExtractValue(fn.Params[0], 0)
+
+		var cases []*ir.Case
+		for _, typeID := range typeIDs {
+			block := fn.NewBlock(name.Block())
+			if !rf.gen(block, data, c.typeInfo.types[typeID]) {
+				fn.Blocks = fn.Blocks[:len(fn.Blocks)-1]
+				continue
+			}
+			cases = append(cases, ir.NewCase(constant.NewInt(llvmTypes.I32, typeID), block))
+		}
+
+		entry.NewSwitch(entry.NewExtractValue(fn.Params[0], 1), defaultBlock, cases...)
+	}
+}
+
+// registerReachableTypes registers all types that can be reached from the
+// values in interfaces, such as the types of struct fields, so that they can
+// be inspected with the reflection functions
+func (c *Compiler) registerReachableTypes() {
+	var visit func(t types.Type)
+	visit = func(t types.Type) {
+		switch t.(type) {
+		case *types.Interface, *types.MultiValue, *types.VoidType, *types.UntypedNil, *types.UntypedConstantNumber, *types.Method:
+			return
+		}
+
+		typeID := getTypeID(t.Name())
+		if _, ok := c.typeInfo.types[typeID]; ok {
+			return
+		}
+		c.registerInterfaceType(t)
+		visitReachableTypes(t, visit)
+	}
+
+	var registered []types.Type
+	for _, t := range c.typeInfo.types {
+		registered = append(registered, t)
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name() < registered[j].Name() })
+
+	for _, t := range registered {
+		visitReachableTypes(t, visit)
+	}
+}
+
+func visitReachableTypes(t types.Type, visit func(types.Type)) {
+	switch tt := t.(type) {
+	case *types.Pointer:
+		visit(tt.Type)
+	case *types.Slice:
+		visit(tt.Type)
+	case *types.Array:
+		visit(tt.Type)
+	case *types.Struct:
+		for _, fieldName := range structFieldNames(tt) {
+			visit(tt.Members[fieldName])
+		}
+	}
+}
+
+// structFieldNames returns the names of the fields in declaration order
+func structFieldNames(t *types.Struct) []string {
+	names := make([]string, len(t.MemberIndexes))
+	for fieldName, index := range t.MemberIndexes {
+		names[index] = fieldName
+	}
+	return names
+}
+
+// typeKind returns the kind of t, see kindInvalid
+func typeKind(t types.Type) int64 {
+	switch tt := t.(type) {
+	case *types.BoolType:
+		return kindBool
+	case *types.Int:
+		if tt.Signed {
+			return kindInt
+		}
+		return kindUint
+	case *types.StringType:
+		return kindString
+	case *types.Pointer:
+		return kindPointer
+	case *types.Struct:
+		return kindStruct
+	case *types.Slice:
+		return kindSlice
+	case *types.Array:
+		return kindArray
+	case *types.Function:
+		return kindFunc
+	case *types.Float:
+		return kindFloat
+	}
+	return kindInvalid
+}
+
+// goTypeName returns the name of t as it's written in Go, such as "[]string".
+// int and int64 are the same type in tre, and are named int.
+func goTypeName(t types.Type) string {
+	switch tt := t.(type) {
+	case *types.Int:
+		if tt == types.I64 {
+			return "int"
+		}
+		return tt.TypeName
+	case *types.Pointer:
+		return "*" + goTypeName(tt.Type)
+	case *types.Slice:
+		return "[]" + goTypeName(tt.Type)
+	case *types.Array:
+		return fmt.Sprintf("[%d]%s", tt.Len, goTypeName(tt.Type))
+	case *types.Interface:
+		if tt.SourceName != "" {
+			return tt.SourceName
+		}
+		return "interface {}"
+	case *types.Struct:
+		if tt.SourceName != "" {
+			return tt.SourceName
+		}
+		var fields []string
+		for _, fieldName := range structFieldNames(tt) {
+			fields = append(fields, fieldName+" "+goTypeName(tt.Members[fieldName]))
+		}
+		if len(fields) == 0 {
+			return "struct {}"
+		}
+		return "struct { " + strings.Join(fields, "; ") + " }"
+	}
+	return t.Name()
+}
+
+// constantString returns a tre string constant
+func (c *Compiler) constantString(s string) constant.Constant {
+	return constant.NewStruct(types.ModuleStringType.(*llvmTypes.StructType),
+		constant.NewInt(llvmTypes.I64, int64(len(s))),
+		c.constantCString(s),
+	)
+}
@@ -18,18 +18,17 @@ import (
 )
 
 func (c *Compiler) compileSubstring(src value.Value, v *parser.SliceArrayNode) value.Value {
-
-	var originalLength *ir.InstExtractValue
-
 	// Get backing array from string type
 	srcVal := internal.LoadIfVariable(c.contextBlock, src)
-	if src.Type.Name() == "string" {
-		originalLength = c.contextBlock.NewExtractValue(srcVal, 0)
-		srcVal = c.contextBlock.NewExtractValue(srcVal, 1)
-	}
+	originalLength := c.contextBlock.NewExtractValue(srcVal, 0)
+	srcPtr := c.contextBlock.NewExtractValue(srcVal, 1)
 
-	start := c.compileValue(v.Start)
-	startVar := internal.LoadIfVariable(c.contextBlock, start)
+	startVar := c.stringIndex(c.compileValue(v.Start))
+
+	var endVar llvmValue.Value = originalLength
+	if v.HasEnd {
+		endVar = c.stringIndex(c.compileValue(v.End))
+	}
 
 	outsideOfLengthBr := c.contextBlock.Parent.NewBlock(name.Block())
 	c.panic(outsideOfLengthBr, "substring out of bounds")
@@ -38,56 +37,45 @@ func (c *Compiler) compileSubstring(src value.Value, v *parser.SliceArrayNode) v
 	// Block jumped to after the bounds checks
 	safeBlock := c.contextBlock.Parent.NewBlock(name.Block())
 
-	// Make sure that the offset is within the string length
-	startIsInBounds := c.contextBlock.NewICmp(enum.IPredSLE, startVar, originalLength)
-
-	var endIsInBounds llvmValue.Value
-	endIsInBounds = constant.NewInt(llvmTypes.I1, 1)
-
-	var length llvmValue.Value
-	if v.HasEnd {
-		end := c.compileValue(v.End)
-		endVar := internal.LoadIfVariable(c.contextBlock, end)
-		endIsInBounds = c.contextBlock.NewICmp(enum.IPredSLE, endVar, originalLength)
-
-		length = safeBlock.NewSub(endVar, startVar)
-	} else {
-		length = safeBlock.NewSub(originalLength, startVar)
-	}
-
-	// Check end is in bounds in this block
-	checkEndIsInBoundsBlock := c.contextBlock.Parent.NewBlock(name.Block())
-	c.contextBlock.NewCondBr(startIsInBounds, checkEndIsInBoundsBlock, outsideOfLengthBr)
-	checkEndIsInBoundsBlock.NewCondBr(endIsInBounds, safeBlock, outsideOfLengthBr)
+	// Make sure that 0 <= start <= end <= len(src)
+	startIsInBounds := c.contextBlock.NewICmp(enum.IPredSGE, startVar, constant.NewInt(llvmTypes.I64, 0))
+	startIsBeforeEnd := c.contextBlock.NewICmp(enum.IPredSLE, startVar, endVar)
+	endIsInBounds := c.contextBlock.NewICmp(enum.IPredSLE, endVar, originalLength)
+	isInBounds := c.contextBlock.NewAnd(c.contextBlock.NewAnd(startIsInBounds, startIsBeforeEnd), endIsInBounds)
+	c.contextBlock.NewCondBr(isInBounds, safeBlock, outsideOfLengthBr)
 
 	c.contextBlock = safeBlock
 
-	offset := safeBlock.NewGetElementPtr(pointer.ElemType(srcVal), srcVal, startVar)
+	offset := safeBlock.NewGetElementPtr(pointer.ElemType(srcPtr), srcPtr, startVar)
+	length := safeBlock.NewSub(endVar, startVar)
 
-	dst := safeBlock.NewCall(c.externalFuncs.Strndup.Value.(llvmValue.Named), offset, length)
+	// Copy the bytes, and add a null terminator
+	dst := safeBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), safeBlock.NewAdd(length, constant.NewInt(llvmTypes.I64, 1)))
+	safeBlock.NewCall(c.externalFuncs.Memcpy.Value.(llvmValue.Named), dst, offset, length)
+	safeBlock.NewStore(constant.NewInt(llvmTypes.I8, 0), safeBlock.NewGetElementPtr(llvmTypes.I8, dst, length))
 
-	// Convert *i8 to %string
-	sType, ok := c.packages["global"].GetPkgType("string", true)
-	if !ok {
-		panic("string type not found")
-	}
-	alloc := c.contextBlock.NewAlloca(sType.LLVM())
+	var res llvmValue.Value = constant.NewUndef(types.ModuleStringType.(*llvmTypes.StructType))
+	res = safeBlock.NewInsertValue(res, length, 0)
+	res = safeBlock.NewInsertValue(res, dst, 1)
 
-	// Save length of the string
-	lenItem := safeBlock.NewGetElementPtr(pointer.ElemType(alloc), alloc, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 0))
-	lenItem.SetName(name.Var("len"))
-	safeBlock.NewStore(length, lenItem) // TODO
+	return value.Value{
+		Value: res,
+		Type:  types.String,
+	}
+}
 
-	// Save i8* version of string
-	strItem := safeBlock.NewGetElementPtr(pointer.ElemType(alloc), alloc, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 1))
-	strItem.SetName(name.Var("str"))
-	safeBlock.NewStore(dst, strItem)
+// stringIndex converts an integer used as a position in a string to an i64
+func (c *Compiler) stringIndex(index value.Value) llvmValue.Value {
+	val := internal.LoadIfVariable(c.contextBlock, index)
 
-	return value.Value{
-		Value:      safeBlock.NewLoad(pointer.ElemType(alloc), alloc),
-		Type:       sType,
-		IsVariable: false,
+	intType, ok := val.Type().(*llvmTypes.IntType)
+	if !ok || intType.BitSize >= 64 {
+		return val
 	}
+	if index.Type.IsSigned() {
+		return c.contextBlock.NewSExt(val, llvmTypes.I64)
+	}
+	return c.contextBlock.NewZExt(val, llvmTypes.I64)
 }
 
 func (c *Compiler) compileSliceArray(src value.Value, v *parser.SliceArrayNode) value.Value {
@@ -95,7 +83,7 @@ func (c *Compiler) compileSliceArray(src value.Value, v *parser.SliceArrayNode)
 
 	sliceType := internal.Slice(arrType.Type.LLVM())
 
-	alloc := c.contextBlock.NewAlloca(sliceType)
+	alloc := c.entryBlockAlloca(sliceType)
 
 	startIndex := c.compileValue(v.Start)
 	endIndex := c.compileValue(v.End)
@@ -138,6 +126,53 @@ func (c *Compiler) compileSliceArray(src value.Value, v *parser.SliceArrayNode)
 	return res
 }
 
+// compileSliceSlice slices a slice, such as s[1:3]. The new slice shares the
+// backing array with the original slice.
+func (c *Compiler) compileSliceSlice(src value.Value, v *parser.SliceArrayNode) value.Value {
+	srcVal := internal.LoadIfVariable(c.contextBlock, src)
+	if _, ok := srcVal.Type().(*llvmTypes.PointerType); ok {
+		srcVal = c.contextBlock.NewLoad(pointer.ElemType(srcVal), srcVal)
+	}
+
+	srcLen := c.contextBlock.NewSExt(c.contextBlock.NewExtractValue(srcVal, 0), llvmTypes.I64)
+	srcCap := c.contextBlock.NewSExt(c.contextBlock.NewExtractValue(srcVal, 1), llvmTypes.I64)
+	srcOffset := c.contextBlock.NewExtractValue(srcVal, 2)
+
+	startVar := c.stringIndex(c.compileValue(v.Start))
+
+	var endVar llvmValue.Value = srcLen
+	if v.HasEnd {
+		endVar = c.stringIndex(c.compileValue(v.End))
+	}
+
+	outsideOfCapBr := c.contextBlock.Parent.NewBlock(name.Block())
+	c.panic(outsideOfCapBr, "slice bounds out of range")
+	outsideOfCapBr.NewUnreachable()
+
+	safeBlock := c.contextBlock.Parent.NewBlock(name.Block())
+
+	// Make sure that 0 <= start <= end <= cap(src)
+	startIsInBounds := c.contextBlock.NewICmp(enum.IPredSGE, startVar, constant.NewInt(llvmTypes.I64, 0))
+	startIsBeforeEnd := c.contextBlock.NewICmp(enum.IPredSLE, startVar, endVar)
+	endIsInBounds := c.contextBlock.NewICmp(enum.IPredSLE, endVar, srcCap)
+	isInBounds := c.contextBlock.NewAnd(c.contextBlock.NewAnd(startIsInBounds, startIsBeforeEnd), endIsInBounds)
+	c.contextBlock.NewCondBr(isInBounds, safeBlock, outsideOfCapBr)
+
+	c.contextBlock = safeBlock
+
+	start32 := safeBlock.NewTrunc(startVar, llvmTypes.I32)
+
+	var res llvmValue.Value = srcVal
+	res = safeBlock.NewInsertValue(res, safeBlock.NewTrunc(safeBlock.NewSub(endVar, startVar), llvmTypes.I32), 0)
+	res = safeBlock.NewInsertValue(res, safeBlock.NewTrunc(safeBlock.NewSub(srcCap, startVar), llvmTypes.I32), 1)
+	res = safeBlock.NewInsertValue(res, safeBlock.NewAdd(srcOffset, start32), 2)
+
+	return value.Value{
+		Value: res,
+		Type:  src.Type,
+	}
+}
+
 func (c *Compiler) appendFuncCall(v *parser.CallNode) value.Value {
 	// 1. Grow the backing array if necessary (cap == len)
 	// 1.1. Create a new array (at least double the size).
@@ -173,7 +208,7 @@ func (c *Compiler) appendFuncCall(v *parser.CallNode) value.Value {
 	appendExistingBlock.Term = ir.NewUnreachable()
 
 	// The slice that we're appending to will be stored here
-	sliceToAppendToLLVM := c.contextBlock.NewAlloca(input.Type.LLVM())
+	sliceToAppendToLLVM := c.entryBlockAlloca(input.Type.LLVM())
 	sliceToAppendToLLVM.SetName(name.Var("sliceToAppendTo"))
 
 	if isSelfAssign {
@@ -208,8 +243,6 @@ func (c *Compiler) appendFuncCall(v *parser.CallNode) value.Value {
 
 	c.generateAppendToSliceBlock(addToSliceBlock, sliceToAppendToLLVM, inputSlice, v)
 
-	c.contextBlock = addToSliceBlock
-
 	return value.Value{
 		Value:      sliceToAppendToLLVM,
 		Type:       inputSlice,
@@ -221,7 +254,7 @@ func (c *Compiler) generateCopySliceBlock(copySliceBlock *ir.Block, appendToSlic
 	c.contextBlock = copySliceBlock
 
 	// Allocate a new slice
-	newSlice := copySliceBlock.NewAlloca(input.Type.LLVM())
+	newSlice := c.entryBlockAlloca(input.Type.LLVM())
 	newSlice.SetName(name.Var("copy-to-new-slice"))
 
 	lenVal := copySliceBlock.NewGetElementPtr(pointer.ElemType(newSlice), newSlice, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 0))
@@ -252,9 +285,12 @@ func (c *Compiler) generateCopySliceBlock(copySliceBlock *ir.Block, appendToSlic
 	// Allocate a new backing array, and copy the data from the previous one to the new
 	// TODO: Make sure that cap is large enough for the new data
 
-	twiceCap := copySliceBlock.NewMul(loadedPrevCap, constant.NewInt(llvmTypes.I32, 2))
+	// Empty slices without a backing array starts with a cap of 2
+	var twiceCap llvmValue.Value = copySliceBlock.NewMul(loadedPrevCap, constant.NewInt(llvmTypes.I32, 2))
+	twiceCapIsEmpty := copySliceBlock.NewICmp(enum.IPredULT, twiceCap, constant.NewInt(llvmTypes.I32, 2))
+	twiceCap = copySliceBlock.NewSelect(twiceCapIsEmpty, constant.NewInt(llvmTypes.I32, 2), twiceCap)
 	twiceCap64 := copySliceBlock.NewZExt(twiceCap, i64.LLVM())
-	sizeTimesCap := copySliceBlock.NewMul(twiceCap64, constant.NewInt(llvmTypes.I64, input.Type.Size()))
+	sizeTimesCap := copySliceBlock.NewMul(twiceCap64, internal.SizeOf(inputSlice.Type.LLVM()))
 	mallocatedSpaceRaw := copySliceBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), sizeTimesCap)
 	mallocatedSpaceRaw.SetName(name.Var("slice-grow"))
 
@@ -269,10 +305,14 @@ func (c *Compiler) generateCopySliceBlock(copySliceBlock *ir.Block, appendToSlic
 	prevBackArray.SetName(name.Var("prev-backarr"))
 
 	prevBackArrayLoaded := copySliceBlock.NewLoad(pointer.ElemType(prevBackArray), prevBackArray)
+
+	// Items are copied from the offset of the previous slice
+	prevSliceOffset := copySliceBlock.NewGetElementPtr(pointer.ElemType(input.Value), input.Value, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 2))
+	loadedPrevOffset := copySliceBlock.NewLoad(pointer.ElemType(prevSliceOffset), prevSliceOffset)
 	prevBackArrayCasted := copySliceBlock.NewBitCast(prevBackArrayLoaded, llvmTypes.NewPointer(i8.LLVM()))
 	prevBackArrayCasted.SetName(name.Var("prev-backarray-casted"))
 
-	copyIndex := copySliceBlock.NewAlloca(llvmTypes.I32)
+	copyIndex := c.entryBlockAlloca(llvmTypes.I32)
 	copySliceBlock.NewStore(constant.NewInt(llvmTypes.I32, 0), copyIndex)
 
 	loadedNewSlice := copySliceBlock.NewLoad(pointer.ElemType(newSlice), newSlice)
@@ -281,7 +321,7 @@ func (c *Compiler) generateCopySliceBlock(copySliceBlock *ir.Block, appendToSlic
 	// Copy all items, one by one
 
 	copyBlock := copySliceBlock.Parent.NewBlock(name.Block() + "-copy-slice-bytes")
-	prevArrItemPtr := copyBlock.NewGetElementPtr(pointer.ElemType(prevBackArrayLoaded), prevBackArrayLoaded, copyBlock.NewLoad(pointer.ElemType(copyIndex), copyIndex))
+	prevArrItemPtr := copyBlock.NewGetElementPtr(pointer.ElemType(prevBackArrayLoaded), prevBackArrayLoaded, copyBlock.NewAdd(loadedPrevOffset, copyBlock.NewLoad(pointer.ElemType(copyIndex), copyIndex)))
 	newArrItemPtr := copyBlock.NewGetElementPtr(pointer.ElemType(bitcasted), bitcasted, copyBlock.NewLoad(pointer.ElemType(copyIndex), copyIndex))
 	copyBlock.NewStore(copyBlock.NewLoad(pointer.ElemType(prevArrItemPtr), prevArrItemPtr), newArrItemPtr)
 	a := copyBlock.NewAdd(constant.NewInt(llvmTypes.I32, 1), copyBlock.NewLoad(pointer.ElemType(copyIndex), copyIndex))
@@ -289,7 +329,9 @@ func (c *Compiler) generateCopySliceBlock(copySliceBlock *ir.Block, appendToSlic
 	cmp := copyBlock.NewICmp(enum.IPredULT, a, loadedPrevLen)
 	copyBlock.NewCondBr(cmp, copyBlock, appendToSliceBlock)
 
-	copySliceBlock.NewBr(copyBlock)
+	// Only copy if there is something to copy
+	hasItems := copySliceBlock.NewICmp(enum.IPredUGT, loadedPrevLen, constant.NewInt(llvmTypes.I32, 0))
+	copySliceBlock.NewCondBr(hasItems, copyBlock, appendToSliceBlock)
 }
 
 func (c *Compiler) generateAppendToSliceBlock(appendToSliceBlock *ir.Block, sliceToAppendTo llvmValue.Value, inputSlice *types.Slice, v *parser.CallNode) {
@@ -312,7 +354,12 @@ func (c *Compiler) generateAppendToSliceBlock(appendToSliceBlock *ir.Block, slic
 
 	backingArrayAppendPtr.SetName(name.Var("backingarrayptr"))
 	loadedPtr := appendToSliceBlock.NewLoad(pointer.ElemType(backingArrayAppendPtr), backingArrayAppendPtr)
-	storePtr := appendToSliceBlock.NewGetElementPtr(pointer.ElemType(loadedPtr), loadedPtr, sliceLen)
+
+	// The slice starts at offset in the backing array
+	sliceOffsetPtr := appendToSliceBlock.NewGetElementPtr(pointer.ElemType(sliceToAppendTo), sliceToAppendTo, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 2))
+	sliceOffset := appendToSliceBlock.NewLoad(pointer.ElemType(sliceOffsetPtr), sliceOffsetPtr)
+
+	storePtr := appendToSliceBlock.NewGetElementPtr(pointer.ElemType(loadedPtr), loadedPtr, appendToSliceBlock.NewAdd(sliceOffset, sliceLen))
 	storePtr.SetName(name.Var("store-ptr"))
 
 	// Add type of items in slice to the context
@@ -320,19 +367,23 @@ func (c *Compiler) generateAppendToSliceBlock(appendToSliceBlock *ir.Block, slic
 
 	addItem := c.compileValue(v.Arguments[1])
 
+	// Compiling the item can create new blocks, such as bounds checks, continue
+	// in the block that the item was compiled to
+	block := c.contextBlock
+
 	// Convert type if necessary
-	addItem = c.valueToInterfaceValue(addItem, inputSlice.Type)
-	addItemVal := internal.LoadIfVariable(appendToSliceBlock, addItem)
+	addItem = c.assignableValue(addItem, inputSlice.Type)
+	addItemVal := internal.LoadIfVariable(block, addItem)
 
 	// Pop assigning type stack
 	c.contextAssignDest = c.contextAssignDest[0 : len(c.contextAssignDest)-1]
 
-	appendToSliceBlock.NewStore(addItemVal, storePtr)
+	block.NewStore(addItemVal, storePtr)
 
 	// Increase len
 
-	newLen := appendToSliceBlock.NewAdd(sliceLen, constant.NewInt(llvmTypes.I32, 1))
-	appendToSliceBlock.NewStore(newLen, sliceLenPtr)
+	newLen := block.NewAdd(sliceLen, constant.NewInt(llvmTypes.I32, 1))
+	block.NewStore(newLen, sliceLenPtr)
 }
 
 func (c *Compiler) compileInitializeSliceNode(v *parser.InitializeSliceNode) value.Value {
@@ -361,7 +412,7 @@ func (c *Compiler) compileInitializeSliceWithValues(itemType types.Type, values
 	}
 
 	// Create slice with cap set to the requested size
-	allocSlice := c.contextBlock.NewAlloca(sliceType.LLVM())
+	allocSlice := c.entryBlockAlloca(sliceType.LLVM())
 	sliceType.SliceZero(c.contextBlock, c.externalFuncs.Malloc.Value.(llvmValue.Named), len(values), allocSlice)
 
 	backingArrayPtr := c.contextBlock.NewGetElementPtr(pointer.ElemType(allocSlice), allocSlice,
@@ -377,7 +428,7 @@ func (c *Compiler) compileInitializeSliceWithValues(itemType types.Type, values
 		storePtr := c.contextBlock.NewGetElementPtr(pointer.ElemType(loadedPtr), loadedPtr, constant.NewInt(llvmTypes.I32, int64(i)))
 		storePtr.SetName(name.Var(fmt.Sprintf("storeptr-%d", i)))
 
-		val = c.valueToInterfaceValue(val, itemType)
+		val = c.assignableValue(val, itemType)
 		v := internal.LoadIfVariable(c.contextBlock, val)
 		c.contextBlock.NewStore(v, storePtr)
 	}
@@ -396,3 +447,55 @@ func (c *Compiler) compileInitializeSliceWithValues(itemType types.Type, values
 		IsVariable: true,
 	}
 }
+
+// compileStringToBytes converts a string to a byte slice, such as []byte("foo").
+// The bytes are copied to a new backing array.
+func (c *Compiler) compileStringToBytes(src value.Value, sliceType *types.Slice) value.Value {
+	if src.Type.Name() != "string" {
+		panic(fmt.Sprintf("cannot convert %s to %s", src.Type.Name(), sliceType.Name()))
+	}
+
+	srcVal := internal.LoadIfVariable(c.contextBlock, src)
+	length := c.contextBlock.NewExtractValue(srcVal, 0)
+	data := c.contextBlock.NewExtractValue(srcVal, 1)
+
+	backing := c.contextBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), length)
+	c.contextBlock.NewCall(c.externalFuncs.Memcpy.Value.(llvmValue.Named), backing, data, length)
+
+	length32 := c.contextBlock.NewTrunc(length, i32.LLVM())
+
+	var res llvmValue.Value = constant.NewUndef(sliceType.LLVM().(*llvmTypes.StructType))
+	res = c.contextBlock.NewInsertValue(res, length32, 0)
+	res = c.contextBlock.NewInsertValue(res, length32, 1)
+	res = c.contextBlock.NewInsertValue(res, constant.NewInt(llvmTypes.I32, 0), 2)
+	res = c.contextBlock.NewInsertValue(res, c.contextBlock.NewBitCast(backing, llvmTypes.NewPointer(sliceType.Type.LLVM())), 3)
+
+	return value.Value{
+		Value: res,
+		Type:  sliceType,
+	}
+}
+
+// compileBytesToString converts a byte slice to a string, such as string(b)
+func (c *Compiler) compileBytesToString(src value.Value) value.Value {
+	srcVal := internal.LoadIfVariable(c.contextBlock, src)
+	length := c.contextBlock.NewSExt(c.contextBlock.NewExtractValue(srcVal, 0), i64.LLVM())
+	offset := c.contextBlock.NewExtractValue(srcVal, 2)
+	backing := c.contextBlock.NewExtractValue(srcVal, 3)
+
+	data := c.contextBlock.NewGetElementPtr(pointer.ElemType(backing), backing, offset)
+
+	// Copy the bytes, and add a null terminator
+	dst := c.contextBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), c.contextBlock.NewAdd(length, constant.NewInt(llvmTypes.I64, 1)))
+	c.contextBlock.NewCall(c.externalFuncs.Memcpy.Value.(llvmValue.Named), dst, c.contextBlock.NewBitCast(data, llvmTypes.I8Ptr), length)
+	c.contextBlock.NewStore(constant.NewInt(llvmTypes.I8, 0), c.contextBlock.NewGetElementPtr(llvmTypes.I8, dst, length))
+
+	var res llvmValue.Value = constant.NewUndef(types.ModuleStringType.(*llvmTypes.StructType))
+	res = c.contextBlock.NewInsertValue(res, length, 0)
+	res = c.contextBlock.NewInsertValue(res, dst, 1)
+
+	return value.Value{
+		Value: res,
+		Type:  types.String,
+	}
+}
@@ -0,0 +1,389 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/strings"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// Runtime panics print the call stack, by following the chain of frame pointers
+// from the panic. Every call from a function in the program is a "call site",
+// and is surrounded by two labels in the generated assembly. The addresses of
+// the labels are stored in the tre_callsites section, together with the index
+// of the function name, file and line of the call in the callsites table.
+//
+// The return address of each frame is looked up among the call sites, and the
+// walk stops at the first frame that was not called from the program, such as
+// the start of main or of a thread.
+//
+// The linker only defines the bounds of the section on ELF targets. On other
+// targets, only the location of the panic is printed.
+
+// Frames deeper than this are not printed
+const maxStackFrames = 1024
+
+// callSiteSection is the section with the { start, end, index } of every call site
+const callSiteSection = "tre_callsites"
+
+// callSiteHidden is the index of call sites in functions that are not written
+// in tre, such as interface method jumps. The frames are not printed.
+const callSiteHidden = -1
+
+// stackTrace is the state used to print stack traces
+type stackTrace struct {
+	enabled bool
+
+	// Location of each call, by the call instruction
+	callSites map[*ir.InstCall]callSite
+
+	// The function that is currently being compiled
+	scope callSiteScope
+
+	// printStackTrace prints the frames of the calls that lead to it
+	printStackTrace *ir.Func
+}
+
+// callSite is the location of a call in the source code
+type callSite struct {
+	funcName string
+	file     string
+	line     int
+}
+
+// callSiteScope is the function whose calls are being located
+type callSiteScope struct {
+	fn       *ir.Func
+	funcName string
+
+	// The number of instructions in each block that have been located, and the
+	// number of blocks that existed the last time
+	located       map[*ir.Block]int
+	locatedBlocks int
+}
+
+func (c *Compiler) addStackTrace() {
+	c.stackTrace = stackTrace{
+		enabled:         c.GOOS == "linux",
+		callSites:       make(map[*ir.InstCall]callSite),
+		printStackTrace: c.module.NewFunc("print-stack-trace", llvmTypes.Void),
+	}
+}
+
+// printPanicTrace prints the stack trace of a panic in the current function
+func (c *Compiler) printPanicTrace(block *ir.Block) {
+	block.NewCall(c.externalFuncs.Printf.Value.(llvmValue.Named), c.constantCString("\ngoroutine 1 [running]:\n"))
+
+	if c.stackTrace.enabled {
+		block.NewCall(c.stackTrace.printStackTrace)
+		return
+	}
+
+	// Without the call sites, the location of the panic is known when compiling
+	block.NewCall(c.externalFuncs.Printf.Value.(llvmValue.Named),
+		c.constantCString("%s()\n\t%s:%lld\n"),
+		c.constantCString(c.stackFrameFuncName()),
+		c.constantCString(c.stackFrameFile()),
+		constant.NewInt(llvmTypes.I64, int64(c.contextLine)),
+	)
+}
+
+// constantCString returns a pointer to a null terminated string constant
+func (c *Compiler) constantCString(s string) constant.Constant {
+	glob, ok := c.stringConstants[s]
+	if !ok {
+		glob = c.module.NewGlobalDef(strings.NextStringName(), strings.Constant(s))
+		glob.Immutable = true
+		c.stringConstants[s] = glob
+	}
+
+	zero := constant.NewInt(llvmTypes.I32, 0)
+	return constant.NewGetElementPtr(glob.ContentType, glob, zero, zero)
+}
+
+// stackFrameFuncName returns the name of the current function, as it's printed
+// in stack traces
+func (c *Compiler) stackFrameFuncName() string {
+	if c.contextFuncName == "" {
+		return c.currentPackageName + ".init"
+	}
+	return c.contextFuncName
+}
+
+// stackFrameFile returns the path of the current file, as it's printed in stack traces
+func (c *Compiler) stackFrameFile() string {
+	if c.contextFilePath == "" {
+		return "<autogenerated>"
+	}
+	return c.contextFilePath
+}
+
+// beginCallSites makes fn the function whose calls are located, and returns the
+// previous scope, which is restored by endCallSites
+func (c *Compiler) beginCallSites(funcName string, fn *ir.Func) callSiteScope {
+	c.locateCallSites()
+
+	prev := c.stackTrace.scope
+	c.stackTrace.scope = callSiteScope{
+		fn:       fn,
+		funcName: funcName,
+		located:  make(map[*ir.Block]int),
+	}
+	return prev
+}
+
+// endCallSites is called when the function has been compiled, prev is the
+// scope returned by beginCallSites
+func (c *Compiler) endCallSites(prev callSiteScope) {
+	c.locateCallSites()
+
+	// Calls that were added to other blocks after their statement, such as
+	// in the entry block
+	for _, block := range c.stackTrace.scope.fn.Blocks {
+		c.stackTrace.scope.located[block] = 0
+		c.locateBlockCallSites(block)
+	}
+
+	c.stackTrace.scope = prev
+}
+
+// locateCallSites gives the calls that have been added since the last statement
+// the line of the current statement
+func (c *Compiler) locateCallSites() {
+	scope := c.stackTrace.scope
+	if !c.stackTrace.enabled || scope.fn == nil {
+		return
+	}
+
+	if c.contextBlock != nil && c.contextBlock.Parent == scope.fn {
+		c.locateBlockCallSites(c.contextBlock)
+	}
+
+	for _, block := range scope.fn.Blocks[scope.locatedBlocks:] {
+		c.locateBlockCallSites(block)
+	}
+	c.stackTrace.scope.locatedBlocks = len(scope.fn.Blocks)
+}
+
+func (c *Compiler) locateBlockCallSites(block *ir.Block) {
+	scope := c.stackTrace.scope
+
+	located := min(scope.located[block], len(block.Insts))
+	for _, inst := range block.Insts[located:] {
+		call, ok := inst.(*ir.InstCall)
+		if !ok {
+			continue
+		}
+		if _, ok := c.stackTrace.callSites[call]; ok {
+			continue
+		}
+		c.stackTrace.callSites[call] = callSite{
+			funcName: scope.funcName,
+			file:     c.stackFrameFile(),
+			line:     c.contextLine,
+		}
+	}
+	scope.located[block] = len(block.Insts)
+}
+
+// finalizeStackTrace surrounds the call sites with labels, and generates the
+// body of print-stack-trace
+func (c *Compiler) finalizeStackTrace() {
+	if !c.stackTrace.enabled {
+		return
+	}
+
+	siteType := llvmTypes.NewStruct(llvmTypes.I8Ptr, llvmTypes.I8Ptr, llvmTypes.I64)
+	var sites []constant.Constant
+	siteIndexes := make(map[callSite]int)
+
+	// Calls to functions that are declared and not defined are not call sites,
+	// they are calls to C or to intrinsics
+	isCallSite := func(call *ir.InstCall) bool {
+		switch callee := call.Callee.(type) {
+		case *ir.InlineAsm:
+			return false
+		case *ir.Func:
+			return len(callee.Blocks) > 0 || callee == c.stackTrace.printStackTrace
+		}
+		return true
+	}
+
+	for _, fn := range c.module.Funcs {
+		if len(fn.Blocks) == 0 {
+			continue
+		}
+
+		// The frames are found from the frame pointers
+		fn.FuncAttrs = append(fn.FuncAttrs, ir.AttrPair{Key: "frame-pointer", Value: "all"})
+
+		hasCallSites := false
+
+		for _, block := range fn.Blocks {
+			var insts []ir.Instruction
+
+			for _, inst := range block.Insts {
+				call, ok := inst.(*ir.InstCall)
+				if !ok || !isCallSite(call) {
+					insts = append(insts, inst)
+					continue
+				}
+
+				index := callSiteHidden
+				if site, ok := c.stackTrace.callSites[call]; ok {
+					index, ok = siteIndexes[site]
+					if !ok {
+						index = len(sites)
+						siteIndexes[site] = index
+						sites = append(sites, constant.NewStruct(siteType,
+							c.constantCString(site.funcName),
+							c.constantCString(site.file),
+							constant.NewInt(llvmTypes.I64, int64(site.line)),
+						))
+					}
+				}
+
+				// The frame of the call must not be removed by a tail call
+				call.Tail = enum.TailNoTail
+
+				insts = append(insts, callSiteLabel("8801:"), call, callSiteLabel(fmt.Sprintf(
+					"8802:\n.pushsection %s,\"aw\"\n.balign 8\n.quad 8801b, 8802b, %d\n.popsection",
+					callSiteSection, index,
+				)))
+				hasCallSites = true
+			}
+
+			block.Insts = insts
+		}
+
+		// The labels of the call sites belongs to the function that they are
+		// in, and can't be copied to other functions
+		if hasCallSites {
+			fn.FuncAttrs = append(fn.FuncAttrs, enum.FuncAttrNoInline)
+		}
+	}
+
+	table := c.module.NewGlobalDef("callsites", constant.NewArray(llvmTypes.NewArray(uint64(len(sites)), siteType), sites...))
+	table.Immutable = true
+
+	c.finalizePrintStackTrace(siteType, table)
+}
+
+// finalizePrintStackTrace generates the body of print-stack-trace, that prints
+// the location of each call site on the stack, starting with its caller
+func (c *Compiler) finalizePrintStackTrace(siteType *llvmTypes.StructType, table *ir.Global) {
+	fn := c.stackTrace.printStackTrace
+	fn.FuncAttrs = append(fn.FuncAttrs, ir.AttrPair{Key: "frame-pointer", Value: "all"}, enum.FuncAttrNoInline)
+
+	frameAddress := c.module.NewFunc("llvm.frameaddress.p0i8", llvmTypes.I8Ptr, ir.NewParam("", llvmTypes.I32))
+
+	// The bounds of the section are defined by the linker, they are null if
+	// the program has no call sites
+	sectionStart := c.module.NewGlobal("__start_"+callSiteSection, llvmTypes.I64)
+	sectionStart.Linkage = enum.LinkageExternWeak
+	sectionStop := c.module.NewGlobal("__stop_"+callSiteSection, llvmTypes.I64)
+	sectionStop.Linkage = enum.LinkageExternWeak
+
+	entry := fn.NewBlock(name.Block())
+	nextFrame := fn.NewBlock(name.Block())
+	search := fn.NewBlock(name.Block())
+	checkSite := fn.NewBlock(name.Block())
+	nextSite := fn.NewBlock(name.Block())
+	found := fn.NewBlock(name.Block())
+	print := fn.NewBlock(name.Block())
+	done := fn.NewBlock(name.Block())
+
+	i64Ptr := llvmTypes.NewPointer(llvmTypes.I64)
+	zero := constant.NewInt(llvmTypes.I64, 0)
+	one := constant.NewInt(llvmTypes.I64, 1)
+
+	startFrame := entry.NewCall(frameAddress, constant.NewInt(llvmTypes.I32, 0))
+	start := entry.NewPtrToInt(sectionStart, llvmTypes.I64)
+	stop := entry.NewPtrToInt(sectionStop, llvmTypes.I64)
+	entry.NewBr(nextFrame)
+
+	// Each frame starts with the frame pointer of the caller, followed by the
+	// return address in the caller
+	frame := nextFrame.NewPhi(ir.NewIncoming(startFrame, entry))
+	depth := nextFrame.NewPhi(ir.NewIncoming(zero, entry))
+	isLast := nextFrame.NewOr(
+		nextFrame.NewICmp(enum.IPredEQ, frame, constant.NewNull(llvmTypes.I8Ptr)),
+		nextFrame.NewICmp(enum.IPredSGE, depth, constant.NewInt(llvmTypes.I64, maxStackFrames)),
+	)
+	frameWords := nextFrame.NewBitCast(frame, i64Ptr)
+	returnAddress := nextFrame.NewLoad(llvmTypes.I64, nextFrame.NewGetElementPtr(llvmTypes.I64, frameWords, one))
+	nextFrame.NewCondBr(isLast, done, search)
+
+	// Find the call site that the return address is in
+	site := search.NewPhi(ir.NewIncoming(start, nextFrame))
+	search.NewCondBr(search.NewICmp(enum.IPredULT, site, stop), checkSite, done)
+
+	siteWords := checkSite.NewIntToPtr(site, i64Ptr)
+	siteStart := checkSite.NewLoad(llvmTypes.I64, siteWords)
+	siteEnd := checkSite.NewLoad(llvmTypes.I64, checkSite.NewGetElementPtr(llvmTypes.I64, siteWords, one))
+	isInSite := checkSite.NewAnd(
+		checkSite.NewICmp(enum.IPredUGT, returnAddress, siteStart),
+		checkSite.NewICmp(enum.IPredULE, returnAddress, siteEnd),
+	)
+	checkSite.NewCondBr(isInSite, found, nextSite)
+
+	site.Incs = append(site.Incs, ir.NewIncoming(nextSite.NewAdd(site, constant.NewInt(llvmTypes.I64, 24)), nextSite))
+	nextSite.NewBr(search)
+
+	index := found.NewLoad(llvmTypes.I64, found.NewGetElementPtr(llvmTypes.I64, siteWords, constant.NewInt(llvmTypes.I64, 2)))
+	callerFrame := found.NewLoad(llvmTypes.I8Ptr, found.NewBitCast(frame, llvmTypes.NewPointer(llvmTypes.I8Ptr)))
+	frame.Incs = append(frame.Incs, ir.NewIncoming(callerFrame, found), ir.NewIncoming(callerFrame, print))
+	nextDepth := found.NewAdd(depth, one)
+	depth.Incs = append(depth.Incs, ir.NewIncoming(nextDepth, found), ir.NewIncoming(nextDepth, print))
+	found.NewCondBr(found.NewICmp(enum.IPredEQ, index, constant.NewInt(llvmTypes.I64, callSiteHidden)), nextFrame, print)
+
+	siteInfo := print.NewGetElementPtr(table.ContentType, table, zero, index)
+	field := func(i int64) llvmValue.Value {
+		return print.NewGetElementPtr(siteType, siteInfo, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, i))
+	}
+	print.NewCall(c.externalFuncs.Printf.Value.(llvmValue.Named), c.constantCString("%s()\n\t%s:%lld\n"),
+		print.NewLoad(llvmTypes.I8Ptr, field(0)),
+		print.NewLoad(llvmTypes.I8Ptr, field(1)),
+		print.NewLoad(llvmTypes.I64, field(2)),
+	)
+	print.NewBr(nextFrame)
+
+	done.NewRet(nil)
+}
+
+// callSiteLabel returns a call to inline assembly that defines a label
+func callSiteLabel(asm string) *ir.InstCall {
+	asmFunc := ir.NewInlineAsm(llvmTypes.NewPointer(llvmTypes.NewFunc(llvmTypes.Void)), asm, "")
+	asmFunc.SideEffect = true
+	return ir.NewCall(asmFunc)
+}
+
+// qualifiedFuncName returns the name of the function as it's presented in stack
+// traces, such as "main.main", "main.(*T).Method" and "main.main.func1"
+func (c *Compiler) qualifiedFuncName(v *parser.DefineFuncNode) string {
+	if v.IsMethod {
+		if v.IsPointerReceiver {
+			return c.currentPackageName + ".(*" + v.MethodOnType.TypeName + ")." + v.Name
+		}
+		return c.currentPackageName + "." + v.MethodOnType.TypeName + "." + v.Name
+	}
+
+	if v.IsNamed {
+		return c.currentPackageName + "." + v.Name
+	}
+
+	// Anonymous functions are named after the function they are defined in
+	outer := c.contextFuncName
+	if outer == "" {
+		outer = c.currentPackageName + ".glob"
+	}
+	c.anonFuncCount[outer]++
+	return fmt.Sprintf("%s.func%d", outer, c.anonFuncCount[outer])
+}
@@ -0,0 +1,209 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/compiler/value"
+	"github.com/zegl/tre/compiler/parser"
+)
+
+// addStringFuncs adds the runtime helpers for strings, string-compare,
+// string-concat and string-cstr.
+func (c *Compiler) addStringFuncs() {
+	c.addStringCompare()
+	c.addStringConcat()
+	c.addStringCStr()
+}
+
+// addStringCompare adds string-compare(a, b string) i32, that compares the
+// bytes of two strings. The result is negative if a < b, 0 if a == b and
+// positive if a > b. A shorter string that is a prefix of the other string
+// sorts first.
+func (c *Compiler) addStringCompare() {
+	fn := c.module.NewFunc("string-compare", llvmTypes.I32,
+		ir.NewParam("a", types.String.LLVM()),
+		ir.NewParam("b", types.String.LLVM()),
+	)
+	entry := fn.NewBlock(name.Block())
+	compareData := fn.NewBlock(name.Block())
+	differentData := fn.NewBlock(name.Block())
+	compareLen := fn.NewBlock(name.Block())
+
+	aLen := entry.NewExtractValue(fn.Params[0], 0)
+	bLen := entry.NewExtractValue(fn.Params[1], 0)
+
+	// Compare the bytes that both strings have, empty strings can have a nil
+	// pointer and are not passed to memcmp
+	n := entry.NewSelect(entry.NewICmp(enum.IPredULT, aLen, bLen), aLen, bLen)
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, n, constant.NewInt(llvmTypes.I64, 0)), compareLen, compareData)
+
+	cmp := compareData.NewCall(c.externalFuncs.Memcmp.Value.(llvmValue.Named),
+		compareData.NewExtractValue(fn.Params[0], 1),
+		compareData.NewExtractValue(fn.Params[1], 1),
+		n,
+	)
+	compareData.NewCondBr(compareData.NewICmp(enum.IPredEQ, cmp, constant.NewInt(llvmTypes.I32, 0)), compareLen, differentData)
+	differentData.NewRet(cmp)
+
+	// The bytes are equal, the longer string is the larger one
+	isGreater := compareLen.NewZExt(compareLen.NewICmp(enum.IPredUGT, aLen, bLen), llvmTypes.I32)
+	isLess := compareLen.NewZExt(compareLen.NewICmp(enum.IPredULT, aLen, bLen), llvmTypes.I32)
+	compareLen.NewRet(compareLen.NewSub(isGreater, isLess))
+
+	c.stringCompareFunc = fn
+}
+
+// addStringConcat adds string-concat(a, b string) string. The result is
+// copied to a new heap allocated buffer with memcpy, so that strings can
+// contain null bytes.
+//
+// The buffer is allocated with twice the needed capacity. The last buffer
+// that was allocated by each thread is remembered, and a string that ends
+// where the data in that buffer ends is extended in place. This makes
+// repeated "s += x" in a loop amortized linear instead of quadratic. The
+// bytes of the strings that share the buffer are never modified, but a
+// string that was extended is no longer followed by a null terminator, see
+// addStringCStr.
+func (c *Compiler) addStringConcat() {
+	i8Ptr := llvmTypes.I8Ptr
+	i64 := llvmTypes.I64
+	zero := constant.NewInt(i64, 0)
+
+	newSlot := func(slotName string, t llvmTypes.Type) *ir.Global {
+		glob := c.module.NewGlobalDef(name.Var(slotName), constant.NewZeroInitializer(t))
+		glob.TLSModel = enum.TLSModelGeneric
+		return glob
+	}
+	lastBuf := newSlot("string-concat-buf", i8Ptr)
+	lastLen := newSlot("string-concat-len", i64)
+	lastCap := newSlot("string-concat-cap", i64)
+
+	fn := c.module.NewFunc("string-concat", types.String.LLVM(),
+		ir.NewParam("a", types.String.LLVM()),
+		ir.NewParam("b", types.String.LLVM()),
+	)
+	entry := fn.NewBlock(name.Block())
+	checkA := fn.NewBlock(name.Block())
+	returnA := fn.NewBlock(name.Block())
+	returnB := fn.NewBlock(name.Block())
+	checkInPlace := fn.NewBlock(name.Block())
+	inPlace := fn.NewBlock(name.Block())
+	grow := fn.NewBlock(name.Block())
+
+	a, b := fn.Params[0], fn.Params[1]
+	aLen := entry.NewExtractValue(a, 0)
+	bLen := entry.NewExtractValue(b, 0)
+	aData := entry.NewExtractValue(a, 1)
+	bData := entry.NewExtractValue(b, 1)
+	sumLen := entry.NewAdd(aLen, bLen)
+
+	newString := func(block *ir.Block, data llvmValue.Value) llvmValue.Value {
+		var res llvmValue.Value = constant.NewUndef(types.ModuleStringType.(*llvmTypes.StructType))
+		res = block.NewInsertValue(res, sumLen, 0)
+		return block.NewInsertValue(res, data, 1)
+	}
+
+	// Concatenation with an empty string returns the other string
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, bLen, zero), returnA, checkA)
+	returnA.NewRet(a)
+	checkA.NewCondBr(checkA.NewICmp(enum.IPredEQ, aLen, zero), returnB, checkInPlace)
+	returnB.NewRet(b)
+
+	// a ends where the data in the last buffer ends, and there is room for b
+	isLastBuf := checkInPlace.NewICmp(enum.IPredEQ, aData, checkInPlace.NewLoad(i8Ptr, lastBuf))
+	isLastLen := checkInPlace.NewICmp(enum.IPredEQ, aLen, checkInPlace.NewLoad(i64, lastLen))
+	hasRoom := checkInPlace.NewICmp(enum.IPredULE, sumLen, checkInPlace.NewLoad(i64, lastCap))
+	canExtend := checkInPlace.NewAnd(checkInPlace.NewAnd(isLastBuf, isLastLen), hasRoom)
+	checkInPlace.NewCondBr(canExtend, inPlace, grow)
+
+	memcpy := c.externalFuncs.Memcpy.Value.(llvmValue.Named)
+
+	inPlace.NewCall(memcpy, inPlace.NewGetElementPtr(llvmTypes.I8, aData, aLen), bData, bLen)
+	inPlace.NewStore(constant.NewInt(llvmTypes.I8, 0), inPlace.NewGetElementPtr(llvmTypes.I8, aData, sumLen))
+	inPlace.NewStore(sumLen, lastLen)
+	inPlace.NewRet(newString(inPlace, aData))
+
+	// One extra byte is needed for the null terminator
+	capacity := grow.NewMul(sumLen, constant.NewInt(i64, 2))
+	buf := grow.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), grow.NewAdd(capacity, constant.NewInt(i64, 1)))
+	grow.NewCall(memcpy, buf, aData, aLen)
+	grow.NewCall(memcpy, grow.NewGetElementPtr(llvmTypes.I8, buf, aLen), bData, bLen)
+	grow.NewStore(constant.NewInt(llvmTypes.I8, 0), grow.NewGetElementPtr(llvmTypes.I8, buf, sumLen))
+	grow.NewStore(buf, lastBuf)
+	grow.NewStore(sumLen, lastLen)
+	grow.NewStore(capacity, lastCap)
+	grow.NewRet(newString(grow, buf))
+
+	c.stringConcatFunc = fn
+}
+
+// addStringCStr adds string-cstr(s string) i8*, that returns the data of s
+// as a null terminated C string. The data is returned as it is if it's
+// already followed by a null terminator, otherwise it's copied to a new
+// buffer.
+func (c *Compiler) addStringCStr() {
+	fn := c.module.NewFunc("string-cstr", llvmTypes.I8Ptr, ir.NewParam("s", types.String.LLVM()))
+	entry := fn.NewBlock(name.Block())
+	checkTerminator := fn.NewBlock(name.Block())
+	isTerminated := fn.NewBlock(name.Block())
+	isNil := fn.NewBlock(name.Block())
+	copyData := fn.NewBlock(name.Block())
+
+	length := entry.NewExtractValue(fn.Params[0], 0)
+	data := entry.NewExtractValue(fn.Params[0], 1)
+
+	// The zero value of strings in some types, such as in slices created with
+	// make, does not have any data
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, data, constant.NewNull(llvmTypes.I8Ptr)), isNil, checkTerminator)
+	isNil.NewRet(constant.NewGetElementPtr(types.EmptyStringConstant.ContentType, types.EmptyStringConstant,
+		constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 0)))
+
+	terminator := checkTerminator.NewLoad(llvmTypes.I8, checkTerminator.NewGetElementPtr(llvmTypes.I8, data, length))
+	checkTerminator.NewCondBr(checkTerminator.NewICmp(enum.IPredEQ, terminator, constant.NewInt(llvmTypes.I8, 0)), isTerminated, copyData)
+	isTerminated.NewRet(data)
+
+	copyData.NewRet(copyData.NewCall(c.externalFuncs.Strndup.Value.(llvmValue.Named), data, length))
+
+	c.stringCStrFunc = fn
+}
+
+// stringConcat concatenates two loaded strings
+func (c *Compiler) stringConcat(left, right llvmValue.Value) value.Value {
+	return value.Value{
+		Value:      c.contextBlock.NewCall(c.stringConcatFunc, left, right),
+		Type:       types.String,
+		IsVariable: false,
+	}
+}
+
+// cString returns the data of the loaded string s as a null terminated C
+// string, that can be passed to external functions
+func (c *Compiler) cString(block *ir.Block, s llvmValue.Value) llvmValue.Value {
+	return block.NewCall(c.stringCStrFunc, s)
+}
+
+// compileStringComparison compares two loaded strings with a comparison
+// operator, such as == or <
+func (c *Compiler) compileStringComparison(operator parser.Operator, left, right llvmValue.Value) value.Value {
+	switch operator {
+	case parser.OP_EQ, parser.OP_NEQ, parser.OP_LT, parser.OP_LTEQ, parser.OP_GT, parser.OP_GTEQ:
+	default:
+		compilePanic(fmt.Sprintf("invalid operation: operator %s not defined on string", operator))
+	}
+
+	cmp := c.contextBlock.NewCall(c.stringCompareFunc, left, right)
+
+	return value.Value{
+		Type:       types.Bool,
+		Value:      c.contextBlock.NewICmp(getConditionLLVMpred(operator), cmp, constant.NewInt(llvmTypes.I32, 0)),
+		IsVariable: false,
+	}
+}
@@ -7,6 +7,7 @@ import (
 	llvmTypes "github.com/llir/llvm/ir/types"
 	llvmValue "github.com/llir/llvm/ir/value"
 
+	"github.com/zegl/tre/compiler/compiler/internal"
 	"github.com/zegl/tre/compiler/compiler/internal/pointer"
 	"github.com/zegl/tre/compiler/compiler/name"
 	"github.com/zegl/tre/compiler/compiler/types"
@@ -15,6 +16,11 @@ import (
 )
 
 func (c *Compiler) compileStructLoadElementNode(v *parser.StructLoadElementNode) value.Value {
+	// Method expressions, such as T.Method and (*T).Method
+	if recvType, ok := c.methodExpressionReceiver(v.Struct); ok {
+		return c.compileMethodExpression(recvType, v.ElementName)
+	}
+
 	src := c.compileValue(v.Struct)
 
 	// Use this type, or the type behind the pointer
@@ -30,7 +36,7 @@ func (c *Compiler) compileStructLoadElementNode(v *parser.StructLoadElementNode)
 	if !src.IsVariable && !isPointer {
 		// GetElementPtr only works on pointer types, and we don't have a pointer to our object.
 		// Allocate it and use the pointer instead
-		dst := c.contextBlock.NewAlloca(src.Type.LLVM())
+		dst := c.entryBlockAlloca(src.Type.LLVM())
 		c.contextBlock.NewStore(src.Value, dst)
 		src = value.Value{
 			Value:      dst,
@@ -60,9 +66,16 @@ func (c *Compiler) compileStructLoadElementNode(v *parser.StructLoadElementNode)
 
 	// Check if it's a method
 	if method, ok := targetType.GetMethod(v.ElementName); ok {
+		val := src.Value
+
+		// The method is called on the value that the pointer points to
+		if isPointer && !isPointerNonAllocDereference && src.IsVariable {
+			val = c.contextBlock.NewLoad(pointer.ElemType(val), val)
+		}
+
 		return value.Value{
 			Type:       method,
-			Value:      src.Value,
+			Value:      val,
 			IsVariable: false,
 		}
 	}
@@ -111,12 +124,11 @@ func (c *Compiler) compileInitStructWithValues(v *parser.InitializeStructNode) v
 	var alloc llvmValue.Value
 
 	// Allocate on the heap or on the stack
-	if len(c.contextAlloc) > 0 && c.contextAlloc[len(c.contextAlloc)-1].Escapes {
-		mallocatedSpaceRaw := c.contextBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), constant.NewInt(llvmTypes.I64, structType.Size()))
+	if v.Escapes || (len(c.contextAlloc) > 0 && c.contextAlloc[len(c.contextAlloc)-1].Escapes) {
+		mallocatedSpaceRaw := c.contextBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), internal.SizeOf(structType.LLVM()))
 		alloc = c.contextBlock.NewBitCast(mallocatedSpaceRaw, llvmTypes.NewPointer(structType.LLVM()))
-		structType.IsHeapAllocated = true
 	} else {
-		alloc = c.contextBlock.NewAlloca(structType.LLVM())
+		alloc = c.entryBlockAlloca(structType.LLVM())
 	}
 
 	treType.Zero(c.contextBlock, alloc)
@@ -130,9 +142,9 @@ func (c *Compiler) compileInitStructWithValues(v *parser.InitializeStructNode) v
 		itemPtr := c.contextBlock.NewGetElementPtr(pointer.ElemType(alloc), alloc, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, int64(keyIndex)))
 		itemPtr.SetName(name.Var(key))
 
-		compiledVal := c.compileValue(val)
+		compiledVal := c.assignableValue(c.compileValue(val), structType.Members[key])
 
-		c.contextBlock.NewStore(compiledVal.Value, itemPtr)
+		c.contextBlock.NewStore(internal.LoadIfVariable(c.contextBlock, compiledVal), itemPtr)
 	}
 
 	return value.Value{
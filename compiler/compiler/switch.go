@@ -3,29 +3,57 @@ package compiler
 import (
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
+	llvmValue "github.com/llir/llvm/ir/value"
 
 	"github.com/zegl/tre/compiler/compiler/internal"
 	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
 	"github.com/zegl/tre/compiler/parser"
 )
 
-func (c *Compiler) compileSwitchNode(v *parser.SwitchNode) {
+// compileSwitchNode compiles a switch, l is the label of the switch or nil
+func (c *Compiler) compileSwitchNode(v *parser.SwitchNode, l *label) {
+	// Variables from the init statement are only available in the switch
+	c.pushVariablesStack()
+	defer c.popVariablesStack()
+	if v.Init != nil {
+		c.compile([]parser.Node{v.Init})
+	}
+
 	switchItem := c.compileValue(v.Item)
 
+	// Strings are compared with string-compare, and not with a switch
+	// instruction
+	_, isString := switchItem.Type.(*types.StringType)
+
 	var cases []*ir.Case
+	var stringCases []stringCase
 	caseBlocks := make([]*ir.Block, len(v.Cases))
 
 	afterSwitch := c.contextBlock.Parent.NewBlock(name.Block() + "after-switch")
 
+	// break in a case jumps to after the switch
+	c.contextLoopBreak = append(c.contextLoopBreak, afterSwitch)
+	if l != nil {
+		l.breakBlock = afterSwitch
+	}
+
 	// build default case
 	defaultCase := c.contextBlock.Parent.NewBlock(name.Block() + "switch-default")
+	defaultEnd := defaultCase
 	if v.DefaultBody != nil {
 		preDefaultBlock := c.contextBlock
 		c.contextBlock = defaultCase
 		c.compile(v.DefaultBody)
+		defaultEnd = c.contextBlock
 		c.contextBlock = preDefaultBlock
 	}
-	defaultCase.NewBr(afterSwitch)
+	if defaultEnd.Term == nil {
+		defaultEnd.NewBr(afterSwitch)
+	}
+
+	// The blocks that the case bodies ends in, the body can create new blocks
+	caseEndBlocks := make([]*ir.Block, len(v.Cases))
 
 	// Parse all cases
 	for caseIndex, parseCase := range v.Cases {
@@ -33,28 +61,70 @@ func (c *Compiler) compileSwitchNode(v *parser.SwitchNode) {
 		caseBlock := c.contextBlock.Parent.NewBlock(name.Block() + "case")
 		c.contextBlock = caseBlock
 		c.compile(parseCase.Body)
+		caseEndBlocks[caseIndex] = c.contextBlock
 		c.contextBlock = preCaseBlock
 
 		caseBlocks[caseIndex] = caseBlock
 
 		for _, cond := range parseCase.Conditions {
-			item := c.compileValue(cond)
+			if isString {
+				stringCases = append(stringCases, stringCase{
+					cond:  cond,
+					block: caseBlock,
+				})
+				continue
+			}
+
+			// Constant cases are converted to the type of the switch value
+			item := constantWithType(c.compileValue(cond), switchItem)
 			cases = append(cases, ir.NewCase(item.Value.(constant.Constant), caseBlock))
 		}
 	}
 
+	c.contextLoopBreak = c.contextLoopBreak[0 : len(c.contextLoopBreak)-1]
+
 	for caseIndex, parseCase := range v.Cases {
+		endBlock := caseEndBlocks[caseIndex]
+
+		// The body has already jumped away, such as with a return
+		if endBlock.Term != nil {
+			continue
+		}
+
 		if parseCase.Fallthrough {
 			// Jump to the next case body
-			caseBlocks[caseIndex].Term = ir.NewBr(caseBlocks[caseIndex+1])
+			endBlock.Term = ir.NewBr(caseBlocks[caseIndex+1])
 		} else {
 			// Jump to after switch
-			caseBlocks[caseIndex].Term = ir.NewBr(afterSwitch)
+			endBlock.Term = ir.NewBr(afterSwitch)
 		}
 	}
 
 	val := internal.LoadIfVariable(c.contextBlock, switchItem)
-	c.contextBlock.Term = c.contextBlock.NewSwitch(val, defaultCase, cases...)
+	if isString {
+		c.compileStringSwitch(val, defaultCase, stringCases)
+	} else {
+		c.contextBlock.Term = c.contextBlock.NewSwitch(val, defaultCase, cases...)
+	}
 
 	c.contextBlock = afterSwitch
 }
+
+type stringCase struct {
+	cond  parser.Node
+	block *ir.Block
+}
+
+// compileStringSwitch compares the string val with the cases in order, and
+// jumps to the block of the first case that is equal. The case values are
+// only evaluated until a case matches.
+func (c *Compiler) compileStringSwitch(val llvmValue.Value, defaultCase *ir.Block, cases []stringCase) {
+	for _, cs := range cases {
+		caseVal := internal.LoadIfVariable(c.contextBlock, c.compileValue(cs.cond))
+		next := c.contextBlock.Parent.NewBlock(name.Block() + "switch-next")
+		isEqual := c.compileStringComparison(parser.OP_EQ, val, caseVal)
+		c.contextBlock.NewCondBr(isEqual.Value, cs.block, next)
+		c.contextBlock = next
+	}
+	c.contextBlock.NewBr(defaultCase)
+}
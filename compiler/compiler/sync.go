@@ -0,0 +1,143 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/internal"
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+)
+
+// atomicTypes are the types that the atomic functions are generated for, the
+// functions are named after the type, such as external.AtomicAddInt32
+var atomicTypes = []struct {
+	name string
+	typ  *types.Int
+}{
+	{"Int32", types.I32},
+	{"Int64", types.I64},
+	{"Uint32", types.U32},
+	{"Uint64", types.U64},
+	{"Uintptr", types.Uintptr},
+}
+
+// addSyncFuncs adds the atomic operations that are used by the sync and
+// sync/atomic packages to the external package. All operations are
+// sequentially consistent.
+//
+// It also adds ThreadCreate and ThreadJoin, that runs a function on a new
+// OS thread.
+func (c *Compiler) addSyncFuncs() {
+	seqCst := enum.AtomicOrderingSeqCst
+
+	for _, at := range atomicTypes {
+		t := at.typ
+		ptrType := &types.Pointer{Type: t, LlvmType: llvmTypes.NewPointer(t.LLVM())}
+		align := ir.Align(t.Size())
+
+		newFunc := func(funcName string, retType llvmTypes.Type, params ...string) (*ir.Func, *ir.Block) {
+			irParams := []*ir.Param{ir.NewParam("addr", ptrType.LLVM())}
+			for _, param := range params {
+				irParams = append(irParams, ir.NewParam(param, t.LLVM()))
+			}
+			fn := c.module.NewFunc(name.Var("atomic-"+funcName), retType, irParams...)
+			return fn, fn.NewBlock(name.Block())
+		}
+
+		// Add(addr *T, delta T) (new T)
+		addFn, block := newFunc("add"+at.name, t.LLVM(), "delta")
+		old := block.NewAtomicRMW(enum.AtomicOpAdd, addFn.Params[0], addFn.Params[1], seqCst)
+		block.NewRet(block.NewAdd(old, addFn.Params[1]))
+		c.defineExternalFunc("AtomicAdd"+at.name, addFn, t, []types.Type{ptrType, t})
+
+		// Load(addr *T) T
+		loadFn, block := newFunc("load"+at.name, t.LLVM())
+		load := block.NewLoad(t.LLVM(), loadFn.Params[0])
+		load.Atomic = true
+		load.Ordering = seqCst
+		load.Align = align
+		block.NewRet(load)
+		c.defineExternalFunc("AtomicLoad"+at.name, loadFn, t, []types.Type{ptrType})
+
+		// Store(addr *T, val T)
+		storeFn, block := newFunc("store"+at.name, llvmTypes.Void, "val")
+		store := block.NewStore(storeFn.Params[1], storeFn.Params[0])
+		store.Atomic = true
+		store.Ordering = seqCst
+		store.Align = align
+		block.NewRet(nil)
+		c.defineExternalFunc("AtomicStore"+at.name, storeFn, types.Void, []types.Type{ptrType, t})
+
+		// Swap(addr *T, new T) (old T)
+		swapFn, block := newFunc("swap"+at.name, t.LLVM(), "new")
+		block.NewRet(block.NewAtomicRMW(enum.AtomicOpXChg, swapFn.Params[0], swapFn.Params[1], seqCst))
+		c.defineExternalFunc("AtomicSwap"+at.name, swapFn, t, []types.Type{ptrType, t})
+
+		// CompareAndSwap(addr *T, old, new T) (swapped bool)
+		casFn, block := newFunc("cas"+at.name, llvmTypes.I1, "old", "new")
+		cas := block.NewCmpXchg(casFn.Params[0], casFn.Params[1], casFn.Params[2], seqCst, seqCst)
+		block.NewRet(block.NewExtractValue(cas, 1))
+		c.defineExternalFunc("AtomicCompareAndSwap"+at.name, casFn, types.Bool, []types.Type{ptrType, t, t})
+	}
+
+	c.addThreadFuncs()
+}
+
+// addThreadFuncs adds ThreadCreate(fn func(int), arg int) int, that calls
+// fn(arg) on a new OS thread, and ThreadJoin(thread int) that waits for the
+// thread to finish. The threads are created with pthreads.
+func (c *Compiler) addThreadFuncs() {
+	i8Ptr := llvmTypes.I8Ptr
+	i64 := llvmTypes.I64
+
+	startFuncType := llvmTypes.NewPointer(llvmTypes.NewFunc(i8Ptr, i8Ptr))
+	pthreadCreate := c.module.NewFunc("pthread_create", llvmTypes.I32,
+		ir.NewParam("thread", llvmTypes.NewPointer(i64)), ir.NewParam("attr", i8Ptr),
+		ir.NewParam("start", startFuncType), ir.NewParam("arg", i8Ptr))
+	pthreadJoin := c.module.NewFunc("pthread_join", llvmTypes.I32,
+		ir.NewParam("thread", i64), ir.NewParam("retval", llvmTypes.NewPointer(i8Ptr)))
+
+	fnType := &types.Function{
+		FuncType:       llvmTypes.NewPointer(llvmTypes.NewFunc(llvmTypes.Void, i64)),
+		LlvmReturnType: types.Void,
+		ArgumentTypes:  []types.Type{types.I64},
+	}
+
+	// The function and the argument are passed to the new thread in a
+	// heap allocated struct, that is freed by the thread
+	startArgType := llvmTypes.NewStruct(fnType.LLVM(), i64)
+	startArgPtrType := llvmTypes.NewPointer(startArgType)
+
+	start := c.module.NewFunc(name.Var("thread-start"), i8Ptr, ir.NewParam("arg", i8Ptr))
+	startBlock := start.NewBlock(name.Block())
+	startArg := startBlock.NewLoad(startArgType, startBlock.NewBitCast(start.Params[0], startArgPtrType))
+	startBlock.NewCall(c.externalFuncs.Free.Value.(llvmValue.Named), start.Params[0])
+
+	prevContextBlock := c.contextBlock
+	c.contextBlock = startBlock
+	c.callFuncValue(fnType, startBlock.NewExtractValue(startArg, 0), []llvmValue.Value{startBlock.NewExtractValue(startArg, 1)})
+	c.contextBlock.NewRet(constant.NewNull(i8Ptr))
+	c.contextBlock = prevContextBlock
+
+	createFn := c.module.NewFunc(name.Var("thread-create"), i64, ir.NewParam("fn", fnType.LLVM()), ir.NewParam("arg", i64))
+	createBlock := createFn.NewBlock(name.Block())
+	argMem := createBlock.NewCall(c.externalFuncs.Malloc.Value.(llvmValue.Named), internal.SizeOf(startArgType))
+	var arg llvmValue.Value = constant.NewUndef(startArgType)
+	arg = createBlock.NewInsertValue(arg, createFn.Params[0], 0)
+	arg = createBlock.NewInsertValue(arg, createFn.Params[1], 1)
+	createBlock.NewStore(arg, createBlock.NewBitCast(argMem, startArgPtrType))
+	thread := createBlock.NewAlloca(i64)
+	createBlock.NewCall(pthreadCreate, thread, constant.NewNull(i8Ptr), start, argMem)
+	createBlock.NewRet(createBlock.NewLoad(i64, thread))
+	c.defineExternalFunc("ThreadCreate", createFn, types.I64, []types.Type{fnType, types.I64})
+
+	joinFn := c.module.NewFunc(name.Var("thread-join"), llvmTypes.Void, ir.NewParam("thread", i64))
+	joinBlock := joinFn.NewBlock(name.Block())
+	joinBlock.NewCall(pthreadJoin, joinFn.Params[0], constant.NewNull(llvmTypes.NewPointer(i8Ptr)))
+	joinBlock.NewRet(nil)
+	c.defineExternalFunc("ThreadJoin", joinFn, types.Void, []types.Type{types.I64})
+}
@@ -0,0 +1,59 @@
+package syscall
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+)
+
+// Call performs the syscall f with args, all arguments must be i64 or
+// pointers. The result is an i64, failures are returned as -errno on all
+// platforms.
+func Call(block *ir.Block, f Fn, goos, goarch string, args ...llvmValue.Value) llvmValue.Value {
+	// Syscalls that takes a path are only available as *at variants on linux/arm64
+	if extra, ok := atFuncs[f]; ok && goos == "linux" && goarch == "arm64" {
+		atArgs := []llvmValue.Value{constant.NewInt(llvmTypes.I64, atFDCWD)}
+		atArgs = append(atArgs, args...)
+		for i := 0; i < extra; i++ {
+			atArgs = append(atArgs, constant.NewInt(llvmTypes.I64, 0))
+		}
+		args = atArgs
+	}
+
+	// getdirentries64 stores the position in the directory to basep
+	if f == GETDENTS && goos == "darwin" {
+		basep := block.NewAlloca(llvmTypes.I64)
+		args = append(args, basep)
+	}
+
+	asm, constraints := instruction(goos, goarch, len(args))
+
+	// On darwin the kernel sets the carry flag and returns a positive errno
+	// on failure, it's negated to match linux.
+	if goos == "darwin" {
+		if goarch == "arm64" {
+			asm += "\n\tb.cc 1f\n\tneg x0, x0\n1:"
+		} else {
+			asm += "\n\tjae 1f\n\tnegq %rax\n1:"
+		}
+		constraints += ",~{cc}"
+	}
+
+	// The kernel might read or write memory that the arguments points to
+	if goarch == "arm64" {
+		constraints += ",~{memory}"
+	}
+
+	paramTypes := []llvmTypes.Type{llvmTypes.I64}
+	callArgs := []llvmValue.Value{constant.NewInt(llvmTypes.I64, Convert(f, goos, goarch))}
+	for _, arg := range args {
+		paramTypes = append(paramTypes, arg.Type())
+		callArgs = append(callArgs, arg)
+	}
+
+	asmFunc := ir.NewInlineAsm(llvmTypes.NewPointer(llvmTypes.NewFunc(llvmTypes.I64, paramTypes...)), asm, constraints)
+	asmFunc.SideEffect = true
+
+	return block.NewCall(asmFunc, callArgs...)
+}
@@ -10,17 +10,18 @@ import (
 	"github.com/zegl/tre/compiler/compiler/value"
 )
 
-func Print(block *ir.Block, value value.Value, goos string) {
-	asmFunc := ir.NewInlineAsm(llvmTypes.NewPointer(llvmTypes.NewFunc(types.I64.LLVM())), "syscall", "=r,{rax},{rdi},{rsi},{rdx}")
+func Print(block *ir.Block, value value.Value, goos, goarch string) {
+	asm, constraints := instruction(goos, goarch, 3)
+	asmFunc := ir.NewInlineAsm(llvmTypes.NewPointer(llvmTypes.NewFunc(types.I64.LLVM())), asm, constraints)
 	asmFunc.SideEffect = true
 
 	strPtr := strings.TreToI8Ptr(block, value.Value)
 	strLen := strings.Len(block, value.Value)
 
 	block.NewCall(asmFunc,
-		constant.NewInt(types.I64.Type, Convert(WRITE, goos)), // rax
-		constant.NewInt(types.I64.Type, 1),                    // rdi, stdout
-		strPtr,                                                // rsi
-		strLen,                                                // rdx
+		constant.NewInt(types.I64.Type, Convert(WRITE, goos, goarch)), // syscall number
+		constant.NewInt(types.I64.Type, 1),                            // stdout
+		strPtr,
+		strLen,
 	)
 }
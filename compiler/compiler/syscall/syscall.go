@@ -1,30 +1,147 @@
 package syscall
 
-// https://opensource.apple.com/source/xnu/xnu-2782.20.48/bsd/kern/syscalls.master
-type fn string
+import "fmt"
+
+type Fn string
 
 const (
-	EXIT fn = "EXIT"
-	WRITE fn = "WRITE"
+	EXIT     Fn = "EXIT"
+	READ     Fn = "READ"
+	WRITE    Fn = "WRITE"
+	OPEN     Fn = "OPEN"
+	CLOSE    Fn = "CLOSE"
+	LSEEK    Fn = "LSEEK"
+	STAT     Fn = "STAT"
+	UNLINK   Fn = "UNLINK"
+	MKDIR    Fn = "MKDIR"
+	GETDENTS Fn = "GETDENTS"
+
+	FUTEX_WAIT Fn = "FUTEX_WAIT"
+	FUTEX_WAKE Fn = "FUTEX_WAKE"
+
+	CLOCK_GETTIME Fn = "CLOCK_GETTIME"
+	NANOSLEEP     Fn = "NANOSLEEP"
 )
 
-var convDarwin = map[fn]int64{
-	EXIT: 0x2000001,
-	WRITE: 0x2000004,
+// https://opensource.apple.com/source/xnu/xnu-2782.20.48/bsd/kern/syscalls.master
+// The syscall class (0x2000000) is only added on amd64
+var convDarwin = map[Fn]int64{
+	EXIT:     1,
+	READ:     3,
+	WRITE:    4,
+	OPEN:     5,
+	CLOSE:    6,
+	UNLINK:   10,
+	MKDIR:    136,
+	LSEEK:    199,
+	STAT:     338, // stat64
+	GETDENTS: 344, // getdirentries64
+
+	FUTEX_WAIT: 515, // __ulock_wait
+	FUTEX_WAKE: 516, // __ulock_wake
+
+	CLOCK_GETTIME: 116, // gettimeofday
+	NANOSLEEP:     334, // __semwait_signal
+}
+
+// https://github.com/torvalds/linux/blob/master/arch/x86/entry/syscalls/syscall_64.tbl
+var convLinuxAmd64 = map[Fn]int64{
+	EXIT:     60,
+	READ:     0,
+	WRITE:    1,
+	OPEN:     2,
+	CLOSE:    3,
+	STAT:     4,
+	LSEEK:    8,
+	MKDIR:    83,
+	UNLINK:   87,
+	GETDENTS: 217, // getdents64
+
+	FUTEX_WAIT: 202, // futex
+	FUTEX_WAKE: 202, // futex
+
+	CLOCK_GETTIME: 228,
+	NANOSLEEP:     35,
 }
 
-var convLinux = map[fn]int64{
-	EXIT: 60,
-	WRITE: 1,
+// https://github.com/torvalds/linux/blob/master/include/uapi/asm-generic/unistd.h
+// The generic table only has the *at variants of the syscalls that takes a
+// path, they are called with AT_FDCWD as the directory, see atFuncs.
+var convLinuxArm64 = map[Fn]int64{
+	EXIT:     93,
+	READ:     63,
+	WRITE:    64,
+	OPEN:     56, // openat
+	CLOSE:    57,
+	LSEEK:    62,
+	STAT:     79, // newfstatat
+	UNLINK:   35, // unlinkat
+	MKDIR:    34, // mkdirat
+	GETDENTS: 61, // getdents64
+
+	FUTEX_WAIT: 98, // futex
+	FUTEX_WAKE: 98, // futex
+
+	CLOCK_GETTIME: 113,
+	NANOSLEEP:     101,
+}
+
+// atFuncs are the syscalls that are replaced by their *at variant on
+// linux/arm64, the value is the number of arguments that are added after the
+// arguments of the original syscall
+var atFuncs = map[Fn]int{
+	OPEN:   0,
+	STAT:   1, // flags
+	UNLINK: 1, // flags
+	MKDIR:  0,
 }
 
-func Convert(f fn, goos string) int64 {
+// AT_FDCWD makes the *at syscalls resolve paths relative to the working directory
+const atFDCWD = -100
+
+func Convert(f Fn, goos, goarch string) int64 {
 	switch goos {
 	case "darwin":
+		if goarch == "amd64" {
+			return 0x2000000 + convDarwin[f]
+		}
 		return convDarwin[f]
 	case "linux":
-		return convLinux[f]
+		if goarch == "arm64" {
+			return convLinuxArm64[f]
+		}
+		return convLinuxAmd64[f]
 	default:
-		panic("unknown goos")
+		panic(fmt.Sprintf("syscalls are not supported on %s", goos))
 	}
-}
\ No newline at end of file
+}
+
+// instruction returns the inline assembly and constraints that performs a syscall
+// with numArgs arguments (at most 6)
+func instruction(goos, goarch string, numArgs int) (asm string, constraints string) {
+	switch goarch {
+	case "arm64":
+		// The syscall number is passed in x16 on darwin, and in x8 on linux
+		asm, constraints = "svc #0", "={x0},{x8}"
+		if goos == "darwin" {
+			asm, constraints = "svc #0x80", "={x0},{x16}"
+		}
+
+		// The first argument is passed in the same register as the result
+		for i := 0; i < numArgs; i++ {
+			if i == 0 {
+				constraints += ",0"
+				continue
+			}
+			constraints += fmt.Sprintf(",{x%d}", i)
+		}
+		return asm, constraints
+	default:
+		regs := []string{"rdi", "rsi", "rdx", "r10", "r8", "r9"}
+		constraints = "={rax},{rax}"
+		for i := 0; i < numArgs; i++ {
+			constraints += ",{" + regs[i] + "}"
+		}
+		return "syscall", constraints + ",~{rcx},~{r11},~{memory}"
+	}
+}
@@ -0,0 +1,240 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/internal"
+	"github.com/zegl/tre/compiler/compiler/internal/pointer"
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/syscall"
+	"github.com/zegl/tre/compiler/compiler/types"
+	"github.com/zegl/tre/compiler/compiler/value"
+)
+
+// The external package has functions that performs syscalls directly, such as
+// external.SysOpen(path, flags, perm). They are used by the syscall package.
+// The functions returns the result of the syscall, failures are returned as
+// -errno on all platforms.
+//
+// Strings are passed to the kernel as null terminated C strings, and byte
+// slices are passed as a pointer to the first byte and the length of the slice.
+
+// Flags to SysOpen that has different values on linux and darwin, they are
+// defined as constants in the external package
+var openFlags = map[string]map[string]int64{
+	"linux": {
+		"O_CREAT":  0x40,
+		"O_EXCL":   0x80,
+		"O_TRUNC":  0x200,
+		"O_APPEND": 0x400,
+	},
+	"darwin": {
+		"O_CREAT":  0x200,
+		"O_EXCL":   0x800,
+		"O_TRUNC":  0x400,
+		"O_APPEND": 0x8,
+	},
+}
+
+func (c *Compiler) addSyscalls() {
+	// Syscalls are only supported on linux and darwin
+	if _, ok := openFlags[c.GOOS]; !ok {
+		return
+	}
+
+	byteSlice := &types.Slice{Type: types.U8, LlvmType: internal.Slice(types.U8.LLVM())}
+
+	add := func(funcName string, f syscall.Fn, argTypes []types.Type) {
+		var params []*ir.Param
+		for _, argType := range argTypes {
+			params = append(params, ir.NewParam(name.Var("arg"), argType.LLVM()))
+		}
+
+		fn := c.module.NewFunc(name.Var("syscall-"+string(f)), llvmTypes.I64, params...)
+		block := fn.NewBlock(name.Block())
+
+		var args []llvmValue.Value
+		for i, argType := range argTypes {
+			param := fn.Params[i]
+
+			switch argType {
+			case types.String:
+				args = append(args, c.cString(block, param))
+			case byteSlice:
+				offset := block.NewExtractValue(param, 2)
+				backing := block.NewExtractValue(param, 3)
+				data := block.NewGetElementPtr(pointer.ElemType(backing), backing, offset)
+				args = append(args, data, block.NewSExt(block.NewExtractValue(param, 0), llvmTypes.I64))
+			default:
+				args = append(args, param)
+			}
+		}
+
+		block.NewRet(syscall.Call(block, f, c.GOOS, c.GOARCH, args...))
+
+		c.defineExternalFunc(funcName, fn, types.I64, argTypes)
+	}
+
+	// SysRead(fd int, p []byte) int
+	add("SysRead", syscall.READ, []types.Type{types.I64, byteSlice})
+
+	// SysWrite(fd int, p []byte) int
+	add("SysWrite", syscall.WRITE, []types.Type{types.I64, byteSlice})
+
+	// SysOpen(path string, flags int, perm int) int
+	add("SysOpen", syscall.OPEN, []types.Type{types.String, types.I64, types.I64})
+
+	// SysClose(fd int) int
+	add("SysClose", syscall.CLOSE, []types.Type{types.I64})
+
+	// SysSeek(fd int, offset int, whence int) int
+	add("SysSeek", syscall.LSEEK, []types.Type{types.I64, types.I64, types.I64})
+
+	// SysStat(path string, buf []byte) int writes the platform specific stat
+	// structure to buf, which has to be large enough to hold it
+	add("SysStat", syscall.STAT, []types.Type{types.String, byteSlice})
+
+	// SysUnlink(path string) int
+	add("SysUnlink", syscall.UNLINK, []types.Type{types.String})
+
+	// SysMkdir(path string, perm int) int
+	add("SysMkdir", syscall.MKDIR, []types.Type{types.String, types.I64})
+
+	// SysGetdents(fd int, buf []byte) int reads platform specific directory
+	// entries to buf
+	add("SysGetdents", syscall.GETDENTS, []types.Type{types.I64, byteSlice})
+
+	c.addFutex()
+	c.addClock()
+
+	for flagName, flagValue := range openFlags[c.GOOS] {
+		c.packages["external"].DefinePkgVar(flagName, value.Value{
+			Type:  types.I64,
+			Value: constant.NewInt(llvmTypes.I64, flagValue),
+		})
+	}
+}
+
+// Operations of the futex syscall on linux, and of __ulock_wait and
+// __ulock_wake on darwin
+const (
+	futexWaitPrivate = 128
+	futexWakePrivate = 129
+
+	ulCompareAndWait = 1
+	ulfWakeAll       = 0x100
+)
+
+// addFutex adds FutexWait(addr *int32, val int32) int, that sleeps until
+// FutexWake is called with the same address, if *addr is still val. The
+// sleep can end spuriously, callers have to check the value again.
+//
+// FutexWake(addr *int32, n int32) int wakes up to n of the threads that are
+// sleeping on addr.
+//
+// Futexes are used on linux, and the similar ulock syscalls on darwin.
+func (c *Compiler) addFutex() {
+	addrType := &types.Pointer{Type: types.I32, LlvmType: llvmTypes.NewPointer(llvmTypes.I32)}
+	argTypes := []types.Type{addrType, types.I32}
+
+	i64 := func(v int64) llvmValue.Value {
+		return constant.NewInt(llvmTypes.I64, v)
+	}
+
+	add := func(funcName string, f syscall.Fn, args func(block *ir.Block, addr, val llvmValue.Value) []llvmValue.Value) {
+		fn := c.module.NewFunc(name.Var("syscall-"+string(f)), llvmTypes.I64,
+			ir.NewParam(name.Var("arg"), addrType.LLVM()), ir.NewParam(name.Var("arg"), llvmTypes.I32))
+		block := fn.NewBlock(name.Block())
+		block.NewRet(syscall.Call(block, f, c.GOOS, c.GOARCH, args(block, fn.Params[0], fn.Params[1])...))
+
+		c.defineExternalFunc(funcName, fn, types.I64, argTypes)
+	}
+
+	if c.GOOS == "darwin" {
+		add("FutexWait", syscall.FUTEX_WAIT, func(block *ir.Block, addr, val llvmValue.Value) []llvmValue.Value {
+			// The timeout is 0, to sleep without a timeout
+			return []llvmValue.Value{i64(ulCompareAndWait), addr, block.NewZExt(val, llvmTypes.I64), i64(0)}
+		})
+		add("FutexWake", syscall.FUTEX_WAKE, func(block *ir.Block, addr, n llvmValue.Value) []llvmValue.Value {
+			// A single thread, or all of them, can be woken up
+			wakeAll := block.NewICmp(enum.IPredSGT, n, constant.NewInt(llvmTypes.I32, 1))
+			op := block.NewSelect(wakeAll, i64(ulCompareAndWait|ulfWakeAll), i64(ulCompareAndWait))
+			return []llvmValue.Value{op, addr, i64(0)}
+		})
+		return
+	}
+
+	add("FutexWait", syscall.FUTEX_WAIT, func(block *ir.Block, addr, val llvmValue.Value) []llvmValue.Value {
+		// The timeout is NULL, to sleep without a timeout
+		return []llvmValue.Value{addr, i64(futexWaitPrivate), block.NewSExt(val, llvmTypes.I64), i64(0)}
+	})
+	add("FutexWake", syscall.FUTEX_WAKE, func(block *ir.Block, addr, n llvmValue.Value) []llvmValue.Value {
+		return []llvmValue.Value{addr, i64(futexWakePrivate), block.NewSExt(n, llvmTypes.I64)}
+	})
+}
+
+// etimedout is returned by __semwait_signal on darwin when the sleep is over
+const etimedout = 60
+
+// addClock adds SysClockGettime(clock int, sec *int, nsec *int) int, that
+// reads the time of clock, and SysNanosleep(sec int, nsec int) int, that
+// sleeps for the duration. The sleep ends early with -EINTR if it's
+// interrupted by a signal.
+//
+// Darwin does not have clock_gettime and nanosleep syscalls.
+// gettimeofday is used instead of clock_gettime, so the time always comes
+// from the wall clock with microsecond precision, and __semwait_signal is
+// used to sleep.
+func (c *Compiler) addClock() {
+	i64 := llvmTypes.I64
+	i8Ptr := llvmTypes.I8Ptr
+	intPtr := &types.Pointer{Type: types.I64, LlvmType: llvmTypes.NewPointer(i64)}
+
+	gettime := c.module.NewFunc(name.Var("syscall-"+string(syscall.CLOCK_GETTIME)), i64,
+		ir.NewParam("clock", i64), ir.NewParam("sec", intPtr.LLVM()), ir.NewParam("nsec", intPtr.LLVM()))
+	block := gettime.NewBlock(name.Block())
+	var res, nsec llvmValue.Value
+	if c.GOOS == "darwin" {
+		tvType := llvmTypes.NewStruct(i64, llvmTypes.I32)
+		tv := block.NewAlloca(tvType)
+		res = syscall.Call(block, syscall.CLOCK_GETTIME, c.GOOS, c.GOARCH, tv, constant.NewNull(i8Ptr), constant.NewNull(i8Ptr))
+		ts := block.NewLoad(tvType, tv)
+		block.NewStore(block.NewExtractValue(ts, 0), gettime.Params[1])
+		nsec = block.NewMul(block.NewSExt(block.NewExtractValue(ts, 1), i64), constant.NewInt(i64, 1000))
+	} else {
+		tsType := llvmTypes.NewStruct(i64, i64)
+		tsPtr := block.NewAlloca(tsType)
+		res = syscall.Call(block, syscall.CLOCK_GETTIME, c.GOOS, c.GOARCH, gettime.Params[0], tsPtr)
+		ts := block.NewLoad(tsType, tsPtr)
+		block.NewStore(block.NewExtractValue(ts, 0), gettime.Params[1])
+		nsec = block.NewExtractValue(ts, 1)
+	}
+	block.NewStore(nsec, gettime.Params[2])
+	block.NewRet(res)
+	c.defineExternalFunc("SysClockGettime", gettime, types.I64, []types.Type{types.I64, intPtr, intPtr})
+
+	sleep := c.module.NewFunc(name.Var("syscall-"+string(syscall.NANOSLEEP)), i64,
+		ir.NewParam("sec", i64), ir.NewParam("nsec", i64))
+	block = sleep.NewBlock(name.Block())
+	if c.GOOS == "darwin" {
+		// __semwait_signal(cond_sem, mutex_sem, timeout, relative, tv_sec, tv_nsec)
+		zero, one := constant.NewInt(i64, 0), constant.NewInt(i64, 1)
+		res = syscall.Call(block, syscall.NANOSLEEP, c.GOOS, c.GOARCH, zero, zero, one, one, sleep.Params[0], sleep.Params[1])
+		timedOut := block.NewICmp(enum.IPredEQ, res, constant.NewInt(i64, -etimedout))
+		res = block.NewSelect(timedOut, zero, res)
+	} else {
+		tsType := llvmTypes.NewStruct(i64, i64)
+		ts := block.NewAlloca(tsType)
+		var tsVal llvmValue.Value = constant.NewUndef(tsType)
+		tsVal = block.NewInsertValue(tsVal, sleep.Params[0], 0)
+		tsVal = block.NewInsertValue(tsVal, sleep.Params[1], 1)
+		block.NewStore(tsVal, ts)
+		res = syscall.Call(block, syscall.NANOSLEEP, c.GOOS, c.GOARCH, ts, constant.NewNull(i8Ptr))
+	}
+	block.NewRet(res)
+	c.defineExternalFunc("SysNanosleep", sleep, types.I64, []types.Type{types.I64, types.I64})
+}
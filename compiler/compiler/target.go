@@ -0,0 +1,113 @@
+package compiler
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Target is the platform that the compiler generates code for
+type Target struct {
+	GOOS   string
+	GOARCH string
+}
+
+// HostTarget returns the platform that tre is running on
+func HostTarget() Target {
+	return Target{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+}
+
+// ParseTargetTriple converts a LLVM target triple to a Target.
+// Triples on the format "x86_64-pc-linux-gnu", "aarch64-linux-gnu" and
+// "arm64-apple-macosx11.0.0" are supported.
+func ParseTargetTriple(triple string) (Target, error) {
+	parts := strings.Split(triple, "-")
+	if len(parts) < 2 {
+		return Target{}, fmt.Errorf("invalid target triple: %s", triple)
+	}
+
+	var t Target
+
+	switch parts[0] {
+	case "x86_64", "amd64":
+		t.GOARCH = "amd64"
+	case "aarch64", "arm64":
+		t.GOARCH = "arm64"
+	default:
+		return Target{}, fmt.Errorf("unsupported architecture in target triple: %s", triple)
+	}
+
+	for _, part := range parts[1:] {
+		switch {
+		case part == "linux":
+			t.GOOS = "linux"
+		case part == "darwin" || strings.HasPrefix(part, "macos"):
+			t.GOOS = "darwin"
+		case part == "windows":
+			t.GOOS = "windows"
+		}
+	}
+
+	if t.GOOS == "" {
+		return Target{}, fmt.Errorf("unsupported operating system in target triple: %s", triple)
+	}
+
+	return t, nil
+}
+
+// Validate returns an error if tre can not generate code for the target.
+// Only 64 bit targets are supported, as int and uintptr is always 64 bits.
+func (t Target) Validate() error {
+	switch t.GOARCH {
+	case "amd64", "arm64":
+	default:
+		return fmt.Errorf("unsupported GOARCH: %s", t.GOARCH)
+	}
+
+	switch t.GOOS {
+	case "darwin", "linux", "windows":
+	default:
+		return fmt.Errorf("unsupported GOOS: %s", t.GOOS)
+	}
+
+	return nil
+}
+
+// Triple returns the LLVM target triple, such as x86_64-pc-linux-gnu
+func (t Target) Triple() string {
+	arch := map[string]string{
+		"amd64": "x86_64",
+		"arm64": "aarch64",
+	}[t.GOARCH]
+
+	switch t.GOOS {
+	case "darwin":
+		if t.GOARCH == "arm64" {
+			return "arm64-apple-macosx11.0.0"
+		}
+		return arch + "-apple-macosx10.13.0"
+	case "windows":
+		return arch + "-pc-windows-msvc"
+	default:
+		return arch + "-pc-linux-gnu"
+	}
+}
+
+// DataLayout returns the LLVM data layout of the target, it describes the
+// size and alignment of types and pointers.
+func (t Target) DataLayout() string {
+	mangling := map[string]string{
+		"darwin":  "m:o",
+		"linux":   "m:e",
+		"windows": "m:w",
+	}[t.GOOS]
+
+	if t.GOARCH == "arm64" {
+		if t.GOOS == "linux" {
+			return "e-" + mangling + "-i8:8:32-i16:16:32-i64:64-i128:128-n32:64-S128"
+		}
+		return "e-" + mangling + "-i64:64-i128:128-n32:64-S128"
+	}
+
+	return "e-" + mangling + "-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+}
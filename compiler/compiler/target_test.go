@@ -0,0 +1,38 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTargetTriple(t *testing.T) {
+	tests := map[string]Target{
+		"x86_64-pc-linux-gnu":        {GOOS: "linux", GOARCH: "amd64"},
+		"x86_64-linux-gnu":           {GOOS: "linux", GOARCH: "amd64"},
+		"aarch64-linux-gnu":          {GOOS: "linux", GOARCH: "arm64"},
+		"aarch64-unknown-linux-gnu":  {GOOS: "linux", GOARCH: "arm64"},
+		"arm64-apple-macosx11.0.0":   {GOOS: "darwin", GOARCH: "arm64"},
+		"x86_64-apple-darwin":        {GOOS: "darwin", GOARCH: "amd64"},
+		"x86_64-apple-macosx10.13.0": {GOOS: "darwin", GOARCH: "amd64"},
+		"x86_64-pc-windows-msvc":     {GOOS: "windows", GOARCH: "amd64"},
+	}
+
+	for triple, expected := range tests {
+		target, err := ParseTargetTriple(triple)
+		assert.Nil(t, err, triple)
+		assert.Equal(t, expected, target, triple)
+
+		// The triple of the parsed target must describe the same platform
+		roundtrip, err := ParseTargetTriple(target.Triple())
+		assert.Nil(t, err, triple)
+		assert.Equal(t, expected, roundtrip, triple)
+	}
+}
+
+func TestParseTargetTripleUnsupported(t *testing.T) {
+	for _, triple := range []string{"i686-pc-linux-gnu", "x86_64-unknown-freebsd", "x86_64"} {
+		_, err := ParseTargetTriple(triple)
+		assert.NotNil(t, err, triple)
+	}
+}
@@ -0,0 +1,373 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	llvmTypes "github.com/llir/llvm/ir/types"
+	llvmValue "github.com/llir/llvm/ir/value"
+
+	"github.com/zegl/tre/compiler/compiler/internal"
+	"github.com/zegl/tre/compiler/compiler/name"
+	"github.com/zegl/tre/compiler/compiler/types"
+)
+
+// An interface value only knows the type ID of the value that it's holding at
+// runtime. Information about the type, such as its size or methods, is looked
+// up with functions that are generated when the compilation is done, as all
+// types and methods are known at that point.
+
+type typeInfo struct {
+	// All types that have been converted to an interface, by type ID
+	types map[int64]types.Type
+
+	// type-size(i32 typeID) i64 returns the size of the type
+	sizeFunc *ir.Func
+
+	// type-elem(i32 typeID) i32 returns the type ID of the type that a pointer
+	// type points to, or 0 if it's not a pointer type
+	elemFunc *ir.Func
+
+	// interface-equal(i8* aData, i32 aType, i8* bData, i32 bType) i1 compares
+	// two interface values
+	equalFunc *ir.Func
+
+	// type-equal(i32 typeID, i8* a, i8* b) i1 compares the values of the
+	// type that a and b points to
+	typeEqualFunc *ir.Func
+
+	// Functions that returns the jump table to use when the type is converted
+	// to an interface, or null if the type does not implement the interface.
+	// Is indexed by the interfaceKey of the interface.
+	tableFuncs map[string]*interfaceTableFunc
+
+	// Jump tables by interfaceKey and type ID
+	jumpTables map[string]map[int64]*ir.Global
+
+	// Functions in the external package that inspects values in interfaces
+	reflectFuncs []*reflectFunc
+}
+
+type interfaceTableFunc struct {
+	iface *types.Interface
+	fn    *ir.Func
+}
+
+func (c *Compiler) addTypeInfo() {
+	c.typeInfo = typeInfo{
+		types:      make(map[int64]types.Type),
+		tableFuncs: make(map[string]*interfaceTableFunc),
+		jumpTables: make(map[string]map[int64]*ir.Global),
+	}
+
+	c.typeInfo.sizeFunc = c.module.NewFunc("type-size", llvmTypes.I64, ir.NewParam("type-id", llvmTypes.I32))
+	c.typeInfo.elemFunc = c.module.NewFunc("type-elem", llvmTypes.I32, ir.NewParam("type-id", llvmTypes.I32))
+	c.typeInfo.typeEqualFunc = c.module.NewFunc("type-equal", llvmTypes.I1,
+		ir.NewParam("type-id", llvmTypes.I32), ir.NewParam("a", llvmTypes.I8Ptr), ir.NewParam("b", llvmTypes.I8Ptr))
+
+	fn := c.module.NewFunc("interface-equal", llvmTypes.I1,
+		ir.NewParam("a-data", llvmTypes.I8Ptr),
+		ir.NewParam("a-type", llvmTypes.I32),
+		ir.NewParam("b-data", llvmTypes.I8Ptr),
+		ir.NewParam("b-type", llvmTypes.I32),
+	)
+	entry := fn.NewBlock(name.Block())
+	checkNil := fn.NewBlock(name.Block())
+	checkPointer := fn.NewBlock(name.Block())
+	comparePointer := fn.NewBlock(name.Block())
+	compareData := fn.NewBlock(name.Block())
+	equal := fn.NewBlock(name.Block())
+	notEqual := fn.NewBlock(name.Block())
+
+	aData, aType, bData, bType := fn.Params[0], fn.Params[1], fn.Params[2], fn.Params[3]
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, aType, bType), checkNil, notEqual)
+
+	isNil := checkNil.NewICmp(enum.IPredEQ, aType, constant.NewInt(llvmTypes.I32, 0))
+	checkNil.NewCondBr(isNil, equal, checkPointer)
+
+	// Pointers are stored directly in the interface, and are only equal if the
+	// data is the same. Other values are compared by their type.
+	isPointer := checkPointer.NewICmp(enum.IPredNE, checkPointer.NewCall(c.typeInfo.elemFunc, aType), constant.NewInt(llvmTypes.I32, 0))
+	checkPointer.NewCondBr(isPointer, comparePointer, compareData)
+
+	comparePointer.NewCondBr(comparePointer.NewICmp(enum.IPredEQ, aData, bData), equal, notEqual)
+
+	compareData.NewRet(compareData.NewCall(c.typeInfo.typeEqualFunc, aType, aData, bData))
+
+	equal.NewRet(constant.True)
+	notEqual.NewRet(constant.False)
+
+	c.typeInfo.equalFunc = fn
+
+	c.addAssignInterface()
+}
+
+// addAssignInterface adds external.AssignInterface(target, val interface{}) bool.
+// If target is a pointer to a variable of the same type as the value in val,
+// the value is copied to the variable and true is returned. It is used by
+// errors.As, as the type of a value in an interface can't be checked from tre.
+func (c *Compiler) addAssignInterface() {
+	emptyIface := &types.Interface{}
+
+	fn := c.module.NewFunc("interface-assign", llvmTypes.I1,
+		ir.NewParam("target", emptyIface.LLVM()),
+		ir.NewParam("val", emptyIface.LLVM()),
+	)
+	entry := fn.NewBlock(name.Block())
+	assign := fn.NewBlock(name.Block())
+	assignPointer := fn.NewBlock(name.Block())
+	assignValue := fn.NewBlock(name.Block())
+	notAssigned := fn.NewBlock(name.Block())
+
+	targetData := entry.NewExtractValue(fn.Params[0], 0)
+	targetType := entry.NewExtractValue(fn.Params[0], 1)
+	data := entry.NewExtractValue(fn.Params[1], 0)
+	dataType := entry.NewExtractValue(fn.Params[1], 1)
+
+	isNil := entry.NewICmp(enum.IPredEQ, dataType, constant.NewInt(llvmTypes.I32, 0))
+	isSameType := entry.NewICmp(enum.IPredEQ, entry.NewCall(c.typeInfo.elemFunc, targetType), dataType)
+	entry.NewCondBr(entry.NewAnd(isSameType, entry.NewXor(isNil, constant.True)), assign, notAssigned)
+
+	// Pointers are stored directly in the interface, other values are pointed to
+	isPointer := assign.NewICmp(enum.IPredNE, assign.NewCall(c.typeInfo.elemFunc, dataType), constant.NewInt(llvmTypes.I32, 0))
+	assign.NewCondBr(isPointer, assignPointer, assignValue)
+
+	assignPointer.NewStore(data, assignPointer.NewBitCast(targetData, llvmTypes.NewPointer(llvmTypes.I8Ptr)))
+	assignPointer.NewRet(constant.True)
+
+	size := assignValue.NewCall(c.typeInfo.sizeFunc, dataType)
+	assignValue.NewCall(c.externalFuncs.Memcpy.Value.(llvmValue.Named), targetData, data, size)
+	assignValue.NewRet(constant.True)
+
+	notAssigned.NewRet(constant.False)
+
+	c.defineExternalFunc("AssignInterface", fn, types.Bool, []types.Type{emptyIface, emptyIface})
+}
+
+// registerInterfaceType is called when a value of type t is converted to an
+// interface, and returns the type ID of t
+func (c *Compiler) registerInterfaceType(t types.Type) int64 {
+	typeID := getTypeID(t.Name())
+	c.typeInfo.types[typeID] = t
+	return typeID
+}
+
+// interfaceKey identifies the methods of an interface. Interfaces with the same
+// key have the same jump tables.
+func interfaceKey(iface *types.Interface) string {
+	return strings.Join(iface.SortedRequiredMethods(), ",") + " " + iface.JumpTable().String()
+}
+
+// interfaceJumpTable returns a global with the methods of the type with typeID
+// that are used by iface. ok is false if the type does not implement iface.
+func (c *Compiler) interfaceJumpTable(iface *types.Interface, typeID int64) (*ir.Global, bool) {
+	tables, ok := c.typeInfo.jumpTables[interfaceKey(iface)]
+	if !ok {
+		tables = make(map[int64]*ir.Global)
+		c.typeInfo.jumpTables[interfaceKey(iface)] = tables
+	}
+	if table, ok := tables[typeID]; ok {
+		return table, table != nil
+	}
+
+	methodsFromType := c.typeInfo.types[typeID]
+
+	// Pointer receiver methods are added to their "parent" type
+	if ptrType, ok := methodsFromType.(*types.Pointer); ok {
+		methodsFromType = ptrType.Type
+	}
+
+	tableType := iface.JumpTable()
+
+	var jumpFuncs []constant.Constant
+	for methodIndex, methodName := range iface.SortedRequiredMethods() {
+		m, ok := methodsFromType.GetMethod(methodName)
+		if !ok || !m.Function.JumpFunction.Type().Equal(tableType.Fields[methodIndex]) {
+			tables[typeID] = nil
+			return nil, false
+		}
+		jumpFuncs = append(jumpFuncs, m.Function.JumpFunction)
+	}
+
+	table := c.module.NewGlobalDef(name.Var("jumptable"), constant.NewStruct(tableType, jumpFuncs...))
+	table.Immutable = true
+	tables[typeID] = table
+	return table, true
+}
+
+// interfaceTableFunc returns a function that looks up the jump table of a type
+// ID for iface at runtime, is used when converting an interface to another interface
+func (c *Compiler) interfaceTableFunc(iface *types.Interface) *ir.Func {
+	if tableFunc, ok := c.typeInfo.tableFuncs[interfaceKey(iface)]; ok {
+		return tableFunc.fn
+	}
+
+	fn := c.module.NewFunc(name.Var("interface-table"), llvmTypes.NewPointer(iface.JumpTable()), ir.NewParam("type-id", llvmTypes.I32))
+	c.typeInfo.tableFuncs[interfaceKey(iface)] = &interfaceTableFunc{iface: iface, fn: fn}
+	return fn
+}
+
+// finalizeTypeInfo generates the bodies of the runtime type information functions
+func (c *Compiler) finalizeTypeInfo() {
+	c.registerReachableTypes()
+
+	var typeIDs []int64
+	for typeID := range c.typeInfo.types {
+		typeIDs = append(typeIDs, typeID)
+	}
+	sort.Slice(typeIDs, func(i, j int) bool { return typeIDs[i] < typeIDs[j] })
+
+	var sizes, elems []constant.Constant
+	for _, typeID := range typeIDs {
+		t := c.typeInfo.types[typeID]
+		sizes = append(sizes, internal.SizeOf(t.LLVM()))
+
+		var elem int64
+		if ptrType, ok := t.(*types.Pointer); ok {
+			elem = getTypeID(ptrType.Type.Name())
+		}
+		elems = append(elems, constant.NewInt(llvmTypes.I32, elem))
+	}
+
+	c.typeSwitchFunc(c.typeInfo.sizeFunc, typeIDs, sizes, constant.NewInt(llvmTypes.I64, 0))
+	c.typeSwitchFunc(c.typeInfo.elemFunc, typeIDs, elems, constant.NewInt(llvmTypes.I32, 0))
+	c.finalizeTypeEqual(typeIDs)
+
+	var ifaceKeys []string
+	for key := range c.typeInfo.tableFuncs {
+		ifaceKeys = append(ifaceKeys, key)
+	}
+	sort.Strings(ifaceKeys)
+
+	for _, key := range ifaceKeys {
+		tableFunc := c.typeInfo.tableFuncs[key]
+		tablePtrType := tableFunc.fn.Sig.RetType.(*llvmTypes.PointerType)
+
+		var tableTypeIDs []int64
+		var tables []constant.Constant
+		for _, typeID := range typeIDs {
+			if table, ok := c.interfaceJumpTable(tableFunc.iface, typeID); ok {
+				tableTypeIDs = append(tableTypeIDs, typeID)
+				tables = append(tables, table)
+			}
+		}
+
+		c.typeSwitchFunc(tableFunc.fn, tableTypeIDs, tables, constant.NewNull(tablePtrType))
+	}
+
+	c.finalizeReflection(typeIDs)
+}
+
+// finalizeTypeEqual generates the body of type-equal, that compares the values
+// by their type like the == operator does. Strings are equal if their contents
+// are, floats are compared as floats, and structs and arrays are compared field
+// by field and element by element.
+func (c *Compiler) finalizeTypeEqual(typeIDs []int64) {
+	fn := c.typeInfo.typeEqualFunc
+	fn.Blocks = nil
+
+	entry := fn.NewBlock(name.Block())
+	defaultBlock := fn.NewBlock(name.Block())
+	defaultBlock.NewRet(constant.False)
+
+	var cases []*ir.Case
+	for _, typeID := range typeIDs {
+		t := c.typeInfo.types[typeID]
+		block := fn.NewBlock(name.Block())
+		ptrType := llvmTypes.NewPointer(t.LLVM())
+		res, ok := c.valuesEqual(block, t, block.NewBitCast(fn.Params[1], ptrType), block.NewBitCast(fn.Params[2], ptrType))
+		if !ok {
+			fn.Blocks = fn.Blocks[:len(fn.Blocks)-1]
+			continue
+		}
+		block.NewRet(res)
+		cases = append(cases, ir.NewCase(constant.NewInt(llvmTypes.I32, typeID), block))
+	}
+
+	entry.NewSwitch(fn.Params[0], defaultBlock, cases...)
+}
+
+// valuesEqual compares the values of type t that a and b points to. ok is false
+// if values of the type can't be compared, such as slices and functions.
+func (c *Compiler) valuesEqual(block *ir.Block, t types.Type, a, b llvmValue.Value) (res llvmValue.Value, ok bool) {
+	switch tt := t.(type) {
+	case *types.Int, *types.BoolType, *types.Pointer:
+		return block.NewICmp(enum.IPredEQ, block.NewLoad(t.LLVM(), a), block.NewLoad(t.LLVM(), b)), true
+
+	case *types.Float:
+		return block.NewFCmp(enum.FPredOEQ, block.NewLoad(t.LLVM(), a), block.NewLoad(t.LLVM(), b)), true
+
+	case *types.StringType:
+		cmp := block.NewCall(c.stringCompareFunc, block.NewLoad(t.LLVM(), a), block.NewLoad(t.LLVM(), b))
+		return block.NewICmp(enum.IPredEQ, cmp, constant.NewInt(llvmTypes.I32, 0)), true
+
+	case *types.Interface:
+		aIface, bIface := block.NewLoad(t.LLVM(), a), block.NewLoad(t.LLVM(), b)
+		return block.NewCall(c.typeInfo.equalFunc,
+			block.NewExtractValue(aIface, 0), block.NewExtractValue(aIface, 1),
+			block.NewExtractValue(bIface, 0), block.NewExtractValue(bIface, 1),
+		), true
+
+	case *types.Struct:
+		res = constant.True
+		for _, fieldName := range structFieldNames(tt) {
+			index := constant.NewInt(llvmTypes.I32, int64(tt.MemberIndexes[fieldName]))
+			zero := constant.NewInt(llvmTypes.I32, 0)
+			fieldEqual, ok := c.valuesEqual(block, tt.Members[fieldName],
+				block.NewGetElementPtr(tt.LLVM(), a, zero, index),
+				block.NewGetElementPtr(tt.LLVM(), b, zero, index),
+			)
+			if !ok {
+				return nil, false
+			}
+			res = block.NewAnd(res, fieldEqual)
+		}
+		return res, true
+
+	case *types.Array:
+		res = constant.True
+		for i := uint64(0); i < tt.Len; i++ {
+			index := constant.NewInt(llvmTypes.I64, int64(i))
+			zero := constant.NewInt(llvmTypes.I64, 0)
+			elemEqual, ok := c.valuesEqual(block, tt.Type,
+				block.NewGetElementPtr(tt.LLVM(), a, zero, index),
+				block.NewGetElementPtr(tt.LLVM(), b, zero, index),
+			)
+			if !ok {
+				return nil, false
+			}
+			res = block.NewAnd(res, elemEqual)
+		}
+		return res, true
+	}
+
+	return nil, false
+}
+
+// typeSwitchFunc sets the body of fn to return results[i] if the type ID
+// argument is typeIDs[i], and defaultResult otherwise
+func (c *Compiler) typeSwitchFunc(fn *ir.Func, typeIDs []int64, results []constant.Constant, defaultResult constant.Constant) {
+	if len(typeIDs) != len(results) {
+		panic(fmt.Sprintf("typeSwitchFunc: got %d type IDs and %d results", len(typeIDs), len(results)))
+	}
+
+	// The function is generated again if the IR is requested more than once
+	fn.Blocks = nil
+
+	entry := fn.NewBlock(name.Block())
+	defaultBlock := fn.NewBlock(name.Block())
+	defaultBlock.NewRet(defaultResult)
+
+	var cases []*ir.Case
+	for i, typeID := range typeIDs {
+		block := fn.NewBlock(name.Block())
+		block.NewRet(results[i])
+		cases = append(cases, ir.NewCase(constant.NewInt(llvmTypes.I32, typeID), block))
+	}
+
+	entry.NewSwitch(fn.Params[0], defaultBlock, cases...)
+}
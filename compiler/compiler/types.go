@@ -38,7 +38,11 @@ func (c *Compiler) parserTypeToType(typeNode parser.TypeNode) types.Type {
 	switch t := typeNode.(type) {
 	case *parser.SingleTypeNode:
 		if len(t.PackageName) > 0 {
-			tp, ok := c.packages[t.PackageName].GetPkgType(t.TypeName, false)
+			p, ok := c.importedPackage(t.PackageName)
+			if !ok {
+				panic("package " + t.PackageName + " does not exist")
+			}
+			tp, ok := p.GetPkgType(t.TypeName, false)
 			if !ok {
 				panic("unknown type: " + t.PackageName + "." + t.TypeName)
 			}
@@ -138,6 +142,28 @@ func (c *Compiler) parserTypeToType(typeNode parser.TypeNode) types.Type {
 func (c *Compiler) compileTypeCastNode(v *parser.TypeCastNode) value.Value {
 	val := c.compileValue(v.Val)
 
+	// Untyped constants are converted directly to the target type
+	if _, ok := val.Type.(*types.UntypedConstantNumber); ok {
+		return value.UntypedConstAs(val, value.Value{Type: c.parserTypeToType(v.Type)})
+	}
+
+	targetType := c.parserTypeToType(v.Type)
+
+	// Conversions between strings and byte slices
+	if _, ok := targetType.(*types.Slice); ok {
+		return c.compileStringToBytes(val, targetType.(*types.Slice))
+	}
+	if _, ok := val.Type.(*types.Slice); ok {
+		return c.compileBytesToString(val)
+	}
+
+	// Conversions to and from floats
+	_, targetIsFloat := targetType.(*types.Float)
+	_, valIsFloat := val.Type.(*types.Float)
+	if targetIsFloat || valIsFloat {
+		return c.compileFloatConversion(val, targetType)
+	}
+
 	var current *llvmTypes.IntType
 	var ok bool
 
@@ -146,7 +172,6 @@ func (c *Compiler) compileTypeCastNode(v *parser.TypeCastNode) value.Value {
 		panic("TypeCast origin must be int type")
 	}
 
-	targetType := c.parserTypeToType(v.Type)
 	target, ok := targetType.LLVM().(*llvmTypes.IntType)
 	if !ok {
 		panic("TypeCast target must be int type")
@@ -154,17 +179,24 @@ func (c *Compiler) compileTypeCastNode(v *parser.TypeCastNode) value.Value {
 
 	llvmVal := internal.LoadIfVariable(c.contextBlock, val)
 
-	// Same size, nothing to do here
+	// Same size, only the type is changed
 	if current.BitSize == target.BitSize {
-		return val
+		return value.Value{
+			Value: llvmVal,
+			Type:  targetType,
+		}
 	}
 
-	res := c.contextBlock.NewAlloca(target)
+	res := c.entryBlockAlloca(target)
 
 	var changedSize llvmValue.Value
 
 	if current.BitSize < target.BitSize {
-		changedSize = c.contextBlock.NewSExt(llvmVal, target)
+		if val.Type.IsSigned() {
+			changedSize = c.contextBlock.NewSExt(llvmVal, target)
+		} else {
+			changedSize = c.contextBlock.NewZExt(llvmVal, target)
+		}
 	} else {
 		changedSize = c.contextBlock.NewTrunc(llvmVal, target)
 	}
@@ -181,12 +213,16 @@ func (c *Compiler) compileTypeCastNode(v *parser.TypeCastNode) value.Value {
 func (c *Compiler) compileTypeCastInterfaceNode(v *parser.TypeCastInterfaceNode) value.Value {
 	tryCastToType := c.parserTypeToType(v.Type)
 
+	if iface, ok := tryCastToType.(*types.Interface); ok {
+		return c.compileTypeCastToInterface(c.compileValue(v.Item), iface)
+	}
+
 	// Allocate the OK variable
-	okVal := c.contextBlock.NewAlloca(types.Bool.LLVM())
+	okVal := c.entryBlockAlloca(types.Bool.LLVM())
 	types.Bool.Zero(c.contextBlock, okVal)
 	okVal.SetName(name.Var("ok"))
 
-	resCastedVal := c.contextBlock.NewAlloca(tryCastToType.LLVM())
+	resCastedVal := c.entryBlockAlloca(tryCastToType.LLVM())
 	tryCastToType.Zero(c.contextBlock, resCastedVal)
 	resCastedVal.SetName(name.Var("rescastedval"))
 
@@ -210,9 +246,15 @@ func (c *Compiler) compileTypeCastInterfaceNode(v *parser.TypeCastInterfaceNode)
 
 	backingDataPtr := trueBlock.NewGetElementPtr(pointer.ElemType(interfaceVal.Value), interfaceVal.Value, constant.NewInt(llvmTypes.I32, 0), constant.NewInt(llvmTypes.I32, 0))
 	loadedBackingDataPtr := trueBlock.NewLoad(pointer.ElemType(backingDataPtr), backingDataPtr)
-	casted := trueBlock.NewBitCast(loadedBackingDataPtr, llvmTypes.NewPointer(tryCastToType.LLVM()))
-	loadedCasted := trueBlock.NewLoad(pointer.ElemType(casted), casted)
-	trueBlock.NewStore(loadedCasted, resCastedVal)
+
+	// Pointers are stored directly in the interface, other values are pointed to
+	if _, isPointer := tryCastToType.(*types.Pointer); isPointer {
+		trueBlock.NewStore(trueBlock.NewBitCast(loadedBackingDataPtr, tryCastToType.LLVM()), resCastedVal)
+	} else {
+		casted := trueBlock.NewBitCast(loadedBackingDataPtr, llvmTypes.NewPointer(tryCastToType.LLVM()))
+		loadedCasted := trueBlock.NewLoad(pointer.ElemType(casted), casted)
+		trueBlock.NewStore(loadedCasted, resCastedVal)
+	}
 
 	c.contextBlock = afterBlock
 
@@ -229,3 +271,42 @@ func (c *Compiler) compileTypeCastInterfaceNode(v *parser.TypeCastInterfaceNode)
 		},
 	}
 }
+
+// compileTypeCastToInterface checks if the value in the interface val
+// implements iface, and converts it to iface if it does
+func (c *Compiler) compileTypeCastToInterface(val value.Value, iface *types.Interface) value.Value {
+	converted := internal.LoadIfVariable(c.contextBlock, c.interfaceToInterfaceValue(val, iface))
+	typeID := c.contextBlock.NewExtractValue(converted, 1)
+
+	// A nil interface can not be converted
+	var ok llvmValue.Value = c.contextBlock.NewICmp(enum.IPredNE, typeID, constant.NewInt(llvmTypes.I32, 0))
+
+	// The jump table is null if the type does not implement the methods of iface
+	if len(iface.RequiredMethods) > 0 {
+		table := c.contextBlock.NewExtractValue(converted, 2)
+		ok = c.contextBlock.NewICmp(enum.IPredNE, table, constant.NewZeroInitializer(table.Type()))
+	}
+
+	res := c.contextBlock.NewSelect(ok, converted, constant.NewZeroInitializer(iface.LLVM()))
+
+	resCastedVal := c.entryBlockAlloca(iface.LLVM())
+	resCastedVal.SetName(name.Var("rescastedval"))
+	c.contextBlock.NewStore(res, resCastedVal)
+
+	okVal := c.entryBlockAlloca(types.Bool.LLVM())
+	okVal.SetName(name.Var("ok"))
+	c.contextBlock.NewStore(ok, okVal)
+
+	return value.Value{
+		Type: &types.MultiValue{
+			Types: []types.Type{
+				iface,
+				types.Bool,
+			},
+		},
+		MultiValues: []value.Value{
+			{Type: iface, Value: resCastedVal, IsVariable: true},
+			{Type: types.Bool, Value: okVal, IsVariable: true},
+		},
+	}
+}
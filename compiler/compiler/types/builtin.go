@@ -12,6 +12,9 @@ var I64 = &Int{Type: types.I64, TypeName: "int64", TypeSize: 64 / 8, Signed: tru
 var U64 = &Int{Type: types.I64, TypeName: "uint64", TypeSize: 64 / 8}
 var Uintptr = &Int{Type: types.I64, TypeName: "uintptr", TypeSize: 64 / 8}
 
+var F32 = &Float{Type: types.Float, TypeName: "float32", TypeSize: 32 / 8}
+var F64 = &Float{Type: types.Double, TypeName: "float64", TypeSize: 64 / 8}
+
 var Void = &VoidType{}
 var String = &StringType{}
 var Bool = &BoolType{}
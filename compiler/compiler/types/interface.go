@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
 	llvmValue "github.com/llir/llvm/ir/value"
 )
@@ -39,11 +41,18 @@ func (i Interface) JumpTable() *types.StructType {
 		methodSignature := i.RequiredMethods[methodName]
 
 		var retType types.Type = types.Void
-		if len(methodSignature.ReturnTypes) > 0 {
+		var paramTypes []types.Type
+
+		if len(methodSignature.ReturnTypes) == 1 {
 			retType = methodSignature.ReturnTypes[0].LLVM()
+		} else {
+			// Multiple values are returned via pointers before the other arguments
+			for _, returnType := range methodSignature.ReturnTypes {
+				paramTypes = append(paramTypes, types.NewPointer(returnType.LLVM()))
+			}
 		}
 
-		paramTypes := []types.Type{types.NewPointer(types.I8)}
+		paramTypes = append(paramTypes, types.NewPointer(types.I8))
 		for _, argType := range methodSignature.ArgumentTypes {
 			paramTypes = append(paramTypes, argType.LLVM())
 		}
@@ -68,6 +77,11 @@ func (i Interface) LLVM() types.Type {
 	)
 }
 
+// Zero sets the interface to nil, an interface without a value and type
+func (i Interface) Zero(block *ir.Block, alloca llvmValue.Value) {
+	block.NewStore(constant.NewZeroInitializer(i.LLVM()), alloca)
+}
+
 func (Interface) Size() int64 {
 	return 64/8 * 3
 }
@@ -64,8 +64,6 @@ type Struct struct {
 	Members       map[string]Type
 	MemberIndexes map[string]int
 
-	IsHeapAllocated bool
-
 	SourceName string
 	Type       types.Type
 }
@@ -117,6 +115,7 @@ type Function struct {
 	backingType
 
 	// LlvmFunction llvmValue.Named
+	// The pointer type of the LLVM function, with the signature of the function
 	FuncType types.Type
 
 	// The return type of the LLVM function (is always 1)
@@ -132,14 +131,29 @@ type Function struct {
 	JumpFunction *ir.Func
 }
 
+// LLVM returns the type of function values, a pointer to the function and a
+// context. The context is nil for plain functions, and points to the receiver
+// of method values.
 func (f Function) LLVM() types.Type {
-	return f.FuncType
+	return FuncValue()
 }
 
 func (f Function) Name() string {
 	return "func"
 }
 
+func (f Function) Zero(block *ir.Block, alloca llvmValue.Value) {
+	block.NewStore(constant.NewZeroInitializer(f.LLVM()), alloca)
+}
+
+// FuncValue is the LLVM type of function values
+func FuncValue() *types.StructType {
+	return types.NewStruct(
+		types.I8Ptr, // Function
+		types.I8Ptr, // Context
+	)
+}
+
 type BoolType struct {
 	backingType
 }
@@ -185,6 +199,12 @@ type Int struct {
 	Signed   bool
 }
 
+// Named returns a new type with the same representation as i, such as
+// Duration in "type Duration int64". The new type has its own methods.
+func (i Int) Named(name string) *Int {
+	return &Int{Type: i.Type, TypeName: name, TypeSize: i.TypeSize, Signed: i.Signed}
+}
+
 func (i Int) LLVM() types.Type {
 	return i.Type
 }
@@ -215,6 +235,49 @@ func (i Int) IsSigned() bool {
 	return i.Signed
 }
 
+type Float struct {
+	backingType
+
+	Type     *types.FloatType
+	TypeName string
+	TypeSize int64
+}
+
+// Named returns a new type with the same representation as f, that has its
+// own methods
+func (f Float) Named(name string) *Float {
+	return &Float{Type: f.Type, TypeName: name, TypeSize: f.TypeSize}
+}
+
+func (f Float) LLVM() types.Type {
+	return f.Type
+}
+
+func (f Float) Name() string {
+	return f.TypeName
+}
+
+func (f Float) Size() int64 {
+	return f.TypeSize
+}
+
+func (f Float) Zero(block *ir.Block, alloca llvmValue.Value) {
+	block.NewStore(f.Constant(0), alloca)
+}
+
+// Constant returns x as a constant of type f. x is rounded to the nearest
+// float32 if f is a float32, as LLVM requires that the constant is exact.
+func (f Float) Constant(x float64) *constant.Float {
+	if f.TypeSize == 4 {
+		x = float64(float32(x))
+	}
+	return constant.NewFloat(f.Type, x)
+}
+
+func (Float) IsSigned() bool {
+	return true
+}
+
 type StringType struct {
 	backingType
 	Type types.Type
@@ -255,7 +318,7 @@ func (a Array) LLVM() types.Type {
 }
 
 func (a Array) Name() string {
-	return "array"
+	return fmt.Sprintf("array(%d, %s)", a.Len, a.Type.Name())
 }
 
 func (a Array) Zero(block *ir.Block, alloca llvmValue.Value) {
@@ -275,12 +338,18 @@ func (s Slice) LLVM() types.Type {
 	return s.LlvmType
 }
 
-func (Slice) Name() string {
-	return "slice"
+func (s Slice) Name() string {
+	return fmt.Sprintf("slice(%s)", s.Type.Name())
 }
 
 func (Slice) Size() int64 {
-	return 3*4 + 8 // 3 int32s and a pointer
+	return 3*4 + 4 + 8 // 3 int32s, padding and a pointer
+}
+
+// Zero sets the slice to an empty slice without a backing array, such as
+// when a slice is a member of a struct. Append allocates the backing array.
+func (s Slice) Zero(block *ir.Block, alloca llvmValue.Value) {
+	block.NewStore(constant.NewZeroInitializer(s.LlvmType), alloca)
 }
 
 func (s Slice) SliceZero(block *ir.Block, mallocFunc llvmValue.Named, initCap int, emptySlice llvmValue.Value) {
@@ -303,7 +372,11 @@ func (s Slice) SliceZero(block *ir.Block, mallocFunc llvmValue.Named, initCap in
 	block.NewStore(constant.NewInt(types.I32, int64(initCap)), cap)
 	block.NewStore(constant.NewInt(types.I32, 0), offset)
 
-	mallocatedSpaceRaw := block.NewCall(mallocFunc, constant.NewInt(types.I64, int64(initCap)*s.Type.Size()))
+	// The size of the items is calculated by LLVM, as it includes padding
+	itemPtr := constant.NewGetElementPtr(s.Type.LLVM(), constant.NewNull(types.NewPointer(s.Type.LLVM())), constant.NewInt(types.I32, 1))
+	itemSize := constant.NewPtrToInt(itemPtr, types.I64)
+	size := constant.NewMul(constant.NewInt(types.I64, int64(initCap)), itemSize)
+	mallocatedSpaceRaw := block.NewCall(mallocFunc, size)
 	mallocatedSpaceRaw.SetName(name.Var("slicezero"))
 	bitcasted := block.NewBitCast(mallocatedSpaceRaw, types.NewPointer(s.Type.LLVM()))
 	block.NewStore(bitcasted, backingArray)
@@ -330,6 +403,10 @@ func (p Pointer) Size() int64 {
 	return 8
 }
 
+func (p Pointer) Zero(block *ir.Block, alloca llvmValue.Value) {
+	block.NewStore(constant.NewNull(p.LLVM().(*types.PointerType)), alloca)
+}
+
 // MultiValue is used when returning multiple values from a function
 type MultiValue struct {
 	backingType
@@ -355,3 +432,17 @@ func (m UntypedConstantNumber) Name() string {
 func (m UntypedConstantNumber) LLVM() types.Type {
 	panic("UntypedConstantNumber has no LLVM type")
 }
+
+// UntypedNil is the type of nil, it gets the type of the value it's used as.
+// Such as in "var err error = nil", or "if ptr == nil".
+type UntypedNil struct {
+	backingType
+}
+
+func (UntypedNil) Name() string {
+	return "UntypedNil"
+}
+
+func (UntypedNil) LLVM() types.Type {
+	panic("UntypedNil has no LLVM type")
+}
@@ -1,6 +1,8 @@
 package value
 
 import (
+	"math/big"
+
 	"github.com/llir/llvm/ir/constant"
 	llvmValue "github.com/llir/llvm/ir/value"
 
@@ -26,17 +28,59 @@ type Value struct {
 func UntypedConstAs(val Value, context Value) Value {
 	switch val.Type.(type) {
 	case *types.UntypedConstantNumber:
-		if contextInt, ok := context.Type.(*types.Int); ok {
-			return Value{
-				Type: contextInt,
-				Value: &constant.Int{
-					Typ: contextInt.Type,
-					X:   val.Value.(*constant.Int).X,
-				},
-			}
+		if res, ok := ConstantAs(val, context.Type); ok {
+			return res
 		}
 		panic("unexpected type in UntypedConstAs")
 	default:
 		panic("unexpected type in UntypedConstAs")
 	}
 }
+
+// UntypedDefault converts an untyped constant to its default type, float64
+// for floats and int for integers. Other values are returned as they are.
+func UntypedDefault(val Value) Value {
+	if _, ok := val.Type.(*types.UntypedConstantNumber); !ok {
+		return val
+	}
+	if _, isFloat := val.Value.(*constant.Float); isFloat {
+		return UntypedConstAs(val, Value{Type: types.F64})
+	}
+	return UntypedConstAs(val, Value{Type: types.I64})
+}
+
+// ConstantAs converts the constant number val to the numeric type t. Integer
+// constants can be used as floats, and float constants can be used as
+// integers if they don't have a fraction. Returns false if val is not a
+// constant, or if it can't be represented as t.
+func ConstantAs(val Value, t types.Type) (Value, bool) {
+	if val.IsVariable {
+		return Value{}, false
+	}
+
+	switch cnst := val.Value.(type) {
+	case *constant.Int:
+		switch tt := t.(type) {
+		case *types.Int:
+			return Value{Type: tt, Value: &constant.Int{Typ: tt.Type, X: cnst.X}}, true
+		case *types.Float:
+			f, _ := new(big.Float).SetInt(cnst.X).Float64()
+			return Value{Type: tt, Value: tt.Constant(f)}, true
+		}
+
+	case *constant.Float:
+		switch tt := t.(type) {
+		case *types.Float:
+			f, _ := cnst.X.Float64()
+			return Value{Type: tt, Value: tt.Constant(f)}, true
+		case *types.Int:
+			if !cnst.X.IsInt() {
+				return Value{}, false
+			}
+			i, _ := cnst.X.Int(nil)
+			return Value{Type: tt, Value: &constant.Int{Typ: tt.Type, X: i}}, true
+		}
+	}
+
+	return Value{}, false
+}
@@ -2,5 +2,8 @@ package lexer
 
 var escapeSequences = map[string]string{
 	`\"`: "\"",
+	`\\`: "\\",
 	`\n`: "\n",
+	`\r`: "\r",
+	`\t`: "\t",
 }
@@ -13,6 +13,7 @@ var keywords = map[string]struct{}{
 	"for":         {},
 	"break":       {},
 	"continue":    {},
+	"goto":        {},
 	"import":      {},
 	"true":        {},
 	"false":       {},
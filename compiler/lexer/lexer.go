@@ -15,6 +15,7 @@ const (
 	OPERATOR
 	EOF
 	EOL
+	DIRECTIVE
 )
 
 type Item struct {
@@ -40,6 +41,8 @@ func (i Item) String() string {
 		t = "EOF"
 	case EOL:
 		t = "EOL"
+	case DIRECTIVE:
+		t = "DIRECTIVE"
 	}
 
 	return fmt.Sprintf("{Type:%s, Val:%s, Line:%d}", t, i.Val, i.Line)
@@ -99,123 +102,265 @@ var operations = map[string]struct{}{
 	"..": {}, // is not a real operation. Is there so that ... can be found.
 }
 
-func Lex(inputFullSource string) []Item {
+// Keywords and operators that ends a statement if they are the last token on
+// a line
+var (
+	semicolonKeywords = map[string]struct{}{
+		"break":       {},
+		"continue":    {},
+		"fallthrough": {},
+		"return":      {},
+		"true":        {},
+		"false":       {},
+	}
+	semicolonOperators = map[string]struct{}{
+		"++": {},
+		"--": {},
+		")":  {},
+		"]":  {},
+		"}":  {},
+	}
+)
+
+// insertSemicolon reports whether a newline after the lexed items in res ends
+// a statement. The parser sees these newlines as EOL items, other newlines are
+// ignored, so that expressions and lists can span multiple lines, such as
+// "a +\n b" or "f(\n a,\n b,\n)".
+//
+// https://golang.org/ref/spec#Semicolons
+func insertSemicolon(res []Item) bool {
+	if len(res) == 0 {
+		return false
+	}
+
+	last := res[len(res)-1]
+	switch last.Type {
+	case IDENTIFIER, NUMBER, STRING, DIRECTIVE:
+		return true
+	case KEYWORD:
+		_, ok := semicolonKeywords[last.Val]
+		return ok
+	case OPERATOR:
+		_, ok := semicolonOperators[last.Val]
+		return ok
+	}
+
+	return false
+}
+
+func Lex(input string) []Item {
 	var res []Item
 
-	for line, input := range strings.Split(inputFullSource, "\n") {
-		if line > 0 {
-			res = append(res, Item{Type: EOL, Line: line})
+	// Lines starts at 1
+	line := 1
+	lineStart := 0
+
+	i := 0
+
+	for i < len(input) {
+
+		if input[i] == '\n' {
+			if insertSemicolon(res) {
+				res = append(res, Item{Type: EOL, Line: line})
+			}
+			i++
+			line++
+			lineStart = i
+			continue
 		}
 
-		// Lines starts at 1
-		line = line + 1
+		// Comment, until end of line or end of file
+		if strings.HasPrefix(input[i:], "//") {
+			end := strings.IndexByte(input[i:], '\n')
+			if end == -1 {
+				end = len(input) - i
+			}
 
-		i := 0
+			// Directives such as "//export Name" must start at the beginning of the line
+			if i == lineStart && strings.HasPrefix(input[i:], "//export ") {
+				res = append(res, Item{Type: DIRECTIVE, Val: strings.TrimSpace(input[i+2 : i+end]), Line: line})
+			}
 
-		for i < len(input) {
+			i += end
+			continue
+		}
 
-			// Comment, until end of line or end of file
-			if input[i] == '/' && input[i+1] == '/' {
-				break
+		// Block comment, until the next */. A comment that contains newlines
+		// acts like a newline.
+		if strings.HasPrefix(input[i:], "/*") {
+			end := strings.Index(input[i+2:], "*/")
+			if end == -1 {
+				panic(fmt.Sprintf("Lexer: comment not terminated on line %d", line))
 			}
 
-			if _, ok := operations[string(input[i])]; ok {
+			if newLines := strings.Count(input[i+2:i+2+end], "\n"); newLines > 0 {
+				if insertSemicolon(res) {
+					res = append(res, Item{Type: EOL, Line: line})
+				}
+				line += newLines
+			}
 
-				operator := string(input[i])
+			i += 2 + end + 2
+			continue
+		}
 
-				// Parse till end of the operator
-				// Can be up to 3 characters long
-				for {
-					if len(input) == i+1 {
-						break
-					}
+		if _, ok := operations[string(input[i])]; ok {
 
-					checkIfOperator := operator + string(input[i+1])
-					if _, ok := operations[checkIfOperator]; ok {
-						operator = checkIfOperator
-						i++
+			operator := string(input[i])
+
+			// Parse till end of the operator
+			// Can be up to 3 characters long
+			for {
+				if len(input) == i+1 {
+					break
+				}
+
+				checkIfOperator := operator + string(input[i+1])
+				if _, ok := operations[checkIfOperator]; ok {
+					operator = checkIfOperator
+					i++
+					continue
+				} else {
+					break
+				}
+			}
+
+			res = append(res, Item{Type: OPERATOR, Val: operator, Line: line})
+			i++
+			continue
+		}
+
+		if input[i] == '"' {
+			// String continues until next unescaped "
+			var str string
+
+			i++
+
+			for i < len(input) && input[i] != '\n' {
+				if input[i] == '"' {
+					break
+				}
+
+				// parse escape sequences
+				if input[i] == '\\' && i+1 < len(input) {
+					if esc, ok := escapeSequences[string(input[i])+string(input[i+1])]; ok {
+						str += esc
+						i += 2
 						continue
-					} else {
-						break
 					}
 				}
 
-				res = append(res, Item{Type: OPERATOR, Val: operator, Line: line})
+				str += input[i : i+1]
 				i++
-				continue
 			}
 
-			if input[i] == '"' {
-				// String continues until next unescaped "
-				var str string
+			if i == len(input) || input[i] != '"' {
+				panic(fmt.Sprintf("Lexer: string literal not terminated on line %d", line))
+			}
 
-				i++
+			i++
+			res = append(res, Item{Type: STRING, Val: str, Line: line})
+			continue
+		}
 
-				for i < len(input) {
-					if input[i] == '"' {
-						break
-					}
+		// Raw string, continues until the next `. Can span multiple lines, and
+		// does not have any escape sequences. Carriage returns are removed.
+		if input[i] == '`' {
+			end := strings.IndexByte(input[i+1:], '`')
+			if end == -1 {
+				panic(fmt.Sprintf("Lexer: raw string literal not terminated on line %d", line))
+			}
 
-					// parse escape sequences
-					if input[i] == '\\' {
-						if esc, ok := escapeSequences[string(input[i])+string(input[i+1])]; ok {
-							str += esc
-							i += 2
-							continue
-						}
-					}
+			str := input[i+1 : i+1+end]
+			res = append(res, Item{Type: STRING, Val: strings.ReplaceAll(str, "\r", ""), Line: line})
 
-					str += string(input[i])
-					i++
-				}
+			line += strings.Count(str, "\n")
 
+			i += 1 + end + 1
+			continue
+		}
+
+		// NAME
+		// Consists of a-z, parse until the last allowed char
+		if (input[i] >= 'a' && input[i] <= 'z') || (input[i] >= 'A' && input[i] <= 'Z') || input[i] == '_' {
+			name := ""
+
+			for i < len(input) && ((input[i] >= 'a' && input[i] <= 'z') ||
+				(input[i] >= 'A' && input[i] <= 'Z') ||
+				(input[i] >= '0' && input[i] <= '9') ||
+				input[i] == '_') {
+				name += string(input[i])
 				i++
-				res = append(res, Item{Type: STRING, Val: str, Line: line})
-				continue
 			}
 
-			// NAME
-			// Consists of a-z, parse until the last allowed char
-			if (input[i] >= 'a' && input[i] <= 'z') || (input[i] >= 'A' && input[i] <= 'Z') || input[i] == '_' {
-				name := ""
+			if _, ok := keywords[name]; ok {
+				res = append(res, Item{Type: KEYWORD, Val: name, Line: line})
+			} else {
+				res = append(res, Item{Type: IDENTIFIER, Val: name, Line: line})
+			}
 
-				for i < len(input) && ((input[i] >= 'a' && input[i] <= 'z') ||
-					(input[i] >= 'A' && input[i] <= 'Z') ||
-					(input[i] >= '0' && input[i] <= '9') ||
-					input[i] == '_') {
-					name += string(input[i])
-					i++
-				}
+			continue
+		}
 
-				if _, ok := keywords[name]; ok {
-					res = append(res, Item{Type: KEYWORD, Val: name, Line: line})
-				} else {
-					res = append(res, Item{Type: IDENTIFIER, Val: name, Line: line})
+		// NUMBER
+		// 0-9, floating point numbers also have a fraction ("1.5") or an
+		// exponent ("1e9", "2.5e-3"). Integers can have a base prefix ("0x1f",
+		// "0o17", "0b101", or the legacy octal "017"). Digits can be separated
+		// with underscores ("1_000_000"). The value is parsed by the parser.
+		if input[i] >= '0' && input[i] <= '9' {
+			isDigit := func(i int) bool {
+				return i < len(input) && ((input[i] >= '0' && input[i] <= '9') || input[i] == '_')
+			}
+
+			start := i
+
+			if input[i] == '0' && i+1 < len(input) && strings.IndexByte("xXoObB", input[i+1]) != -1 {
+				i += 2
+				for i < len(input) && (isDigit(i) ||
+					(input[i] >= 'a' && input[i] <= 'f') ||
+					(input[i] >= 'A' && input[i] <= 'F')) {
+					i++
 				}
 
+				res = append(res, Item{Type: NUMBER, Val: input[start:i], Line: line})
 				continue
 			}
 
-			// NUMBER
-			// 0-9
-			if input[i] >= '0' && input[i] <= '9' {
-				val := ""
-				for i < len(input) && input[i] >= '0' && input[i] <= '9' {
-					val += string(input[i])
+			for isDigit(i) {
+				i++
+			}
+
+			if i < len(input) && input[i] == '.' && isDigit(i+1) {
+				i++
+				for isDigit(i) {
 					i++
 				}
-				res = append(res, Item{Type: NUMBER, Val: val, Line: line})
-				continue
 			}
 
-			// Whitespace (ignore)
-			if len(strings.TrimSpace(string(input[i]))) == 0 {
-				i++
-				continue
+			if i < len(input) && (input[i] == 'e' || input[i] == 'E') {
+				exp := i + 1
+				if exp < len(input) && (input[exp] == '+' || input[exp] == '-') {
+					exp++
+				}
+				if isDigit(exp) {
+					i = exp
+					for isDigit(i) {
+						i++
+					}
+				}
 			}
 
-			panic("Unexpected char in Lexer: " + string(input[i]))
+			res = append(res, Item{Type: NUMBER, Val: input[start:i], Line: line})
+			continue
+		}
+
+		// Whitespace (ignore)
+		if len(strings.TrimSpace(string(input[i]))) == 0 {
+			i++
+			continue
 		}
+
+		panic("Unexpected char in Lexer: " + string(input[i]))
 	}
 
 	res = append(res, Item{Type: EOL}, Item{Type: EOF})
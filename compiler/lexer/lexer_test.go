@@ -56,6 +56,43 @@ func TestLexerSimpleAddNumber(t *testing.T) {
 	assert.Equal(t, expected, r)
 }
 
+func TestLexerFloat(t *testing.T) {
+	r := Lex("1.5 + 2e10 * 3.25E-3")
+
+	expected := []Item{
+		{Type: NUMBER, Val: "1.5", Line: 1},
+		{Type: OPERATOR, Val: "+", Line: 1},
+		{Type: NUMBER, Val: "2e10", Line: 1},
+		{Type: OPERATOR, Val: "*", Line: 1},
+		{Type: NUMBER, Val: "3.25E-3", Line: 1},
+
+		{Type: EOL},
+		{Type: EOF},
+	}
+
+	assert.Equal(t, expected, r)
+}
+
+func TestLexerNumberBases(t *testing.T) {
+	r := Lex("0x1F 0Xe 0o17 017 0b1010 1_000 0x_FF_FF 1_0.5")
+
+	expected := []Item{
+		{Type: NUMBER, Val: "0x1F", Line: 1},
+		{Type: NUMBER, Val: "0Xe", Line: 1},
+		{Type: NUMBER, Val: "0o17", Line: 1},
+		{Type: NUMBER, Val: "017", Line: 1},
+		{Type: NUMBER, Val: "0b1010", Line: 1},
+		{Type: NUMBER, Val: "1_000", Line: 1},
+		{Type: NUMBER, Val: "0x_FF_FF", Line: 1},
+		{Type: NUMBER, Val: "1_0.5", Line: 1},
+
+		{Type: EOL},
+		{Type: EOF},
+	}
+
+	assert.Equal(t, expected, r)
+}
+
 func TestLexerSimpleCall(t *testing.T) {
 	r := Lex("foo(bar)")
 
@@ -145,3 +182,77 @@ func TestLexerSimpleCallWithStringNum(t *testing.T) {
 
 	assert.Equal(t, expected, r)
 }
+
+func TestExportDirective(t *testing.T) {
+	r := Lex("//export Add\nfunc // comment")
+
+	expected := []Item{
+		{Type: DIRECTIVE, Val: "export Add", Line: 1},
+		{Type: EOL, Val: "", Line: 1},
+		{Type: KEYWORD, Val: "func", Line: 2},
+		{Type: EOL},
+		{Type: EOF},
+	}
+
+	assert.Equal(t, expected, r)
+}
+
+func TestRawString(t *testing.T) {
+	r := Lex("a = `b\\n\"\nc`\nd")
+
+	expected := []Item{
+		{Type: IDENTIFIER, Val: "a", Line: 1},
+		{Type: OPERATOR, Val: "=", Line: 1},
+		{Type: STRING, Val: "b\\n\"\nc", Line: 1},
+		{Type: EOL, Val: "", Line: 2},
+		{Type: IDENTIFIER, Val: "d", Line: 3},
+		{Type: EOL},
+		{Type: EOF},
+	}
+
+	assert.Equal(t, expected, r)
+}
+
+func TestBlockComment(t *testing.T) {
+	r := Lex("a /* b */ c /* d\ne */\nf")
+
+	expected := []Item{
+		{Type: IDENTIFIER, Val: "a", Line: 1},
+		{Type: IDENTIFIER, Val: "c", Line: 1},
+		{Type: EOL, Val: "", Line: 1},
+		{Type: IDENTIFIER, Val: "f", Line: 3},
+		{Type: EOL},
+		{Type: EOF},
+	}
+
+	assert.Equal(t, expected, r)
+}
+
+func TestSemicolonInsertion(t *testing.T) {
+	r := Lex("a +\nb\nf(\nc,\n)\n\nx.\ny++\nreturn\n}")
+
+	expected := []Item{
+		{Type: IDENTIFIER, Val: "a", Line: 1},
+		{Type: OPERATOR, Val: "+", Line: 1},
+		{Type: IDENTIFIER, Val: "b", Line: 2},
+		{Type: EOL, Val: "", Line: 2},
+		{Type: IDENTIFIER, Val: "f", Line: 3},
+		{Type: OPERATOR, Val: "(", Line: 3},
+		{Type: IDENTIFIER, Val: "c", Line: 4},
+		{Type: OPERATOR, Val: ",", Line: 4},
+		{Type: OPERATOR, Val: ")", Line: 5},
+		{Type: EOL, Val: "", Line: 5},
+		{Type: IDENTIFIER, Val: "x", Line: 7},
+		{Type: OPERATOR, Val: ".", Line: 7},
+		{Type: IDENTIFIER, Val: "y", Line: 8},
+		{Type: OPERATOR, Val: "++", Line: 8},
+		{Type: EOL, Val: "", Line: 8},
+		{Type: KEYWORD, Val: "return", Line: 9},
+		{Type: EOL, Val: "", Line: 9},
+		{Type: OPERATOR, Val: "}", Line: 10},
+		{Type: EOL},
+		{Type: EOF},
+	}
+
+	assert.Equal(t, expected, r)
+}
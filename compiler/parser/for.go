@@ -30,37 +30,79 @@ func (p *parser) parseFor() *ForNode {
 		{Type: lexer.OPERATOR, Val: "{"}, // range type for
 	})
 
-	if len(beforeLoop) != 1 {
+	// Loop forever, "for { ... }"
+	if len(beforeLoop) == 0 && reachedItem.Val == "{" {
+		res.IsThreeTypeFor = true
+		res.Condition = loopForever()
+		p.i++
+		res.Block = p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "}"})
+		return res
+	}
+
+	// The init statement is optional, such as in "for ; i < n; i++ { ... }"
+	if len(beforeLoop) > 1 || (len(beforeLoop) == 0 && reachedItem.Val != ";") {
 		panic("Expected only one beforeLoop in for loop")
 	}
 
+	// Loop while the condition is true, "for a < b { ... }"
+	if reachedItem.Val == "{" && !isRangeBeforeLoop(beforeLoop[0]) {
+		res.IsThreeTypeFor = true
+		if conditionNode, ok := beforeLoop[0].(*OperatorNode); ok {
+			res.Condition = conditionNode
+		} else {
+			// Add implicit == true
+			res.Condition = &OperatorNode{
+				Left:     beforeLoop[0],
+				Right:    &ConstantNode{Type: BOOL, Value: 1},
+				Operator: OP_EQ,
+			}
+		}
+		p.i++
+		res.Block = p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "}"})
+		return res
+	}
+
 	isThreeTypeFor := false
 	if reachedItem.Val == ";" {
 		isThreeTypeFor = true
 		res.IsThreeTypeFor = true
 	}
 
-	res.BeforeLoop = beforeLoop[0]
+	if len(beforeLoop) == 1 {
+		res.BeforeLoop = beforeLoop[0]
+	}
 
+	// The condition and the post statement are also optional, such as in
+	// "for i := 0; ; i++ { ... }" and "for i := 0; i < n; { ... }"
 	if isThreeTypeFor {
 		p.i++
 		loopCondition := p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: ";"})
-		if len(loopCondition) != 1 {
+		switch len(loopCondition) {
+		case 0:
+			res.Condition = loopForever()
+		case 1:
+			if conditionNode, ok := loopCondition[0].(*OperatorNode); ok {
+				res.Condition = conditionNode
+			} else {
+				// Add implicit == true
+				res.Condition = &OperatorNode{
+					Left:     loopCondition[0],
+					Right:    &ConstantNode{Type: BOOL, Value: 1},
+					Operator: OP_EQ,
+				}
+			}
+		default:
 			panic("Expected only one condition in for loop")
 		}
 
-		if conditionNode, ok := loopCondition[0].(*OperatorNode); ok {
-			res.Condition = conditionNode
-		} else {
-			panic(fmt.Sprintf("Expected OperatorNode in for loop. Got: %T: %+v", loopCondition[0], loopCondition[0]))
-		}
-
 		p.i++
 		afterIteration := p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "{"})
-		if len(afterIteration) != 1 {
+		if len(afterIteration) > 1 {
 			panic("Expected only one afterIteration in for loop")
 		}
-		res.AfterIteration = afterIteration[0]
+		if len(afterIteration) == 1 {
+			res.AfterIteration = afterIteration[0]
+		}
 	}
 
 	p.i++
@@ -68,3 +110,26 @@ func (p *parser) parseFor() *ForNode {
 
 	return res
 }
+
+// isRangeBeforeLoop reports whether the node before the block of a for loop is
+// a range clause, such as "k, v := range a" or "range a"
+func isRangeBeforeLoop(n Node) bool {
+	if _, ok := n.(*RangeNode); ok {
+		return true
+	}
+	if alloc, ok := n.(*AllocNode); ok && len(alloc.Val) == 1 {
+		_, ok := alloc.Val[0].(*RangeNode)
+		return ok
+	}
+	return false
+}
+
+// loopForever returns the condition of a loop without a condition, such as
+// "for { ... }"
+func loopForever() *OperatorNode {
+	return &OperatorNode{
+		Left:     &ConstantNode{Type: BOOL, Value: 1},
+		Right:    &ConstantNode{Type: BOOL, Value: 1},
+		Operator: OP_EQ,
+	}
+}
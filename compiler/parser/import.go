@@ -28,7 +28,7 @@ func (p *parser) parseImport() *ImportNode {
 		if checkIfEndParen.Type == lexer.OPERATOR && checkIfEndParen.Val == ")" {
 			break
 		}
-		if checkIfEndParen.Type == lexer.EOL {
+		if isEndOfStatement(checkIfEndParen) {
 			p.i++
 			continue
 		}
@@ -61,11 +61,14 @@ func sortInfix(outer *OperatorNode) *OperatorNode {
 			res := &OperatorNode{
 				Operator: left.Operator,
 				Left:     left.Left,
-				Right: &OperatorNode{
+				// The right side is sorted again, as the outer operator can have
+				// a higher priority than operators in it as well, such as in
+				// (a == b + c) * d
+				Right: sortInfix(&OperatorNode{
 					Operator: outer.Operator,
 					Left:     left.Right,
 					Right:    outer.Right,
-				},
+				}),
 			}
 			return res
 		}
@@ -57,7 +57,16 @@ func TestMultiAssignVar(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]:                           1,
+		parsed.Instructions[1]:                           3,
+		parsed.Instructions[1].(*DefineFuncNode).Body[0]: 4,
+		parsed.Instructions[1].(*DefineFuncNode).Body[1]: 5,
+		parsed.Instructions[1].(*DefineFuncNode).Body[2]: 6,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 func TestMultiAllocVar(t *testing.T) {
 	input := []lexer.Item{
@@ -98,5 +107,12 @@ func TestMultiAllocVar(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]:                           1,
+		parsed.Instructions[1]:                           3,
+		parsed.Instructions[1].(*DefineFuncNode).Body[0]: 6,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
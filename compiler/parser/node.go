@@ -35,6 +35,10 @@ type PackageNode struct {
 
 	Files []FileNode
 	Name  string
+
+	// Import path of the package, such as "math/bits". Packages are
+	// identified by their path, as different packages can have the same name.
+	Path string
 }
 
 // FileNode is a list of other nodes
@@ -43,6 +47,12 @@ type FileNode struct {
 	baseNode
 
 	Instructions []Node
+
+	// Path of the source file
+	Path string
+
+	// Line numbers of the statements in the file, is used to generate debug information
+	Lines map[Node]int
 }
 
 func (fn FileNode) String() string {
@@ -94,24 +104,29 @@ const (
 )
 
 var opsCharToOp map[string]Operator
-var arithOperators map[Operator]struct{}
+
+// assignOpsCharToOp maps the compound assignment operators, such as "+=",
+// to the operator that they apply
+var assignOpsCharToOp map[string]Operator
 
 func init() {
-	opsCharToOp = make(map[string]Operator)
-	for _, op := range []Operator{
+	arithmetic := []Operator{
 		OP_ADD, OP_SUB, OP_DIV, OP_MUL, OP_REMAINDER,
 		OP_BIT_AND, OP_BIT_OR, OP_BIT_XOR, OP_BIT_CLEAR,
 		OP_LEFT_SHIFT, OP_RIGHT_SHIFT,
+	}
+
+	opsCharToOp = make(map[string]Operator)
+	for _, op := range append(arithmetic,
 		OP_GT, OP_GTEQ, OP_LT, OP_LTEQ, OP_EQ, OP_NEQ,
-	} {
+		OP_LOGICAL_AND, OP_LOGICAL_OR,
+	) {
 		opsCharToOp[string(op)] = op
 	}
 
-	arithOperators = make(map[Operator]struct{})
-	for _, op := range []Operator{
-		OP_ADD, OP_SUB, OP_DIV, OP_MUL,
-	} {
-		arithOperators[op] = struct{}{}
+	assignOpsCharToOp = make(map[string]Operator)
+	for _, op := range arithmetic {
+		assignOpsCharToOp[string(op)+"="] = op
 	}
 }
 
@@ -123,9 +138,10 @@ func (on OperatorNode) String() string {
 type ConstantNode struct {
 	baseNode
 
-	Type     DataType
-	Value    int64
-	ValueStr string
+	Type       DataType
+	Value      int64
+	ValueStr   string
+	ValueFloat float64
 }
 
 type DataType uint8
@@ -134,20 +150,25 @@ const (
 	STRING DataType = iota
 	NUMBER
 	BOOL
+	FLOAT
 )
 
 func (cn ConstantNode) String() string {
 	if len(cn.ValueStr) > 0 {
 		return cn.ValueStr
 	}
+	if cn.Type == FLOAT {
+		return fmt.Sprintf("const(%g)", cn.ValueFloat)
+	}
 	return fmt.Sprintf("const(%d)", cn.Value)
 }
 
 // ConditionNode creates a new if condition
-// False is optional
+// Init and False are optional
 type ConditionNode struct {
 	baseNode
 
+	Init  Node // Statement that is executed before the condition, such as "v, ok := m()"
 	Cond  *OperatorNode
 	True  []Node
 	False []Node
@@ -173,6 +194,9 @@ type DefineFuncNode struct {
 	Arguments    []*NameNode
 	ReturnValues []*NameNode
 	Body         []Node
+
+	// Name that the function is exported as to C, set by a "//export Name" directive
+	Export string
 }
 
 func (dfn DefineFuncNode) String() string {
@@ -233,6 +257,10 @@ type AllocNode struct {
 
 	Escapes bool
 
+	// Is true when a pointer to the variable outlives the function, such as
+	// in "return &a", the variable is then allocated on the heap
+	AddressEscapes bool
+
 	Name []string
 	Val  []Node
 
@@ -266,6 +294,20 @@ func (an AssignNode) String() string {
 	return fmt.Sprintf("assign(%+v) = %v", an.Target, an.Val)
 }
 
+// OperatorAssignNode is a compound assignment, such as "a[i] += 1". The
+// Target is only evaluated once.
+type OperatorAssignNode struct {
+	baseNode
+
+	Target   Node
+	Operator Operator
+	Val      Node
+}
+
+func (n OperatorAssignNode) String() string {
+	return fmt.Sprintf("assign(%+v) %s= %v", n.Target, string(n.Operator), n.Val)
+}
+
 // TypeCastNode tries to cast Val to Type
 type TypeCastNode struct {
 	baseNode
@@ -341,22 +383,48 @@ func (d DeclarePackageNode) String() string {
 	return "DeclarePackageNode(" + d.PackageName + ")"
 }
 
-// BreakNode breaks out of the current for loop
+// BreakNode breaks out of the current for loop or switch, or out of the
+// labeled for loop or switch if Label is set
 type BreakNode struct {
 	baseNode
+	Label string
 }
 
 func (n BreakNode) String() string {
-	return "break"
+	return strings.TrimSpace("break " + n.Label)
 }
 
-// ContinueNode skips the current iteration of the current for loop
+// ContinueNode skips the current iteration of the current for loop, or of the
+// labeled for loop if Label is set
 type ContinueNode struct {
 	baseNode
+	Label string
 }
 
 func (n ContinueNode) String() string {
-	return "continue"
+	return strings.TrimSpace("continue " + n.Label)
+}
+
+// LabelNode is a labeled statement, such as "Outer: for { ... }". Statement
+// is nil if the label is followed by the end of the block.
+type LabelNode struct {
+	baseNode
+	Label     string
+	Statement Node
+}
+
+func (n LabelNode) String() string {
+	return fmt.Sprintf("%s: %v", n.Label, n.Statement)
+}
+
+// GotoNode jumps to the labeled statement in the current function
+type GotoNode struct {
+	baseNode
+	Label string
+}
+
+func (n GotoNode) String() string {
+	return "goto " + n.Label
 }
 
 type GetReferenceNode struct {
@@ -429,6 +497,10 @@ type InitializeStructNode struct {
 	baseNode
 	Type  TypeNode
 	Items map[string]Node
+
+	// Escapes is set by the escape analysis if the struct is referenced, such
+	// as in &T{}, and outlives the function. It's then allocated on the heap.
+	Escapes bool
 }
 
 func (i InitializeStructNode) String() string {
@@ -3,7 +3,9 @@ package parser
 import (
 	"fmt"
 	"log"
+	"path"
 	"strconv"
+	"strings"
 
 	"errors"
 
@@ -23,6 +25,12 @@ type parser struct {
 	// names of packages imported in this file
 	// used to detect the difference in "a.b" where a is a package or a struct
 	packages map[string]struct{}
+
+	// name from an "//export" directive, is used by the next function definition
+	nextFuncExport string
+
+	// line numbers of all parsed statements
+	lines map[Node]int
 }
 
 func Parse(input []lexer.Item, debug bool) *FileNode {
@@ -31,22 +39,32 @@ func Parse(input []lexer.Item, debug bool) *FileNode {
 		input:    input,
 		debug:    debug,
 		packages: map[string]struct{}{},
+		lines:    map[Node]int{},
 		types: map[string]struct{}{
 			"int":     {},
 			"uint":    {},
 			"int8":    {},
 			"uint8":   {},
+			"int16":   {},
+			"uint16":  {},
 			"int32":   {},
 			"uint32":  {},
 			"int64":   {},
 			"uint64":  {},
 			"uintptr": {},
+			"float32": {},
+			"float64": {},
 			"string":  {},
+			"byte":    {},
+			"rune":    {},
 		},
 	}
 
+	instructions := p.parseUntil(lexer.Item{Type: lexer.EOF})
+
 	return &FileNode{
-		Instructions: p.parseUntil(lexer.Item{Type: lexer.EOF}),
+		Instructions: instructions,
+		Lines:        p.lines,
 	}
 }
 
@@ -68,6 +86,13 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 		// return p.parseOne()
 		return nil
 
+	case lexer.DIRECTIVE:
+		// "//export Name", is applied to the next function
+		if fields := strings.Fields(current.Val); len(fields) == 2 && fields[0] == "export" {
+			p.nextFuncExport = fields[1]
+		}
+		return nil
+
 	// IDENTIFIERS are converted to either:
 	// - a CallNode if followed by an opening parenthesis (a function call), or
 	// - a NodeName (variables)
@@ -79,16 +104,24 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 		return
 
 		// NUMBER always returns a ConstantNode
-		// Convert string representation to int64
+		// Convert string representation to int64, or to float64 if the number
+		// has a fraction or an exponent
 	case lexer.NUMBER:
-		val, err := strconv.ParseInt(current.Val, 10, 64)
-		if err != nil {
-			panic(err)
-		}
-
-		res = &ConstantNode{
-			Type:  NUMBER,
-			Value: val,
+		isHex := strings.HasPrefix(current.Val, "0x") || strings.HasPrefix(current.Val, "0X")
+		if !isHex && strings.ContainsAny(current.Val, ".eE") {
+			val, err := strconv.ParseFloat(current.Val, 64)
+			if err != nil {
+				panic(err)
+			}
+			res = &ConstantNode{
+				Type:       FLOAT,
+				ValueFloat: val,
+			}
+		} else {
+			res = &ConstantNode{
+				Type:  NUMBER,
+				Value: parseInt(current.Val),
+			}
 		}
 		if withAheadParse {
 			res = p.aheadParse(res)
@@ -107,6 +140,11 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 		return
 
 	case lexer.OPERATOR:
+		// Explicit semicolons separates statements, the same as newlines
+		if current.Val == ";" {
+			return nil
+		}
+
 		if current.Val == "&" {
 			p.i++
 			res = &GetReferenceNode{Item: p.parseOne(true)}
@@ -126,13 +164,23 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 
 		if current.Val == "!" {
 			p.i++
-			res = &NegateNode{Item: p.parseOne(false)}
+			// Selectors, calls and indexes belongs to the negated value, such as
+			// in "!a.b()", but operators does not
+			res = &NegateNode{Item: p.parseOneWithOptions(false, false, true)}
+			if withAheadParse {
+				res = p.aheadParse(res)
+			}
 			return
 		}
 
 		if current.Val == "-" {
 			p.i++
-			res = p.aheadParse(&SubNode{Item: p.parseOne(true)})
+			// The minus belongs to the value, and not to the operators that
+			// follows it, such as in "-a - 1"
+			res = &SubNode{Item: p.parseOneWithOptions(false, false, true)}
+			if withAheadParse {
+				res = p.aheadParse(res)
+			}
 			return
 		}
 
@@ -152,21 +200,36 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 				p.i++
 
 				next = p.lookAhead(0)
+
+				// Conversion to a slice type, such as []byte("foo")
+				if next.Type == lexer.OPERATOR && next.Val == "(" {
+					p.i++
+					val := p.parseExpressionsUntil(lexer.Item{Type: lexer.OPERATOR, Val: ")"})
+					if len(val) != 1 {
+						panic("type conversion must take only one argument")
+					}
+					res = &TypeCastNode{
+						Type: &SliceTypeNode{ItemType: sliceItemType},
+						Val:  val[0],
+					}
+					if withAheadParse {
+						res = p.aheadParse(res)
+					}
+					return
+				}
+
+				// A slice type used as a value, such as in make([]byte, 10)
 				if next.Type != lexer.OPERATOR || next.Val != "{" {
-					log.Printf("%+v", next)
-					panic("expected { after type in slice init")
+					p.i--
+					res = &SliceTypeNode{ItemType: sliceItemType}
+					return
 				}
 
 				p.i++
 
-				prevInAlloc := p.inAllocRightHand
-				p.inAllocRightHand = false
-				items := p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "}"})
-				p.inAllocRightHand = prevInAlloc
-
 				res = &InitializeSliceNode{
 					Type:  sliceItemType,
-					Items: items,
+					Items: p.parseCompositeItems(sliceItemType),
 				}
 				if withAheadParse {
 					res = p.aheadParse(res)
@@ -181,10 +244,7 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 			if arraySize.Type != lexer.NUMBER {
 				panic("expected number in array size")
 			}
-			size, err := strconv.Atoi(arraySize.Val)
-			if err != nil {
-				panic("expected number in array size")
-			}
+			size := int(parseInt(arraySize.Val))
 
 			p.i++
 			p.expect(p.lookAhead(0), lexer.Item{
@@ -206,16 +266,11 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 
 			p.i++
 
-			prevInAlloc := p.inAllocRightHand
-			p.inAllocRightHand = false
-			items := p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "}"})
-			p.inAllocRightHand = prevInAlloc
-
 			// Array init
 			res = &InitializeArrayNode{
 				Type:  arrayItemType,
 				Size:  size,
-				Items: items,
+				Items: p.parseCompositeItems(arrayItemType),
 			}
 			if withAheadParse {
 				res = p.aheadParse(res)
@@ -225,27 +280,56 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 
 		if current.Val == "(" {
 			p.i++
-			i := p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: ")"})
+			i := p.parseExpressionsUntil(lexer.Item{Type: lexer.OPERATOR, Val: ")"})
 			if len(i) != 1 {
 				panic("Expected exactly one item in GroupNode '('")
 			}
-			return p.aheadParseWithOptions(&GroupNode{Item: i[0]}, true, false)
+			return p.aheadParseWithOptions(&GroupNode{Item: i[0]}, withArithAhead, false)
 		}
 
 	case lexer.KEYWORD:
 
+		// Struct literals of anonymous struct types, such as struct{ a int }{a: 1}
+		if current.Val == "struct" {
+			structType, err := p.parseOneType()
+			if err != nil {
+				panic(err)
+			}
+			p.i++
+			p.expect(p.lookAhead(0), lexer.Item{Type: lexer.OPERATOR, Val: "{"})
+			res = p.parseStructLiteral(structType)
+			if withAheadParse {
+				res = p.aheadParseWithOptions(res, withArithAhead, withIdentifierAhead)
+			}
+			return
+		}
+
 		// "if" gets converted to a ConditionNode
 		// the keyword "if" is followed by
+		// - optional: an init statement and a semicolon
 		// - a condition
 		// - an opening curly bracket ({)
 		// - a body
 		// - a closing bracket (})
 		if current.Val == "if" {
 
-			getCondition := func() *OperatorNode {
+			getCondition := func() (Node, *OperatorNode) {
 				p.i++
 
-				condNodes := p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "{"})
+				var init Node
+				condNodes, reached := p.parseUntilEither([]lexer.Item{
+					{Type: lexer.OPERATOR, Val: ";"},
+					{Type: lexer.OPERATOR, Val: "{"},
+				})
+				if reached.Val == ";" {
+					if len(condNodes) != 1 {
+						panic("could not parse if-init")
+					}
+					init = condNodes[0]
+
+					p.i++
+					condNodes = p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "{"})
+				}
 				if len(condNodes) != 1 {
 					panic("could not parse if-condition")
 				}
@@ -253,11 +337,11 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 				p.i++
 
 				if cond, ok := condNodes[0].(*OperatorNode); ok {
-					return cond
+					return init, cond
 				}
 
 				// Add implicit == true
-				return &OperatorNode{
+				return init, &OperatorNode{
 					Left: condNodes[0],
 					Right: &ConstantNode{
 						Type:  BOOL,
@@ -268,10 +352,9 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 
 			}
 
-			outerConditionNode := &ConditionNode{
-				Cond: getCondition(),
-				True: p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "}"}),
-			}
+			outerConditionNode := &ConditionNode{}
+			outerConditionNode.Init, outerConditionNode.Cond = getCondition()
+			outerConditionNode.True = p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "}"})
 
 			lastConditionNode := outerConditionNode
 
@@ -289,10 +372,9 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 				checkIfElseIf := p.lookAhead(0)
 				if checkIfElseIf.Type == lexer.KEYWORD && checkIfElseIf.Val == "if" {
 
-					newCondNode := &ConditionNode{
-						Cond: getCondition(),
-						True: p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "}"}),
-					}
+					newCondNode := &ConditionNode{}
+					newCondNode.Init, newCondNode.Cond = getCondition()
+					newCondNode.True = p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: "}"})
 
 					lastConditionNode.False = []Node{newCondNode}
 					lastConditionNode = newCondNode
@@ -328,7 +410,8 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 		// value func:  func (a abc) {
 
 		if current.Val == "func" {
-			defineFunc := &DefineFuncNode{}
+			defineFunc := &DefineFuncNode{Export: p.nextFuncExport}
+			p.nextFuncExport = ""
 			p.i++
 
 			var argsOrMethodType []*NameNode
@@ -415,7 +498,11 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 
 					// Next can be type, that means that the previous was the name of the var
 					isType := p.lookAhead(0)
-					if isType.Type == lexer.IDENTIFIER {
+					isNamed := isType.Type == lexer.IDENTIFIER
+					if allowMultiRetVals && isType.Type == lexer.OPERATOR {
+						isNamed = isType.Val != "," && isType.Val != ")"
+					}
+					if isNamed {
 						retType, err := p.parseOneType()
 						if err != nil {
 							panic(err)
@@ -470,8 +557,7 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 			var retVals []Node
 
 			for {
-				checkIfEOL := p.lookAhead(0)
-				if checkIfEOL.Type == lexer.EOL {
+				if isEndOfStatement(p.lookAhead(0)) {
 					break
 				}
 
@@ -487,6 +573,10 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 				break
 			}
 
+			// Stop at the last item of the statement, the next item can be the
+			// end of the block, such as in "{ return x }"
+			p.i--
+
 			res = &ReturnNode{Vals: retVals}
 			if withAheadParse {
 				res = p.aheadParse(res)
@@ -542,7 +632,7 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 					if nextToken.Val == ")" {
 						break
 					}
-					if nextToken.Type == lexer.EOL {
+					if isEndOfStatement(nextToken) {
 						p.i++
 						continue
 					}
@@ -573,17 +663,27 @@ func (p *parser) parseOneWithOptions(withAheadParse, withArithAhead, withIdentif
 		}
 
 		if current.Val == "break" {
-			return &BreakNode{}
+			return &BreakNode{Label: p.branchLabel()}
 		}
 
 		if current.Val == "continue" {
-			return &ContinueNode{}
+			return &ContinueNode{Label: p.branchLabel()}
+		}
+
+		if current.Val == "goto" {
+			label := p.branchLabel()
+			if label == "" {
+				panic("goto must be followed by a label")
+			}
+			return &GotoNode{Label: label}
 		}
 
 		if current.Val == "import" {
 			imp := p.parseImport()
 			for _, n := range imp.PackagePaths {
-				p.packages[n] = struct{}{}
+				// Packages are referred to by the last element of the path,
+				// such as bits in "math/bits"
+				p.packages[path.Base(n)] = struct{}{}
 			}
 			return imp
 		}
@@ -618,7 +718,7 @@ func (p *parser) parseVarDecl(isConst bool) *AllocNode {
 	allocNode := &AllocNode{Name: p.identifierList(), IsConst: isConst}
 
 	isEq := p.lookAhead(0)
-	if (isEq.Type != lexer.OPERATOR || isEq.Val != "=") && isEq.Type != lexer.EOL {
+	if (isEq.Type != lexer.OPERATOR || isEq.Val != "=") && !isEndOfStatement(isEq) {
 		if isConst {
 			// panic("unexpected type in const declaration")
 		}
@@ -719,10 +819,10 @@ func (p *parser) aheadParseWithOptions(input Node, withArithAhead, withIdentifie
 
 				p.i++
 
-				return p.aheadParse(&TypeCastInterfaceNode{
+				return p.aheadParseWithOptions(&TypeCastInterfaceNode{
 					Item: input,
 					Type: castToType,
-				})
+				}, withArithAhead, withIdentifierAhead)
 			}
 
 			panic(fmt.Sprintf("Expected IDENTFIER or ( after . Got: %+v", next))
@@ -760,29 +860,14 @@ func (p *parser) aheadParseWithOptions(input Node, withArithAhead, withIdentifie
 			panic(fmt.Sprintf("%s can only be used after a name. Got: %+v", next.Val, input))
 		}
 
-		if next.Val == "+=" || next.Val == "-=" || next.Val == "*=" || next.Val == "/=" {
-			p.i++
-			p.i++
-
-			var op Operator
-			switch next.Val {
-			case "+=":
-				op = OP_ADD
-			case "-=":
-				op = OP_SUB
-			case "*=":
-				op = OP_MUL
-			case "/=":
-				op = OP_DIV
-			}
+		// Compound assignment, such as "a[i] += 1"
+		if op, ok := assignOpsCharToOp[next.Val]; ok {
+			p.i += 2
 
-			return &AssignNode{
-				Target: []Node{input},
-				Val: []Node{&OperatorNode{
-					Operator: op,
-					Left:     input,
-					Right:    p.parseOne(true),
-				}},
+			return &OperatorAssignNode{
+				Target:   input,
+				Operator: op,
+				Val:      p.parseOne(true),
 			}
 		}
 
@@ -797,7 +882,17 @@ func (p *parser) aheadParseWithOptions(input Node, withArithAhead, withIdentifie
 		if next.Val == "[" {
 			p.i += 2
 
-			index := p.parseOne(true)
+			var index Node
+
+			// Slicing without a start, such as "a[:2]", starts at 0. The position
+			// is moved back to the "[" so that the ":" is the next item.
+			checkIfStartColon := p.lookAhead(0)
+			if checkIfStartColon.Type == lexer.OPERATOR && checkIfStartColon.Val == ":" {
+				index = &ConstantNode{Type: NUMBER, Value: 0}
+				p.i--
+			} else {
+				index = p.parseOne(true)
+			}
 
 			var res Node
 
@@ -831,18 +926,15 @@ func (p *parser) aheadParseWithOptions(input Node, withArithAhead, withIdentifie
 
 			expectEndBracket := p.lookAhead(0)
 			if expectEndBracket.Type == lexer.OPERATOR && expectEndBracket.Val == "]" {
-				return p.aheadParse(res)
+				return p.aheadParseWithOptions(res, withArithAhead, withIdentifierAhead)
 			}
 
 			panic(fmt.Sprintf("Unexpected %+v, expected ]", expectEndBracket))
 		}
 
-		// Handle "Operations" both arith and comparision
-		if _, ok := opsCharToOp[next.Val]; ok {
-			operator := opsCharToOp[next.Val]
-			_, isArithOp := arithOperators[operator]
-
-			if !withArithAhead && isArithOp {
+		// Handle "Operations", arith, comparisons and logical operators
+		if operator, ok := opsCharToOp[next.Val]; ok {
+			if !withArithAhead {
 				return input
 			}
 
@@ -850,15 +942,12 @@ func (p *parser) aheadParseWithOptions(input Node, withArithAhead, withIdentifie
 			res := &OperatorNode{
 				Operator: operator,
 				Left:     input,
+				Right:    p.parseOneWithOptions(false, false, true),
 			}
 
-			if isArithOp {
-				res.Right = p.parseOneWithOptions(false, false, true)
-				// Sort infix operations if necessary (eg: apply OP_MUL before OP_ADD)
-				res = sortInfix(res)
-			} else {
-				res.Right = p.parseOneWithOptions(true, true, true)
-			}
+			// Sort infix operations if necessary (eg: apply OP_MUL before OP_ADD,
+			// and OP_EQ before OP_LOGICAL_AND)
+			res = sortInfix(res)
 
 			return p.aheadParseWithOptions(res, true, true)
 		}
@@ -880,24 +969,24 @@ func (p *parser) aheadParseWithOptions(input Node, withArithAhead, withIdentifie
 		p.i += 2 // identifier and left paren
 
 		if _, ok := p.types[current.Val]; ok {
-			val := p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: ")"})
+			val := p.parseExpressionsUntil(lexer.Item{Type: lexer.OPERATOR, Val: ")"})
 			if len(val) != 1 {
 				panic("type conversion must take only one argument")
 			}
-			return p.aheadParse(&TypeCastNode{
+			return p.aheadParseWithOptions(&TypeCastNode{
 				Type: &SingleTypeNode{
 					TypeName: current.Val,
 				},
 				Val: val[0],
-			})
+			}, withArithAhead, withIdentifierAhead)
 		}
 
 		beforeAllocRightHand := p.inAllocRightHand
 		p.inAllocRightHand = false
-		callNode := p.aheadParse(&CallNode{
+		callNode := p.aheadParseWithOptions(&CallNode{
 			Function:  input,
-			Arguments: p.parseUntil(lexer.Item{Type: lexer.OPERATOR, Val: ")"}),
-		})
+			Arguments: p.parseExpressionsUntil(lexer.Item{Type: lexer.OPERATOR, Val: ")"}),
+		}, withArithAhead, withIdentifierAhead)
 		p.inAllocRightHand = beforeAllocRightHand
 		return callNode
 	}
@@ -910,60 +999,11 @@ func (p *parser) aheadParseWithOptions(input Node, withArithAhead, withIdentifie
 		if isNamedNode {
 			_, isType := p.types[nameNode.Name]
 			if isType {
-				inputType := &SingleTypeNode{
+				p.i++
+				structLiteral := p.parseStructLiteral(&SingleTypeNode{
 					TypeName: nameNode.Name,
-				}
-
-				p.i += 2
-
-				items := make(map[string]Node)
-
-				for {
-					// Skip EOLs
-					checkIfEOL := p.lookAhead(0)
-					if checkIfEOL.Type == lexer.EOL {
-						p.i++
-					}
-
-					// Find end of parsing
-					checkIfEndBracket := p.lookAhead(0)
-					if checkIfEndBracket.Type == lexer.OPERATOR && checkIfEndBracket.Val == "}" {
-						p.i++
-						break
-					}
-
-					key := p.lookAhead(0)
-					if key.Type != lexer.IDENTIFIER {
-						panic("Expected IDENTIFIER in struct initialization")
-					}
-
-					col := p.lookAhead(1)
-					p.expect(col, lexer.Item{Type: lexer.OPERATOR, Val: ":"})
-
-					p.i += 2
-
-					prevInAlloc := p.inAllocRightHand
-					p.inAllocRightHand = false
-					items[key.Val] = p.parseOne(true)
-					p.inAllocRightHand = prevInAlloc
-
-					p.i++
-
-					commaOrEnd := p.lookAhead(0)
-					if commaOrEnd.Type == lexer.OPERATOR && commaOrEnd.Val == "," {
-						p.i++
-						continue
-					}
-
-					if commaOrEnd.Type == lexer.OPERATOR && commaOrEnd.Val == "}" {
-						break
-					}
-				}
-
-				return p.aheadParse(&InitializeStructNode{
-					Type:  inputType,
-					Items: items,
 				})
+				return p.aheadParseWithOptions(structLiteral, withArithAhead, withIdentifierAhead)
 			}
 		}
 	}
@@ -1046,10 +1086,24 @@ func (p *parser) parseUntil(until lexer.Item) []Node {
 	return n
 }
 
+// parseExpressionsUntil parses a list of expressions, such as the arguments of
+// a call, until it finds until. The expressions are not statements, and their
+// lines are not recorded.
+func (p *parser) parseExpressionsUntil(until lexer.Item) []Node {
+	n, _ := p.parseNodesUntilEither([]lexer.Item{until}, false)
+	return n
+}
+
 // parseUntilEither reads lexer items until it finds one that equals to a item in "untils"
 // The list of parsed nodes is returned in res. The lexer item that stopped the iteration
 // is returned in "reached"
 func (p *parser) parseUntilEither(untils []lexer.Item) (res []Node, reached lexer.Item) {
+	return p.parseNodesUntilEither(untils, true)
+}
+
+// parseNodesUntilEither is parseUntilEither, the lines of the nodes are only
+// recorded if they are statements
+func (p *parser) parseNodesUntilEither(untils []lexer.Item, statements bool) (res []Node, reached lexer.Item) {
 	for {
 		current := p.input[p.i]
 
@@ -1066,19 +1120,144 @@ func (p *parser) parseUntilEither(untils []lexer.Item) (res []Node, reached lexe
 			continue
 		}
 
+		// Labeled statement, such as "Outer: for { ... }"
+		if next := p.lookAhead(1); current.Type == lexer.IDENTIFIER && next.Type == lexer.OPERATOR && next.Val == ":" {
+			p.i += 2
+			label := &LabelNode{Label: current.Val}
+			res = append(res, label)
+			if statements {
+				p.lines[label] = current.Line
+			}
+
+			// The label applies to the next statement, that can be on the next line
+			for isEndOfStatement(p.lookAhead(0)) {
+				p.i++
+			}
+			next := p.lookAhead(0)
+			if next.Type == lexer.OPERATOR && next.Val == "}" {
+				continue
+			}
+
+			label.Statement = p.parseOne(true)
+			p.i++
+			continue
+		}
+
 		one := p.parseOne(true)
 		if one != nil {
 			res = append(res, one)
+			if statements {
+				p.lines[one] = current.Line
+			}
 		}
 
 		p.i++
 	}
 }
 
+// parseStructLiteral parses the fields of a struct literal of the type
+// structType, such as {a: 1, b: "b"}. It starts at the { and stops at the }.
+func (p *parser) parseStructLiteral(structType TypeNode) *InitializeStructNode {
+	p.i++
+
+	prevInAlloc := p.inAllocRightHand
+	p.inAllocRightHand = false
+	defer func() {
+		p.inAllocRightHand = prevInAlloc
+	}()
+
+	items := make(map[string]Node)
+
+	for {
+		current := p.lookAhead(0)
+
+		// Skip EOLs and commas
+		if isEndOfStatement(current) || (current.Type == lexer.OPERATOR && current.Val == ",") {
+			p.i++
+			continue
+		}
+
+		// Find end of parsing
+		if current.Type == lexer.OPERATOR && current.Val == "}" {
+			break
+		}
+
+		if current.Type != lexer.IDENTIFIER {
+			panic("Expected IDENTIFIER in struct initialization")
+		}
+
+		p.expect(p.lookAhead(1), lexer.Item{Type: lexer.OPERATOR, Val: ":"})
+		p.i += 2
+
+		items[current.Val] = p.parseOne(true)
+		p.i++
+	}
+
+	return &InitializeStructNode{
+		Type:  structType,
+		Items: items,
+	}
+}
+
+// parseCompositeItems parses the items of a slice or array literal with the
+// item type itemType, until the closing }.
+func (p *parser) parseCompositeItems(itemType TypeNode) []Node {
+	prevInAlloc := p.inAllocRightHand
+	p.inAllocRightHand = false
+	defer func() {
+		p.inAllocRightHand = prevInAlloc
+	}()
+
+	var items []Node
+
+	for {
+		current := p.lookAhead(0)
+
+		// Skip EOLs and commas
+		if isEndOfStatement(current) || (current.Type == lexer.OPERATOR && current.Val == ",") {
+			p.i++
+			continue
+		}
+
+		if current.Type == lexer.OPERATOR && current.Val == "}" {
+			return items
+		}
+
+		// The type of composite literals can be omitted, such as in
+		// []T{{a: 1}, {a: 2}}
+		if current.Type == lexer.OPERATOR && current.Val == "{" {
+			items = append(items, p.parseElidedLiteral(itemType))
+		} else {
+			items = append(items, p.parseOne(true))
+		}
+		p.i++
+	}
+}
+
+// parseElidedLiteral parses a composite literal where the type has been
+// omitted, the literal is of the type t. It starts at the { and stops at the }.
+func (p *parser) parseElidedLiteral(t TypeNode) Node {
+	switch tt := t.(type) {
+	case *PointerTypeNode:
+		// &T can be omitted as well, such as in []*T{{a: 1}}
+		return &GetReferenceNode{Item: p.parseElidedLiteral(tt.ValueType)}
+	case *SliceTypeNode:
+		p.i++
+		return &InitializeSliceNode{Type: tt.ItemType, Items: p.parseCompositeItems(tt.ItemType)}
+	case *ArrayTypeNode:
+		p.i++
+		return &InitializeArrayNode{Type: tt.ItemType, Size: int(tt.Len), Items: p.parseCompositeItems(tt.ItemType)}
+	}
+	return p.parseStructLiteral(t)
+}
+
 func (p *parser) parseFunctionArguments() []*NameNode {
 	var res []*NameNode
 	var i int
 
+	// Arguments that are waiting for the type of the next argument ("a, b int")
+	var withoutType []*NameNode
+
 	for {
 		current := p.input[p.i]
 		if current.Type == lexer.OPERATOR && current.Val == ")" {
@@ -1093,6 +1272,12 @@ func (p *parser) parseFunctionArguments() []*NameNode {
 
 			p.i++
 			current = p.input[p.i]
+
+			// Trailing comma after the last argument
+			if current.Type == lexer.OPERATOR && current.Val == ")" {
+				p.i++
+				return res
+			}
 		}
 
 		name := p.lookAhead(0)
@@ -1101,12 +1286,26 @@ func (p *parser) parseFunctionArguments() []*NameNode {
 		}
 		p.i++
 
+		// The type is shared with the arguments that follows
+		if next := p.lookAhead(0); next.Type == lexer.OPERATOR && next.Val == "," {
+			arg := &NameNode{Name: name.Val}
+			res = append(res, arg)
+			withoutType = append(withoutType, arg)
+			i++
+			continue
+		}
+
 		argType, err := p.parseOneType()
 		if err != nil {
 			panic(err)
 		}
 		p.i++
 
+		for _, arg := range withoutType {
+			arg.Type = argType
+		}
+		withoutType = nil
+
 		res = append(res, &NameNode{
 			Name: name.Val,
 			Type: argType,
@@ -1116,6 +1315,59 @@ func (p *parser) parseFunctionArguments() []*NameNode {
 	}
 }
 
+// parseInterfaceMethodParams parses the parameters or results of a method in an
+// interface, such as "(p []byte)" or "(int, error)", and returns their types.
+// The parameters are either all named or all unnamed, named parameters can
+// share a type ("a, b int").
+func (p *parser) parseInterfaceMethodParams() []TypeNode {
+	p.expect(p.lookAhead(0), lexer.Item{Type: lexer.OPERATOR, Val: "("})
+	p.i++
+
+	var res []TypeNode
+
+	// Parameters that are waiting for the type of the next named parameter
+	var withoutType int
+
+	for {
+		current := p.lookAhead(0)
+		if current.Type == lexer.OPERATOR && current.Val == ")" {
+			p.i++
+			return res
+		}
+		if current.Type == lexer.OPERATOR && current.Val == "," {
+			p.i++
+			continue
+		}
+
+		// A named parameter, the name is followed by the type
+		next := p.lookAhead(1)
+		isNamed := current.Type == lexer.IDENTIFIER && !isEndOfStatement(next) &&
+			!(next.Type == lexer.OPERATOR && (next.Val == "," || next.Val == ")" || next.Val == "."))
+		if isNamed {
+			p.i++
+		}
+
+		paramType, err := p.parseOneType()
+		if err != nil {
+			panic(err)
+		}
+		p.i++
+
+		if !isNamed {
+			res = append(res, paramType)
+			withoutType++
+			continue
+		}
+
+		// The previous parameters were names without types ("a, b int")
+		res = res[:len(res)-withoutType]
+		for i := 0; i <= withoutType; i++ {
+			res = append(res, paramType)
+		}
+		withoutType = 0
+	}
+}
+
 func (p *parser) parseOneType() (TypeNode, error) {
 	current := p.lookAhead(0)
 
@@ -1160,7 +1412,7 @@ func (p *parser) parseOneType() (TypeNode, error) {
 			itemName := p.lookAhead(0)
 
 			// Ignore EOL
-			if itemName.Type == lexer.EOL {
+			if isEndOfStatement(itemName) {
 				p.i++
 				continue
 			}
@@ -1175,14 +1427,27 @@ func (p *parser) parseOneType() (TypeNode, error) {
 			}
 			p.i++
 
+			// Fields that share the type, such as "a, b int"
+			itemNames := []string{itemName.Val}
+			for comma := p.lookAhead(0); comma.Type == lexer.OPERATOR && comma.Val == ","; comma = p.lookAhead(0) {
+				nextName := p.lookAhead(1)
+				if nextName.Type != lexer.IDENTIFIER {
+					panic("expected IDENTIFIER in struct{}, got " + fmt.Sprintf("%+v", nextName))
+				}
+				itemNames = append(itemNames, nextName.Val)
+				p.i += 2
+			}
+
 			itemType, err := p.parseOneType()
 			if err != nil {
 				panic("expected TYPE in struct{}, got: " + err.Error())
 			}
 			p.i++
 
-			res.Types = append(res.Types, itemType)
-			res.Names[itemName.Val] = len(res.Types) - 1
+			for _, name := range itemNames {
+				res.Types = append(res.Types, itemType)
+				res.Names[name] = len(res.Types) - 1
+			}
 
 			current = p.lookAhead(0)
 		}
@@ -1202,7 +1467,7 @@ func (p *parser) parseOneType() (TypeNode, error) {
 		// Parse methods if set
 		for {
 			current := p.lookAhead(0)
-			if current.Type == lexer.EOL {
+			if isEndOfStatement(current) {
 				p.i++
 				continue
 			}
@@ -1218,42 +1483,17 @@ func (p *parser) parseOneType() (TypeNode, error) {
 
 			p.i++
 			p.expect(p.lookAhead(0), lexer.Item{Type: lexer.OPERATOR, Val: "("})
+			methodDef.ArgumentTypes = p.parseInterfaceMethodParams()
 
-			// Check if the method takes any arguments
-			for {
-				p.i++
-				current = p.lookAhead(0)
-				if current.Type == lexer.OPERATOR && current.Val == ")" {
-					p.i++
-					break
-				}
-
-				current = p.lookAhead(0)
-				if current.Type == lexer.OPERATOR && current.Val == "," {
-					continue
-				}
-
-				argumentType, err := p.parseOneType()
-				if err != nil {
-					panic(err)
-				}
-
-				methodDef.ArgumentTypes = append(methodDef.ArgumentTypes, argumentType)
-			}
-
-			// Function return types
-			for {
-				current = p.lookAhead(0)
-				if current.Type == lexer.EOL {
-					p.i++
-					break
-				}
-
+			// Function return types, either a single type or a list of types
+			current = p.lookAhead(0)
+			if current.Type == lexer.OPERATOR && current.Val == "(" {
+				methodDef.ReturnTypes = p.parseInterfaceMethodParams()
+			} else if !isEndOfStatement(current) && !(current.Type == lexer.OPERATOR && current.Val == "}") {
 				returnType, err := p.parseOneType()
 				if err != nil {
 					panic(err)
 				}
-
 				methodDef.ReturnTypes = append(methodDef.ReturnTypes, returnType)
 				p.i++
 			}
@@ -1310,7 +1550,7 @@ func (p *parser) parseOneType() (TypeNode, error) {
 		if arrayLenght.Type != lexer.NUMBER {
 			return nil, errors.New("parseArray failed: Expected number or ] after [")
 		}
-		arrayLengthInt, err := strconv.Atoi(arrayLenght.Val)
+		arrayLengthInt, err := strconv.ParseInt(arrayLenght.Val, 0, 64)
 		if err != nil {
 			return nil, err
 		}
@@ -1329,7 +1569,7 @@ func (p *parser) parseOneType() (TypeNode, error) {
 
 		return &ArrayTypeNode{
 			ItemType:   arrayItemType,
-			Len:        int64(arrayLengthInt),
+			Len:        arrayLengthInt,
 			IsVariadic: isVariadic,
 		}, nil
 	}
@@ -1339,53 +1579,15 @@ func (p *parser) parseOneType() (TypeNode, error) {
 		p.i++
 
 		expectOpenParen := p.lookAhead(0)
-		if expectOpenParen.Type != lexer.OPERATOR && expectOpenParen.Val != "(" {
+		if expectOpenParen.Type != lexer.OPERATOR || expectOpenParen.Val != "(" {
 			return nil, errors.New("parse func failed, expected ( after func")
 		}
-		p.i++
 
 		fn := &FuncTypeNode{}
 
-		multiTypeParse := func() ([]TypeNode, error) {
-			var typeList []TypeNode
-
-			for {
-				checkIfEndParen := p.lookAhead(0)
-				if checkIfEndParen.Type == lexer.OPERATOR && checkIfEndParen.Val == ")" {
-					break
-				}
-
-				argType, err := p.parseOneType()
-				if err != nil {
-					return nil, err
-				}
-
-				typeList = append(typeList, argType)
-
-				p.i++
-
-				expectCommaOrEndParen := p.lookAhead(0)
-				if expectCommaOrEndParen.Type == lexer.OPERATOR && expectCommaOrEndParen.Val == "," {
-					p.i++
-					continue
-				}
-
-				if expectCommaOrEndParen.Type == lexer.OPERATOR && expectCommaOrEndParen.Val == ")" {
-					continue
-				}
-
-				return nil, errors.New("expected ) or , in func arg parsing")
-			}
-
-			return typeList, nil
-		}
-
-		// List of arguments
-		var err error
-		fn.ArgTypes, err = multiTypeParse()
-		if err != nil {
-			return nil, fmt.Errorf("unable to parse func arguments: %s", err)
-		}
+		// List of arguments, ends with p.i at the closing parenthesis
+		fn.ArgTypes = p.parseInterfaceMethodParams()
+		p.i--
 
 		// Return type parsing
 		// Possible formats:
@@ -1398,18 +1600,17 @@ func (p *parser) parseOneType() (TypeNode, error) {
 		// Multiple types
 		if checkIfParenOrType.Type == lexer.OPERATOR && checkIfParenOrType.Val == "(" {
 			p.i++
-			p.i++
-
-			fn.RetTypes, err = multiTypeParse()
-			if err != nil {
-				return nil, fmt.Errorf("unable to parse func return values: %s", err)
-			}
-
+			fn.RetTypes = p.parseInterfaceMethodParams()
+			p.i--
 			return fn, nil
 		}
 
 		// Single types
-		if checkIfParenOrType.Type == lexer.IDENTIFIER {
+		isSingleType := checkIfParenOrType.Type == lexer.IDENTIFIER
+		if checkIfParenOrType.Type == lexer.OPERATOR {
+			isSingleType = checkIfParenOrType.Val == "[" || checkIfParenOrType.Val == "*"
+		}
+		if isSingleType {
 			p.i++
 
 			t, err := p.parseOneType()
@@ -1427,6 +1628,23 @@ func (p *parser) parseOneType() (TypeNode, error) {
 	return nil, errors.New("parseOneType failed: " + fmt.Sprintf("%+v", current))
 }
 
+// branchLabel returns the label after break, continue or goto, such as Outer
+// in "break Outer". Returns an empty string if there is no label.
+func (p *parser) branchLabel() string {
+	next := p.lookAhead(1)
+	if next.Type != lexer.IDENTIFIER {
+		return ""
+	}
+	p.i++
+	return next.Val
+}
+
+// isEndOfStatement reports whether item ends a statement, either an EOL where
+// the lexer has inserted a semicolon, or an explicit semicolon
+func isEndOfStatement(item lexer.Item) bool {
+	return item.Type == lexer.EOL || (item.Type == lexer.OPERATOR && item.Val == ";")
+}
+
 // panics if check fails
 func (p *parser) expect(input lexer.Item, expected lexer.Item) {
 	if expected.Type != input.Type {
@@ -1437,3 +1655,19 @@ func (p *parser) expect(input lexer.Item, expected lexer.Item) {
 		panic(fmt.Sprintf("Expected %+v got %+v", expected, input))
 	}
 }
+
+// parseInt parses an integer literal, such as "1_000", "0x1f", "0o17", "0b101"
+// or the legacy octal "017". Literals that are too large for an int64, such as
+// 0xffffffffffffffff, are stored with the same bits as the uint64 value.
+func parseInt(literal string) int64 {
+	val, err := strconv.ParseInt(literal, 0, 64)
+	if err == nil {
+		return val
+	}
+
+	uval, uerr := strconv.ParseUint(literal, 0, 64)
+	if uerr != nil {
+		panic(err)
+	}
+	return int64(uval)
+}
@@ -26,7 +26,12 @@ func TestCall(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestAdd(t *testing.T) {
@@ -53,7 +58,12 @@ func TestAdd(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestInfixPriority(t *testing.T) {
@@ -89,7 +99,12 @@ func TestInfixPriority(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestInfixPriority2(t *testing.T) {
@@ -125,7 +140,12 @@ func TestInfixPriority2(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestInfixPriority3(t *testing.T) {
@@ -170,7 +190,12 @@ func TestInfixPriority3(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestInfixPriority4(t *testing.T) {
@@ -210,7 +235,12 @@ func TestInfixPriority4(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestInfixPriority4Load(t *testing.T) {
@@ -254,5 +284,255 @@ func TestInfixPriority4Load(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(input, false))
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
+}
+
+func TestInfixPriorityLogical(t *testing.T) {
+	input := []lexer.Item{
+		{Type: lexer.IDENTIFIER, Val: "a"},
+		{Type: lexer.OPERATOR, Val: "||"},
+		{Type: lexer.IDENTIFIER, Val: "b"},
+		{Type: lexer.OPERATOR, Val: "&&"},
+		{Type: lexer.IDENTIFIER, Val: "c"},
+		{Type: lexer.OPERATOR, Val: "=="},
+		{Type: lexer.IDENTIFIER, Val: "d"},
+		{Type: lexer.OPERATOR, Val: "+"},
+		{Type: lexer.NUMBER, Val: "1"},
+		{Type: lexer.EOF, Val: ""},
+	}
+
+	/*
+		OP(a || OP(b && OP(c == OP(d + 1))))
+	*/
+
+	expected := &FileNode{
+		Instructions: []Node{
+			&OperatorNode{
+				Operator: OP_LOGICAL_OR,
+				Left:     &NameNode{Name: "a"},
+				Right: &OperatorNode{
+					Operator: OP_LOGICAL_AND,
+					Left:     &NameNode{Name: "b"},
+					Right: &OperatorNode{
+						Operator: OP_EQ,
+						Left:     &NameNode{Name: "c"},
+						Right: &OperatorNode{
+							Operator: OP_ADD,
+							Left:     &NameNode{Name: "d"},
+							Right:    &ConstantNode{Type: NUMBER, Value: 1},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
+}
+
+func TestOperatorAssign(t *testing.T) {
+	input := []lexer.Item{
+		{Type: lexer.OPERATOR, Val: "*"},
+		{Type: lexer.IDENTIFIER, Val: "p"},
+		{Type: lexer.OPERATOR, Val: "<<="},
+		{Type: lexer.NUMBER, Val: "2"},
+		{Type: lexer.EOL, Val: ""},
+		{Type: lexer.IDENTIFIER, Val: "a"},
+		{Type: lexer.OPERATOR, Val: "["},
+		{Type: lexer.IDENTIFIER, Val: "i"},
+		{Type: lexer.OPERATOR, Val: "]"},
+		{Type: lexer.OPERATOR, Val: "&^="},
+		{Type: lexer.IDENTIFIER, Val: "b"},
+		{Type: lexer.OPERATOR, Val: "|"},
+		{Type: lexer.NUMBER, Val: "1"},
+		{Type: lexer.EOF, Val: ""},
+	}
+
+	expected := &FileNode{
+		Instructions: []Node{
+			&OperatorAssignNode{
+				Target:   &DereferenceNode{Item: &NameNode{Name: "p"}},
+				Operator: OP_LEFT_SHIFT,
+				Val:      &ConstantNode{Type: NUMBER, Value: 2},
+			},
+			&OperatorAssignNode{
+				Target:   &LoadArrayElement{Array: &NameNode{Name: "a"}, Pos: &NameNode{Name: "i"}},
+				Operator: OP_BIT_CLEAR,
+				Val: &OperatorNode{
+					Operator: OP_BIT_OR,
+					Left:     &NameNode{Name: "b"},
+					Right:    &ConstantNode{Type: NUMBER, Value: 1},
+				},
+			},
+		},
+	}
+
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 0,
+		parsed.Instructions[1]: 0,
+	}
+
+	assert.Equal(t, expected, parsed)
+}
+
+func TestExportDirective(t *testing.T) {
+	input := []lexer.Item{
+		{Type: lexer.DIRECTIVE, Val: "export Foo", Line: 1},
+		{Type: lexer.EOL, Val: "", Line: 1},
+		{Type: lexer.KEYWORD, Val: "func", Line: 2},
+		{Type: lexer.IDENTIFIER, Val: "foo", Line: 2},
+		{Type: lexer.OPERATOR, Val: "(", Line: 2},
+		{Type: lexer.OPERATOR, Val: ")", Line: 2},
+		{Type: lexer.OPERATOR, Val: "{", Line: 2},
+		{Type: lexer.OPERATOR, Val: "}", Line: 2},
+		{Type: lexer.EOL, Val: "", Line: 2},
+		{Type: lexer.KEYWORD, Val: "func", Line: 3},
+		{Type: lexer.IDENTIFIER, Val: "bar", Line: 3},
+		{Type: lexer.OPERATOR, Val: "(", Line: 3},
+		{Type: lexer.OPERATOR, Val: ")", Line: 3},
+		{Type: lexer.OPERATOR, Val: "{", Line: 3},
+		{Type: lexer.OPERATOR, Val: "}", Line: 3},
+		{Type: lexer.EOL, Val: "", Line: 3},
+		{Type: lexer.EOF, Val: "", Line: 0},
+	}
+
+	expected := &FileNode{
+		Instructions: []Node{
+			&DefineFuncNode{Name: "foo", IsNamed: true, Export: "Foo"},
+			&DefineFuncNode{Name: "bar", IsNamed: true},
+		},
+	}
+
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 2,
+		parsed.Instructions[1]: 3,
+	}
+
+	assert.Equal(t, expected, parsed)
+}
+
+func TestFuncGroupedArguments(t *testing.T) {
+	input := []lexer.Item{
+		{Type: lexer.KEYWORD, Val: "func", Line: 1},
+		{Type: lexer.IDENTIFIER, Val: "foo", Line: 1},
+		{Type: lexer.OPERATOR, Val: "(", Line: 1},
+		{Type: lexer.IDENTIFIER, Val: "a", Line: 1},
+		{Type: lexer.OPERATOR, Val: ",", Line: 1},
+		{Type: lexer.IDENTIFIER, Val: "b", Line: 1},
+		{Type: lexer.IDENTIFIER, Val: "int", Line: 1},
+		{Type: lexer.OPERATOR, Val: ",", Line: 1},
+		{Type: lexer.IDENTIFIER, Val: "c", Line: 1},
+		{Type: lexer.IDENTIFIER, Val: "string", Line: 1},
+		{Type: lexer.OPERATOR, Val: ")", Line: 1},
+		{Type: lexer.OPERATOR, Val: "{", Line: 1},
+		{Type: lexer.OPERATOR, Val: "}", Line: 1},
+		{Type: lexer.EOL, Val: "", Line: 1},
+		{Type: lexer.EOF, Val: "", Line: 0},
+	}
+
+	intType := &SingleTypeNode{TypeName: "int"}
+	expected := &FileNode{
+		Instructions: []Node{
+			&DefineFuncNode{
+				Name:    "foo",
+				IsNamed: true,
+				Arguments: []*NameNode{
+					{Name: "a", Type: intType},
+					{Name: "b", Type: intType},
+					{Name: "c", Type: &SingleTypeNode{TypeName: "string"}},
+				},
+			},
+		},
+	}
+
+	parsed := Parse(input, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 1,
+	}
+
+	assert.Equal(t, expected, parsed)
+}
+
+func TestLabels(t *testing.T) {
+	input := lexer.Lex(`func f() {
+outer:
+	for {
+		break outer
+	}
+	goto end
+end:
+}`)
+
+	expected := []Node{
+		&LabelNode{
+			Label: "outer",
+			Statement: &ForNode{
+				Condition:      loopForever(),
+				Block:          []Node{&BreakNode{Label: "outer"}},
+				IsThreeTypeFor: true,
+			},
+		},
+		&GotoNode{Label: "end"},
+		&LabelNode{Label: "end"},
+	}
+
+	fn := Parse(input, false).Instructions[0].(*DefineFuncNode)
+	assert.Equal(t, expected, fn.Body)
+}
+
+func TestElidedCompositeLiterals(t *testing.T) {
+	input := lexer.Lex(`type T struct {
+	a, b int
+}
+
+func f() {
+	x := []*T{{a: 1}, {b: 2}}
+	y := [][]int{{1}, {}}
+}`)
+
+	itemType := &SingleTypeNode{TypeName: "T"}
+	expectedX := &InitializeSliceNode{
+		Type: &PointerTypeNode{ValueType: itemType},
+		Items: []Node{
+			&GetReferenceNode{Item: &InitializeStructNode{
+				Type:  itemType,
+				Items: map[string]Node{"a": &ConstantNode{Type: NUMBER, Value: 1}},
+			}},
+			&GetReferenceNode{Item: &InitializeStructNode{
+				Type:  itemType,
+				Items: map[string]Node{"b": &ConstantNode{Type: NUMBER, Value: 2}},
+			}},
+		},
+	}
+	expectedY := &InitializeSliceNode{
+		Type: &SliceTypeNode{ItemType: &SingleTypeNode{TypeName: "int"}},
+		Items: []Node{
+			&InitializeSliceNode{
+				Type:  &SingleTypeNode{TypeName: "int"},
+				Items: []Node{&ConstantNode{Type: NUMBER, Value: 1}},
+			},
+			&InitializeSliceNode{Type: &SingleTypeNode{TypeName: "int"}},
+		},
+	}
+
+	parsed := Parse(input, false)
+
+	structType := parsed.Instructions[0].(*DefineTypeNode).Type.(*StructTypeNode)
+	assert.Equal(t, map[string]int{"a": 0, "b": 1}, structType.Names)
+
+	fn := parsed.Instructions[1].(*DefineFuncNode)
+	assert.Equal(t, expectedX, fn.Body[0].(*AllocNode).Val[0])
+	assert.Equal(t, expectedY, fn.Body[1].(*AllocNode).Val[0])
 }
@@ -8,6 +8,7 @@ import (
 
 type SwitchNode struct {
 	baseNode
+	Init        Node // Statement that is executed before the switch, such as "x := f()"
 	Item        Node
 	Cases       []*SwitchCaseNode
 	DefaultBody []Node // can be null
@@ -37,13 +38,21 @@ func (p *parser) parseSwitch() *SwitchNode {
 	}
 
 	p.i++
+
+	// The value is preceded by an init statement, such as in "switch x := f(); x {"
+	if next := p.lookAhead(0); next.Type == lexer.OPERATOR && next.Val == ";" {
+		p.i++
+		s.Init = s.Item
+		s.Item = p.parseOne(true)
+		p.i++
+	}
 	p.expect(p.lookAhead(0), lexer.Item{Type: lexer.OPERATOR, Val: "{"})
 	p.i++
 
 	for {
 		next := p.lookAhead(0)
 
-		if next.Type == lexer.EOL {
+		if isEndOfStatement(next) {
 			p.i++
 			continue
 		}
@@ -65,9 +74,9 @@ func (p *parser) parseSwitch() *SwitchNode {
 
 				if curr.Type == lexer.OPERATOR && curr.Val == "," {
 					p.i++
-					switchCase.Conditions = append(append(switchCase.Conditions,
+					switchCase.Conditions = append(switchCase.Conditions,
 						p.parseOne(true),
-					))
+					)
 					p.i++
 					continue
 				}
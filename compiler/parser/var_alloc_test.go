@@ -20,7 +20,12 @@ func TestAllocType(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(lexed, false))
+	parsed := Parse(lexed, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 1,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestAllocTypeWithValue(t *testing.T) {
@@ -36,7 +41,12 @@ func TestAllocTypeWithValue(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(lexed, false))
+	parsed := Parse(lexed, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 1,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestAllocImplicitTypeValue(t *testing.T) {
@@ -51,7 +61,12 @@ func TestAllocImplicitTypeValue(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(lexed, false))
+	parsed := Parse(lexed, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 1,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestAllocMultiWithType(t *testing.T) {
@@ -66,7 +81,12 @@ func TestAllocMultiWithType(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(lexed, false))
+	parsed := Parse(lexed, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 1,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestAllocGroup(t *testing.T) {
@@ -97,7 +117,12 @@ func TestAllocGroup(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(lexed, false))
+	parsed := Parse(lexed, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 1,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestConstAlloc(t *testing.T) {
@@ -112,7 +137,12 @@ func TestConstAlloc(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(lexed, false))
+	parsed := Parse(lexed, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 1,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
 
 func TestAllocConstGroup(t *testing.T) {
@@ -146,5 +176,10 @@ func TestAllocConstGroup(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, Parse(lexed, false))
+	parsed := Parse(lexed, false)
+	expected.Lines = map[Node]int{
+		parsed.Instructions[0]: 1,
+	}
+
+	assert.Equal(t, expected, parsed)
 }
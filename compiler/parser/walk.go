@@ -34,6 +34,9 @@ func Walk(v Visitor, node Node) (r Node) {
 	case *ConstantNode:
 		// nothing to do
 	case *ConditionNode:
+		if n.Init != nil {
+			n.Init = Walk(v, n.Init)
+		}
 		n.Cond = Walk(v, n.Cond).(*OperatorNode)
 		for i, a := range n.True {
 			n.True[i] = Walk(v, a)
@@ -62,11 +65,13 @@ func Walk(v Visitor, node Node) (r Node) {
 			n.Allocs[i] = Walk(v, a).(*AllocNode)
 		}
 	case *TypeCastNode:
-		// nothing to do
+		n.Val = Walk(v, n.Val)
 	case *DefineTypeNode:
 		// nothing to do
-	case *StructLoadElementNode:
+	case *SliceTypeNode:
 		// nothing to do
+	case *StructLoadElementNode:
+		n.Struct = Walk(v, n.Struct)
 	case *LoadArrayElement:
 		n.Array = Walk(v, n.Array)
 		n.Pos = Walk(v, n.Pos)
@@ -80,6 +85,8 @@ func Walk(v Visitor, node Node) (r Node) {
 		// nothing to do
 	case *ContinueNode:
 		// nothing to do
+	case *GotoNode:
+		// nothing to do
 	case *GetReferenceNode:
 		n.Item = Walk(v, n.Item)
 	case *DereferenceNode:
@@ -122,6 +129,9 @@ func Walk(v Visitor, node Node) (r Node) {
 	case *RangeNode:
 		n.Item = Walk(v, n.Item)
 	case *SwitchNode:
+		if n.Init != nil {
+			n.Init = Walk(v, n.Init)
+		}
 		n.Item = Walk(v, n.Item)
 		for i, a := range n.Cases {
 			n.Cases[i] = Walk(v, a).(*SwitchCaseNode)
@@ -143,6 +153,13 @@ func Walk(v Visitor, node Node) (r Node) {
 		for i, a := range n.Val {
 			n.Val[i] = Walk(v, a)
 		}
+	case *LabelNode:
+		if n.Statement != nil {
+			n.Statement = Walk(v, n.Statement)
+		}
+	case *OperatorAssignNode:
+		n.Target = Walk(v, n.Target)
+		n.Val = Walk(v, n.Val)
 	case *InitializeStructNode:
 		for i, a := range n.Items {
 			n.Items[i] = Walk(v, a)
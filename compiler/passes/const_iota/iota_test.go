@@ -61,6 +61,11 @@ const (
 		},
 	}
 
+	expected.Lines = map[parser.Node]int{
+		res.Instructions[0]: 2,
+		res.Instructions[1]: 4,
+	}
+
 	assert.Equal(t, expected, res)
 }
 
@@ -105,6 +110,11 @@ const (
 		},
 	}
 
+	expected.Lines = map[parser.Node]int{
+		res.Instructions[0]: 2,
+		res.Instructions[1]: 4,
+	}
+
 	assert.Equal(t, expected, res)
 }
 
@@ -167,5 +177,10 @@ const (
 		},
 	}
 
+	expected.Lines = map[parser.Node]int{
+		res.Instructions[0]: 2,
+		res.Instructions[1]: 4,
+	}
+
 	assert.Equal(t, expected, res)
 }
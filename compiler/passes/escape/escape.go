@@ -8,34 +8,40 @@ import (
 func Escape(input *parser.FileNode) *parser.FileNode {
 	for _, ins := range input.Instructions {
 		if defFunc, ok := ins.(*parser.DefineFuncNode); ok {
+			v := &escapeVisitor{
+				allocatedVars:  map[string][]*parser.AllocNode{},
+				assignedVals:   map[string][]parser.Node{},
+				escapingVars:   map[string]struct{}{},
+				referencedVars: map[string]struct{}{},
+			}
 
-			// Name of the var mapped to their allocNode instruction index
-			allocatedVars := map[string]int{}
-			escapingVars := map[string]struct{}{}
-
-			for insIndex, ins := range defFunc.Body {
+			for _, ins := range defFunc.Body {
+				parser.Walk(v, ins)
+			}
 
-				// Find all variables allocated in this function
-				if allocIns, ok := ins.(*parser.AllocNode); ok {
-					for _, name := range allocIns.Name {
-						allocatedVars[name] = insIndex
-					}
-				}
+			for _, val := range v.escapingVals {
+				v.findEscaping(val)
+			}
 
-				// Find all variables returned from this function
-				if retIns, ok := ins.(*parser.ReturnNode); ok {
-					for _, val := range retIns.Vals {
-						findEscaping(escapingVars, val)
+			// Values assigned to variables that are not allocated in this
+			// function, such as package vars, outlive the function
+			for varName, vals := range v.assignedVals {
+				if _, ok := v.allocatedVars[varName]; !ok {
+					for _, val := range vals {
+						v.findEscaping(val)
 					}
 				}
 			}
 
 			// Mark as escaping in the AST
-			for escapingName := range escapingVars {
-				if allocIndex, ok := allocatedVars[escapingName]; ok {
-					allocIns := defFunc.Body[allocIndex].(*parser.AllocNode)
+			for escapingName := range v.escapingVars {
+				for _, allocIns := range v.allocatedVars[escapingName] {
 					allocIns.Escapes = true
-					defFunc.Body[allocIndex] = allocIns
+				}
+			}
+			for referencedName := range v.referencedVars {
+				for _, allocIns := range v.allocatedVars[referencedName] {
+					allocIns.AddressEscapes = true
 				}
 			}
 		}
@@ -44,18 +50,122 @@ func Escape(input *parser.FileNode) *parser.FileNode {
 	return input
 }
 
-func findEscaping(escapingVars map[string]struct{}, ins parser.Node) {
-	if retVariable, ok := ins.(*parser.NameNode); ok {
-		escapingVars[retVariable.Name] = struct{}{}
-		return
+// escapeVisitor finds the values in a function that can outlive it, values
+// that are returned, passed to functions, or stored in slices, arrays,
+// structs, or through pointers
+type escapeVisitor struct {
+	// Name of the var mapped to their allocNode instructions
+	allocatedVars map[string][]*parser.AllocNode
+
+	// Name of the var mapped to the values that are assigned to it
+	assignedVals map[string][]parser.Node
+
+	escapingVals []parser.Node
+	escapingVars map[string]struct{}
+
+	// Variables that escaping pointers points to
+	referencedVars map[string]struct{}
+}
+
+func (e *escapeVisitor) Visit(node parser.Node) (parser.Node, parser.Visitor) {
+	switch n := node.(type) {
+	case parser.TypeNode:
+		return node, nil
+
+	case *parser.AllocNode:
+		for i, name := range n.Name {
+			e.allocatedVars[name] = append(e.allocatedVars[name], n)
+			if len(n.Val) == len(n.Name) {
+				e.assignedVals[name] = append(e.assignedVals[name], n.Val[i])
+			}
+		}
+
+	case *parser.AssignNode:
+		if len(n.Val) != len(n.Target) {
+			break
+		}
+		for i, target := range n.Target {
+			if name, ok := target.(*parser.NameNode); ok && name.Package == "" {
+				e.assignedVals[name.Name] = append(e.assignedVals[name.Name], n.Val[i])
+
+				// The variable can be declared outside of the current loop
+				// iteration, and outlive what it points to
+				if _, isRef := n.Val[i].(*parser.GetReferenceNode); isRef {
+					e.escapingVals = append(e.escapingVals, n.Val[i])
+				}
+			} else {
+				e.escapingVals = append(e.escapingVals, n.Val[i])
+			}
+		}
+
+	case *parser.ReturnNode:
+		e.escapingVals = append(e.escapingVals, n.Vals...)
+
+	case *parser.CallNode:
+		e.escapingVals = append(e.escapingVals, n.Arguments...)
+
+	case *parser.InitializeSliceNode:
+		e.escapingVals = append(e.escapingVals, n.Items...)
+
+	case *parser.InitializeArrayNode:
+		e.escapingVals = append(e.escapingVals, n.Items...)
+
+	case *parser.InitializeStructNode:
+		for _, item := range n.Items {
+			e.escapingVals = append(e.escapingVals, item)
+		}
 	}
 
-	if retPtr, ok := ins.(*parser.GetReferenceNode); ok {
-		findEscaping(escapingVars, retPtr.Item)
+	return node, e
+}
+
+// findEscaping marks the variables and the referenced struct literals of the
+// escaping value ins
+func (e *escapeVisitor) findEscaping(ins parser.Node) {
+	switch n := ins.(type) {
+	case *parser.NameNode:
+		e.escape(n.Name)
+	case *parser.GetReferenceNode:
+		e.findReferenced(n.Item)
+	case *parser.StructLoadElementNode:
+		// A method value with a pointer receiver points to the receiver
+		e.findReferenced(n.Struct)
+	case *parser.GroupNode:
+		e.findEscaping(n.Item)
+	case *parser.TypeCastNode:
+		e.findEscaping(n.Val)
+	case *parser.TypeCastInterfaceNode:
+		e.findEscaping(n.Item)
+	}
+}
+
+// findReferenced marks the variable or the struct literal that the escaping
+// pointer ins points to, such as in &a, &a.b, &a[i], or &T{}
+func (e *escapeVisitor) findReferenced(ins parser.Node) {
+	switch n := ins.(type) {
+	case *parser.NameNode:
+		e.referencedVars[n.Name] = struct{}{}
+		e.escape(n.Name)
+	case *parser.InitializeStructNode:
+		n.Escapes = true
+	case *parser.StructLoadElementNode:
+		e.findReferenced(n.Struct)
+	case *parser.LoadArrayElement:
+		e.findReferenced(n.Array)
+	case *parser.GroupNode:
+		e.findReferenced(n.Item)
+	}
+}
+
+// escape marks the variable as escaping, and the values that are assigned to
+// it as well
+func (e *escapeVisitor) escape(varName string) {
+	if _, ok := e.escapingVars[varName]; ok {
 		return
 	}
+	e.escapingVars[varName] = struct{}{}
 
-	/*if initStruct, ok := ins.(*parser.InitializeStructNode); ok {
-		// initStruct.Items
-	}*/
+	for _, val := range e.assignedVals[varName] {
+		e.findEscaping(val)
+	}
 }
@@ -117,6 +117,121 @@ func TestEscapeNestedStruct(t *testing.T) {
 		})
 }
 
+func TestEscapesCallArgument(t *testing.T) {
+	escapeTest(t, `package main
+
+		type item struct {
+			a int
+		}
+
+		func main() {
+			var list []*item
+			a := item{a: 100}
+			b := &item{a: 200}
+			list = append(list, b)
+		}
+	`, map[string]bool{
+		"list": true,
+		"a":    false,
+		"b":    true,
+	})
+}
+
+func TestEscapesAssigned(t *testing.T) {
+	escapeTest(t, `package main
+
+		func main() *int {
+			a := 100
+			b := &a
+			c := b
+			return c
+		}
+	`, map[string]bool{
+		"a": true,
+		"b": true,
+		"c": true,
+	})
+}
+
+func TestEscapesArrayElement(t *testing.T) {
+	escapeTest(t, `package main
+
+		func main() *int {
+			a := [2]int{1, 2}
+			b := 3
+			return &a[1]
+		}
+	`, map[string]bool{
+		"a": true,
+		"b": false,
+	})
+}
+
+func TestAddressEscapes(t *testing.T) {
+	lexed := lexer.Lex(`package main
+
+		func main() (*int, int) {
+			a := [2]int{1, 2}
+			b := 3
+			return &a[1], b
+		}
+	`)
+	parsed := Escape(parser.Parse(lexed, false))
+	body := parsed.Instructions[1].(*parser.DefineFuncNode).Body
+
+	a := body[0].(*parser.AllocNode)
+	assert.True(t, a.Escapes)
+	assert.True(t, a.AddressEscapes)
+
+	b := body[1].(*parser.AllocNode)
+	assert.True(t, b.Escapes)
+	assert.False(t, b.AddressEscapes)
+}
+
+func TestEscapesMethodValue(t *testing.T) {
+	escapeTest(t, `package main
+
+		type counter struct {
+			n int
+		}
+
+		func main() func() int {
+			c := counter{n: 1}
+			f := c.get
+			return f
+		}
+	`, map[string]bool{
+		"c": true,
+		"f": true,
+	})
+}
+
+func TestEscapesStructLiteral(t *testing.T) {
+	lexed := lexer.Lex(`package main
+
+		type item struct {
+			a int
+		}
+
+		func main() {
+			var list []*item
+			for i := 0; i < 3; i++ {
+				list = append(list, &item{a: i})
+			}
+			local := &item{a: 100}
+		}
+	`)
+	parsed := Escape(parser.Parse(lexed, false))
+	body := parsed.Instructions[2].(*parser.DefineFuncNode).Body
+
+	appendCall := body[1].(*parser.ForNode).Block[0].(*parser.AssignNode).Val[0].(*parser.CallNode)
+	appended := appendCall.Arguments[1].(*parser.GetReferenceNode).Item.(*parser.InitializeStructNode)
+	assert.True(t, appended.Escapes)
+
+	local := body[2].(*parser.AllocNode).Val[0].(*parser.GetReferenceNode).Item.(*parser.InitializeStructNode)
+	assert.False(t, local.Escapes)
+}
+
 /*
 TODO: Implement feature so that this case can pass
 f can be stack allocated, but f.bar needs to allocqated on the heap
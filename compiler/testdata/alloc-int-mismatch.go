@@ -0,0 +1,11 @@
+package main
+
+import "external"
+
+func main() {
+	var a int64 = -1
+
+	// compile panic: cannot use int64 value as uint64 value in variable declaration
+	var u uint64 = a
+	external.Printf("%d\n", u)
+}
@@ -19,4 +19,10 @@ func main() {
 
 	// 14
 	external.Printf("%d\n",  2 + 3 * 4)
+
+	// 17
+	external.Printf("%d\n",  f1.a * int64(4) + 5)
+
+	// 11
+	external.Printf("%d\n",  f1.a * (2) + 5)
 }
\ No newline at end of file
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"external"
+	"fmt"
+	"sync/atomic"
+)
+
+const maxUint64 = 1<<64 - 1
+
+var counter int64
+var total atomic.Uint32
+
+func worker(n int) {
+	for i := 0; i < 10000; i++ {
+		atomic.AddInt64(&counter, 1)
+		total.Add(1)
+	}
+}
+
+func main() {
+	var x int32 = 5
+	fmt.Println(atomic.AddInt32(&x, 3), atomic.LoadInt32(&x))                               // 8 8
+	fmt.Println(atomic.CompareAndSwapInt32(&x, 7, 1), atomic.CompareAndSwapInt32(&x, 8, 1)) // false true
+	fmt.Println(atomic.SwapInt32(&x, 10), x)                                                // 1 10
+	atomic.StoreInt32(&x, -2)
+	fmt.Println(atomic.LoadInt32(&x)) // -2
+
+	var u uint64
+	atomic.AddUint64(&u, maxUint64)
+	fmt.Println(u) // 18446744073709551615
+
+	var b atomic.Bool
+	fmt.Println(b.Load(), b.Swap(true), b.Load(), b.CompareAndSwap(true, false)) // false false true true
+
+	var threads []int
+	for i := 0; i < 4; i++ {
+		threads = append(threads, external.ThreadCreate(worker, i))
+	}
+	for _, t := range threads {
+		external.ThreadJoin(t)
+	}
+	fmt.Println(atomic.LoadInt64(&counter), total.Load()) // 40000 40000
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const allOnes = 1<<64 - 1
+
+func main() {
+	fmt.Println(bits.OnesCount(255), bits.OnesCount8(7), bits.OnesCount64(allOnes))      // 8 3 64
+	fmt.Println(bits.LeadingZeros(1), bits.LeadingZeros8(1), bits.LeadingZeros32(0))     // 63 7 32
+	fmt.Println(bits.TrailingZeros(8), bits.TrailingZeros16(0), bits.TrailingZeros64(0)) // 3 16 64
+	fmt.Println(bits.Len(255), bits.Len8(0), bits.Len32(65536))                          // 8 0 17
+	fmt.Println(bits.RotateLeft32(1, -1), bits.RotateLeft64(3, 62))                      // 2147483648 13835058055282163712
+	fmt.Println(bits.Reverse8(1), bits.ReverseBytes16(258), bits.ReverseBytes32(1))      // 128 513 16777216
+}
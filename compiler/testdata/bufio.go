@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+type chunkReader struct {
+	data  string
+	pos   int
+	chunk int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.EOF
+	}
+	n := 0
+	for n < len(p) {
+		if n == r.chunk {
+			break
+		}
+		if r.pos == len(r.data) {
+			break
+		}
+		p[n] = r.data[r.pos]
+		n++
+		r.pos++
+	}
+	return n, nil
+}
+
+func main() {
+	s := bufio.NewScanner(&chunkReader{data: "first line\nsecond\r\n\nlast", chunk: 3})
+	for s.Scan() {
+		fmt.Println("line:", s.Text(), len(s.Bytes()))
+	}
+	// line: first line 10
+	// line: second 6
+	// line:  0
+	// line: last 4
+	fmt.Println(s.Err())
+	// <nil>
+
+	w := bufio.NewScanner(&chunkReader{data: "  alpha beta\n\tgamma  ", chunk: 2})
+	w.Split(bufio.ScanWords)
+	count := 0
+	for w.Scan() {
+		fmt.Println("word:", w.Text())
+		count++
+	}
+	// word: alpha
+	// word: beta
+	// word: gamma
+	fmt.Println(count)
+	// 3
+
+	b := bufio.NewScanner(&chunkReader{data: "xyz", chunk: 1})
+	b.Split(bufio.ScanBytes)
+	for b.Scan() {
+		fmt.Println(b.Text())
+	}
+	// x
+	// y
+	// z
+
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		fmt.Println("unexpected stdin", in.Text())
+	}
+	fmt.Println("stdin done", in.Err())
+	// stdin done <nil>
+
+	r := bufio.NewReader(&chunkReader{data: "a,bc,def", chunk: 2})
+	for {
+		s, err := r.ReadString(byte(44))
+		fmt.Println(s, err)
+		if err != nil {
+			break
+		}
+	}
+	// a, <nil>
+	// bc, <nil>
+	// def EOF
+
+	bw := bufio.NewWriterSize(os.Stdout, 16)
+	bw.WriteString("buffered ")
+	fmt.Println(bw.Buffered())
+	// 9
+	bw.WriteString("writer output that is long\n")
+	bw.WriteByte(byte(33))
+	bw.WriteByte(byte(10))
+	fmt.Println(bw.Buffered())
+	bw.Flush()
+	fmt.Println(bw.Buffered())
+	// buffered writer output that is long
+	// 2
+	// !
+	// 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+func main() {
+	a := []byte("key=value=x")
+	fmt.Println(bytes.Equal(a, []byte("key=value=x")), bytes.Equal(a, []byte("key"))) // true false
+	fmt.Println(bytes.Index(a, []byte("=")), bytes.LastIndex(a, []byte("=")))         // 3 9
+	fmt.Println(bytes.Index(a, []byte("zz")), bytes.IndexByte(a, 118))                // -1 4
+
+	parts := bytes.Split(a, []byte("="))
+	fmt.Printf("%q\n", parts)                               // ["key" "value" "x"]
+	fmt.Printf("%q\n", bytes.Split([]byte("héj"), nil))     // ["h" "é" "j"]
+	fmt.Println(string(bytes.Join(parts, []byte(", "))))    // key, value, x
+	fmt.Printf("%q\n", bytes.Fields([]byte(" a b\t c ")))   // ["a" "b" "c"]
+	fmt.Printf("%q\n", bytes.TrimSpace([]byte("\t hi \n"))) // "hi"
+	fmt.Printf("%q\n", bytes.Fields([]byte("Ņ a\t\tb")))    // ["Ņ" "a" "b"]
+
+	fmt.Println(bytes.Compare([]byte("a"), []byte("b")), bytes.Compare([]byte("b"), []byte("a")), bytes.Compare(a, a)) // -1 1 0
+	fmt.Println(bytes.Count([]byte("cheese"), []byte("e")), bytes.Contains(a, []byte("val")))                          // 3 true
+
+	var buf bytes.Buffer
+	buf.WriteString("hello ")
+	buf.Write([]byte("world"))
+	buf.WriteByte(10)
+	buf.WriteRune(8364)
+	fmt.Println(buf.Len()) // 15
+
+	line, err := buf.ReadString(10)
+	fmt.Printf("%q %v\n", line, err) // "hello world\n" <nil>
+	r, size, _ := buf.ReadRune()
+	fmt.Println(r, size) // 8364 3
+	_, err = buf.ReadByte()
+	fmt.Println(err == io.EOF) // true
+
+	b := bytes.NewBufferString("abcdef")
+	p := make([]byte, 4)
+	n, _ := b.Read(p)
+	fmt.Println(n, string(p[:n]), b.String()) // 4 abcd ef
+	b.Grow(10)
+	b.Truncate(1)
+	fmt.Println(b.String(), b.Len()) // e 1
+}
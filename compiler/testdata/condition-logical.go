@@ -0,0 +1,51 @@
+package main
+
+import "fmt"
+
+type point struct {
+	x int
+}
+
+func check(name string, v bool) bool {
+	fmt.Println("check", name)
+	return v
+}
+
+func between(a, b, c int) bool {
+	return a < b && b < c
+}
+
+func main() {
+	var p *point
+	if p != nil && p.x > 0 {
+		fmt.Println("unreachable")
+	}
+	p = &point{x: 3}
+	if p != nil && p.x > 0 {
+		fmt.Println("x", p.x) // x 3
+	}
+
+	a := check("a", false) && check("b", true) // check a
+	b := check("c", true) || check("d", true)  // check c
+	// check e
+	// check f
+	// check g
+	c := check("e", false) || check("f", true) && check("g", false)
+	fmt.Println(a, b, c) // false true false
+
+	x, y := 2, 3
+	fmt.Println(x+y == 5, -x == -2, x*y+1 == 7, x&1 == 0, x+y*2 == 8)    // true true true true true
+	fmt.Println(!(x == 2) || y == 3, between(1, 2, 3), between(3, 2, 1)) // true true false
+
+	var ip *int
+	if ip == nil || *ip == 0 {
+		fmt.Println("nil or zero") // nil or zero
+	}
+
+	for i := 0; i < 10 && i*i < 5; i++ {
+		fmt.Println(i)
+	}
+	// 0
+	// 1
+	// 2
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"external"
+	"fmt"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type PathError struct {
+	Path string
+}
+
+func (e *PathError) Error() string {
+	return "bad path " + e.Path
+}
+
+func find(key int) (int, error) {
+	if key == 1 {
+		return 10, nil
+	}
+	if key == 2 {
+		return 0, &PathError{Path: "/tmp"}
+	}
+	return 0, ErrNotFound
+}
+
+func main() {
+	v, err := find(1)
+	if err == nil {
+		external.Printf("found %d\n", v) // found 10
+	}
+
+	_, err = find(3)
+	if err != nil {
+		external.Printf("%s\n", err.Error()) // not found
+	}
+	if err == ErrNotFound {
+		external.Printf("is ErrNotFound\n") // is ErrNotFound
+	}
+	if errors.New("not found") != ErrNotFound {
+		external.Printf("errors are distinct\n") // errors are distinct
+	}
+
+	wrapped := fmt.Errorf("lookup %d: %w", 3, err)
+	external.Printf("%s\n", wrapped.Error()) // lookup 3: not found
+	if errors.Is(wrapped, ErrNotFound) {
+		external.Printf("wrapped is ErrNotFound\n") // wrapped is ErrNotFound
+	}
+	if errors.Unwrap(wrapped) == err {
+		external.Printf("unwrapped\n") // unwrapped
+	}
+	if errors.Unwrap(err) == nil {
+		external.Printf("nothing to unwrap\n") // nothing to unwrap
+	}
+
+	_, err = find(2)
+	var pathErr *PathError
+	if errors.As(fmt.Errorf("open: %w", err), &pathErr) {
+		external.Printf("as %s\n", pathErr.Path) // as /tmp
+	}
+	if errors.Is(err, ErrNotFound) == false {
+		external.Printf("%s\n", err.Error()) // bad path /tmp
+	}
+	if errors.As(ErrNotFound, &pathErr) == false {
+		external.Printf("not a PathError\n") // not a PathError
+	}
+}
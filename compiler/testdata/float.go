@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type vec struct {
+	X float64
+	Y float32
+}
+
+func scale(v vec, f float64) vec {
+	return vec{X: v.X * f, Y: v.Y * float32(f)}
+}
+
+func main() {
+	a := 1.5
+	b := 2.0
+	fmt.Println(a+b, a-b, a*b, a/b, -a)  // 3.5 -0.5 3 0.75 -1.5
+	fmt.Println(a < b, a >= b, a == 1.5) // true false true
+
+	var c float64 = 1
+	c = c / 3
+	fmt.Println(c, 1e21, 1e-7, 100.0) // 0.3333333333333333 1e+21 1e-07 100
+
+	var f32 float32 = 0.1
+	fmt.Println(f32, float64(f32) == 0.1) // 0.1 false
+
+	fmt.Println(int(-2.9), int64(a*10), float64(7)/2, uint8(250.0)) // -2 15 3.5 250
+	fmt.Println(scale(vec{X: 1, Y: 2}, 1.5))                        // {1.5 3}
+
+	fmt.Printf("%f|%.2f|%8.3f|%-8.1f|%08.3f|%+.1f\n", a, a, a, a, -a, a) // 1.500000|1.50|   1.500|1.5     |-001.500|+1.5
+	fmt.Printf("%e|%E|%.3g|%g|%v\n", 123456.789, 0.000123, 123456.789, 1e6, f32)
+	// 1.234568e+05|1.230000E-04|1.23e+05|1e+06|0.1
+
+	fmt.Println(strconv.FormatFloat(3.14159, 102, 2, 64), strconv.FormatFloat(1.0/3, 103, -1, 32)) // 3.14 0.33333334
+	v, err := strconv.ParseFloat("-12.5e-1", 64)
+	fmt.Println(v, err) // -1.25 <nil>
+	v, err = strconv.ParseFloat("1e500", 64)
+	fmt.Println(v, err) // +Inf strconv.ParseFloat: parsing "1e500": value out of range
+	v, err = strconv.ParseFloat("1.5x", 64)
+	fmt.Println(v, err) // 0 strconv.ParseFloat: parsing "1.5x": invalid syntax
+}
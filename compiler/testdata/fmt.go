@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Point struct {
+	X int
+	Y int
+}
+
+type Celsius struct {
+	deg int
+}
+
+func (c Celsius) String() string {
+	return fmt.Sprintf("%d°C", c.deg)
+}
+
+type buffer struct {
+	data string
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	b.data = b.data + string(p)
+	return len(p), nil
+}
+
+func main() {
+	fmt.Println("hello", 5, true) // hello 5 true
+	fmt.Print("a", 1, 2, "b\n")   // a1 2b
+
+	fmt.Printf("%d|%5d|%-5d|%05d|%+d\n", 42, 42, 42, -42, 5) // 42|   42|42   |-0042|+5
+	fmt.Printf("%x|%X|%#x|%o|%b\n", 255, 255, 255, 8, 5)     // ff|FF|0xff|10|101
+	fmt.Printf("%.3d|%6.2x|\n", 7, 1)                        // 007|    01|
+
+	fmt.Printf("%s|%6s|%-6s|%.2s|\n", "go", "go", "go", "golang") // go|    go|go    |go|
+	fmt.Printf("%q %x % x\n", "a\"b\n", "hi", []byte("hi"))       // "a\"b\n" 6869 68 69
+	fmt.Printf("%t %v %c\n", true, false, 233)                    // true false é
+	fmt.Printf("%.2s|%4s|%q|%c\n", "héj", "é€", 8364, 55296)      // hé|  é€|'€'|�
+
+	p := Point{X: 1, Y: 2}
+	fmt.Printf("%v %+v %v\n", p, p, &p)              // {1 2} {X:1 Y:2} &{1 2}
+	fmt.Printf("%v %d\n", []int{1, 2}, [2]int{3, 4}) // [1 2] [3 4]
+	fmt.Printf("%T %T %T\n", 1, "s", &p)             // int string *main.Point
+
+	fmt.Printf("%v %s|%8v|\n", Celsius{deg: 20}, Celsius{deg: 3}, Celsius{deg: 1}) // 20°C 3°C|     1°C|
+	fmt.Printf("%v\n", errors.New("oops"))                                         // oops
+
+	var np *Point
+	var nothing interface{}
+	fmt.Println(np, nothing, nil)                             // <nil> <nil> <nil>
+	fmt.Printf("%p %v\n", np, len(fmt.Sprintf("%p", &p)) > 2) // 0x0 true
+
+	fmt.Printf("%d %d\n", 1)              // 1 %!d(MISSING)
+	fmt.Printf("%d %z\n", "a", 1)         // %!d(string=a) %!z(int=1)
+	fmt.Printf("%*d|%-*d|\n", 4, 1, 3, 2) //    1|2  |
+
+	s := fmt.Sprintln("x", 1)
+	fmt.Print(s) // x 1
+
+	b := &buffer{}
+	n, err := fmt.Fprintf(b, "%s=%d", "n", 10)
+	if err == nil {
+		fmt.Println(b.data, n) // n=10 4
+	}
+}
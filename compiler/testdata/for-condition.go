@@ -0,0 +1,28 @@
+package main
+
+import "external"
+
+func countdown(n int) {
+	for n > 0 {
+		external.Printf("%d\n", n)
+		n--
+	}
+}
+
+func main() {
+	countdown(3)
+
+	i := 0
+	for {
+		i++
+		if i == 5 {
+			break
+		}
+	}
+	external.Printf("%d\n", i)
+}
+
+// 3
+// 2
+// 1
+// 5
@@ -0,0 +1,14 @@
+package main
+
+import "fmt"
+
+func main() {
+	n := 1
+
+	// compile panic: goto L jumps into block
+	goto L
+	if n > 0 {
+	L:
+		fmt.Println(n)
+	}
+}
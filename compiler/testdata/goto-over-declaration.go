@@ -0,0 +1,11 @@
+package main
+
+import "fmt"
+
+func main() {
+	// compile panic: goto L jumps over variable declaration
+	goto L
+	x := 5
+L:
+	fmt.Println(x)
+}
@@ -0,0 +1,21 @@
+package main
+
+import "fmt"
+
+func div(a, b int) int {
+	return a % b
+}
+
+func main() {
+	fmt.Println(div(7, 2))
+	fmt.Println(div(7, 0))
+}
+
+// 1
+// runtime panic: integer divide by zero
+//
+// goroutine 1 [running]:
+// main.div()
+// 	testdata/int-divide-by-zero.go:6
+// main.main()
+// 	testdata/int-divide-by-zero.go:11
@@ -0,0 +1,49 @@
+package main
+
+import "fmt"
+
+func fnv32a(s string) uint32 {
+	var h uint32 = 2166136261
+	for i := 0; i < len(s); i++ {
+		h = h ^ uint32(s[i])
+		h = h * 16777619
+	}
+	return h
+}
+
+func main() {
+	a, b := -7, 3
+
+	// -1 1 -2 -1
+	fmt.Println(a%b, 7%b, a/b, a%-b)
+
+	var u uint32 = 4000000000
+	var v uint32 = 7
+	// 3 571428571 250000000
+	fmt.Println(u%v, u/v, u>>4)
+
+	var s int32 = -64
+	// -16 -1 0
+	fmt.Println(s>>2, s>>40, s<<40)
+
+	var n uint = 70
+	x := 1
+	// 0 0 -1 -1
+	fmt.Println(x<<n, x>>n, -8>>n, a>>n)
+
+	var c uint8 = 3
+	// 8 -8 463129088
+	fmt.Println(x<<c, -64>>c, uint32(u<<1)>>c)
+
+	var min int8 = -128
+	m := int8(-1)
+	// -128 0
+	fmt.Println(min/m, min%m)
+
+	var w uint8 = 200
+	// 200 200 200 -56
+	fmt.Println(int(w), int64(w), uint16(w), int8(w))
+
+	// 2166136261 1335831723
+	fmt.Println(fnv32a(""), fnv32a("hello"))
+}
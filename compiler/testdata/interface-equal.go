@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+type point struct {
+	name string
+	x    float64
+}
+
+type codeError struct {
+	code string
+}
+
+func (e codeError) Error() string {
+	return "code " + e.code
+}
+
+type wrapped struct {
+	err error
+}
+
+func (w *wrapped) Error() string {
+	return "wrapped"
+}
+
+func (w *wrapped) Unwrap() error {
+	return w.err
+}
+
+func join(a, b string) string {
+	return a + b
+}
+
+func main() {
+	var a interface{} = join("ab", "c")
+	var b interface{} = "abc"
+	var c interface{} = "abd"
+	fmt.Println(a == b, a == c) // true false
+
+	var zero float64
+	var f interface{} = zero
+	var negZero interface{} = -zero
+	nan := zero / zero
+	var n interface{} = nan
+	fmt.Println(f == negZero, n == n) // true false
+
+	var p interface{} = point{name: join("o", "rigin"), x: 1.5}
+	var q interface{} = point{name: "origin", x: 1.5}
+	var r interface{} = point{name: "origin", x: 2.5}
+	fmt.Println(p == q, p == r) // true false
+
+	var arr interface{} = [2]string{join("a", "b"), "c"}
+	var arr2 interface{} = [2]string{"ab", "c"}
+	fmt.Println(arr == arr2) // true
+
+	var err error = &wrapped{err: codeError{code: join("4", "04")}}
+	fmt.Println(errors.Is(err, codeError{code: "404"})) // true
+	fmt.Println(errors.Is(err, codeError{code: "500"})) // false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type recorder struct {
+	b strings.Builder
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.b.Write(p)
+	return len(p), nil
+}
+
+type failWriter struct{}
+
+func (f *failWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func main() {
+	path := "/tmp/tre-testdata-io.txt"
+	os.WriteFile(path, []byte("abc\ndef\n"), 420)
+
+	f, _ := os.Open(path)
+	data, err := io.ReadAll(f)
+	f.Close()
+	fmt.Println(len(data), err)
+	// 8 <nil>
+
+	rec := &recorder{}
+	w := io.MultiWriter(os.Stdout, rec)
+	f, _ = os.Open(path)
+	n, err := io.Copy(w, f)
+	f.Close()
+	// abc
+	// def
+	fmt.Println(n, err, rec.b.Len())
+	// 8 <nil> 8
+
+	io.WriteString(w, "ws\n")
+	// ws
+	fmt.Println(rec.b.Len())
+	// 11
+
+	f, _ = os.Open(path)
+	n, err = io.Copy(&failWriter{}, f)
+	f.Close()
+	fmt.Println(n, err)
+	// 0 short write
+
+	var c io.Closer = f
+	fmt.Println(c.Close())
+	// close /tmp/tre-testdata-io.txt: file already closed
+
+	os.Remove(path)
+}
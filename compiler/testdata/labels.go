@@ -0,0 +1,142 @@
+package main
+
+import "external"
+
+func lookup(key string) (int, bool) {
+	if key == "a" {
+		return 1, true
+	}
+	return 0, false
+}
+
+func next(i int) int {
+	return i + 1
+}
+
+func main() {
+	// found 1
+	if v, ok := lookup("a"); ok {
+		external.Printf("found %d\n", v)
+	}
+
+	// missing 0
+	if v, ok := lookup("b"); ok {
+		external.Printf("found %d\n", v)
+	} else {
+		external.Printf("missing %d\n", v)
+	}
+
+	// big 10
+	if x := 5; x > 10 {
+		external.Printf("small %d\n", x)
+	} else if y := x * 2; y >= 10 {
+		external.Printf("big %d\n", y)
+	}
+
+	// two
+	switch x := next(1); x {
+	case 1:
+		external.Printf("one\n")
+	case 2:
+		external.Printf("two\n")
+	}
+
+	// 0 0
+	// 0 1
+	// 1 0
+	// 2 0
+	// 2 1
+outer:
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if i == 1 && j == 1 {
+				continue outer
+			}
+			if j == 2 {
+				break
+			}
+			external.Printf("%d %d\n", i, j)
+		}
+	}
+
+	// found at 2 3
+	found := false
+search:
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			if i*j == 6 {
+				external.Printf("found at %d %d\n", i, j)
+				found = true
+				break search
+			}
+		}
+	}
+	// true
+	if found {
+		external.Printf("true\n")
+	}
+
+	// 0 1 2 3 after
+	for i := 0; i < 10; i++ {
+		switch i {
+		case 4:
+			break
+		default:
+			external.Printf("%d ", i)
+			continue
+		}
+		break
+	}
+	external.Printf("after\n")
+
+	// slice done
+loop:
+	for _, s := range []string{"a", "b", "c"} {
+		switch s {
+		case "b":
+			break loop
+		}
+	}
+	external.Printf("slice done\n")
+
+	// 1
+	// 2
+	// 3
+	xs := []int{}
+	xs = append(xs, 0)
+again:
+	if len(xs) < 4 {
+		external.Printf("%d\n", len(xs))
+		xs = append(xs, 0)
+		goto again
+	}
+
+	// skipped
+	goto end
+	external.Printf("not printed\n")
+end:
+	external.Printf("skipped\n")
+
+	// 0
+	// 2
+	// 4
+	i := 0
+back:
+	double := i * 2
+	external.Printf("%d\n", double)
+	if i < 2 {
+		i++
+		goto back
+	}
+
+	// 0
+	// out
+	for k := 0; k < 3; k++ {
+		if k == 1 {
+			goto out
+		}
+		external.Printf("%d\n", k)
+	}
+out:
+	external.Printf("out\n")
+}
@@ -0,0 +1,43 @@
+package main
+
+import "fmt"
+
+/*
+ * Flags, as they are written in a C header
+ */
+const (
+	flagA = 0x01
+	flagB = 0x_F0 /* upper nibble */
+	perm  = 0o755
+	mode  = 0644
+	mask  = 0b1010_1010
+	large = 1_000_000
+)
+
+const text = `first line
+	"quoted" \n is not escaped
+last line`
+
+func main() {
+	// 1 240 493 420 170 1000000
+	fmt.Println(flagA, flagB, perm, mode, mask, large)
+
+	var all uint64 = 0xFFFF_FFFF_FFFF_FFFF
+	// 18446744073709551615 15
+	fmt.Println(all, all>>60)
+
+	x := 0xE + 1 /* a comment
+	that spans
+	multiple lines */
+	// 15 10.5 5e+09
+	fmt.Println(x, 1_0.5, 0.5e1_0)
+
+	// first line
+	// 	"quoted" \n is not escaped
+	// last line
+	fmt.Println(text)
+
+	var arr [0x4]int
+	// 0 a\b 4
+	fmt.Println(len(``), `a\b`, len(arr))
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+func main() {
+	fmt.Println(math.MaxInt64, math.MinInt64, math.MaxInt8, math.MinInt8) // 9223372036854775807 -9223372036854775808 127 -128
+	var u uint64 = math.MaxUint64
+	var u32 uint32 = math.MaxUint32
+	fmt.Println(u, u32) // 18446744073709551615 4294967295
+
+	fmt.Println(math.Pi, math.Sqrt2, math.MaxFloat64)                                  // 3.141592653589793 1.4142135623730951 1.7976931348623157e+308
+	fmt.Println(math.Abs(-2.5), math.Sqrt(2), math.Pow(2, 10))                         // 2.5 1.4142135623730951 1024
+	fmt.Println(math.Floor(-1.5), math.Ceil(-1.5), math.Round(2.5), math.Trunc(-2.7))  // -2 -1 3 -2
+	fmt.Println(math.Min(1, -3), math.Max(1, -3), math.Min(0, math.Copysign(0, -1)))   // -3 1 -0
+	fmt.Println(math.Inf(1), math.Inf(-1), math.NaN())                                 // +Inf -Inf NaN
+	fmt.Println(math.IsNaN(math.NaN()), math.IsInf(math.Inf(-1), 1), math.Signbit(-1)) // true false true
+	fmt.Println(math.Max(math.NaN(), 1), math.Min(math.Inf(-1), math.NaN()))           // NaN -Inf
+	fmt.Printf("%.4f %.4f\n", math.Log(math.E), math.Exp(1))                           // 1.0000 2.7183
+	fmt.Println(math.Float64bits(1.0), math.Float64frombits(4611686018427387904))      // 4607182418800017408 2
+}
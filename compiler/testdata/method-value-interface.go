@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"external"
+)
+
+type getter interface {
+	Get() int
+	Add(d int) int
+	DivMod(d int) (int, int)
+}
+
+type counter struct {
+	n int
+}
+
+func (c counter) Get() int {
+	return c.n
+}
+
+func (c *counter) Add(d int) int {
+	c.n = c.n + d
+	return c.n
+}
+
+func (c counter) DivMod(d int) (int, int) {
+	return c.n / d, c.n % d
+}
+
+type box struct {
+	n int
+}
+
+func (b *box) Get() int {
+	return b.n * 10
+}
+
+func (b *box) Add(d int) int {
+	b.n = b.n + d
+	return b.n * 10
+}
+
+func (b *box) DivMod(d int) (int, int) {
+	return b.n, d
+}
+
+func adder(g getter) func(int) int {
+	return g.Add
+}
+
+func apply(f func(int) int, v int) int {
+	return f(v)
+}
+
+func main() {
+	c := &counter{n: 10}
+	var g getter = c
+
+	get := g.Get
+	add := g.Add
+	// 10 15 15
+	external.Printf("%d %d %d\n", get(), add(5), c.n)
+
+	divmod := g.DivMod
+	q, r := divmod(4)
+	// 3 3
+	external.Printf("%d %d\n", q, r)
+
+	b := &box{n: 1}
+	g = b
+	// 15 10
+	external.Printf("%d %d\n", get(), g.Get())
+
+	add2 := adder(g)
+	// 30 3
+	external.Printf("%d %d\n", apply(add2, 2), b.n)
+
+	err := errors.New("failed")
+	msg := err.Error
+	// failed
+	external.Printf("%s\n", msg())
+}
@@ -0,0 +1,157 @@
+package main
+
+import "external"
+
+type counter struct {
+	n int
+}
+
+func (c counter) get() int {
+	return c.n
+}
+
+func (c *counter) add(d int) int {
+	c.n = c.n + d
+	return c.n
+}
+
+func (c counter) divmod(d int) (int, int) {
+	return c.n / d, c.n % d
+}
+
+type celsius int
+
+func (c celsius) String() string {
+	if c < 0 {
+		return "freezing"
+	}
+	return "warm"
+}
+
+type handler struct {
+	name string
+	fn   func(int) int
+}
+
+func call(f func(int) int, v int) int {
+	return f(v)
+}
+
+func newAdd(n int) func(int) int {
+	c := &counter{n: n}
+	return c.add
+}
+
+func newAddVar(n int) func(int) int {
+	c := counter{n: n}
+	f := c.add
+	return f
+}
+
+func newAddValue(n int) func(int) int {
+	c := counter{n: n}
+	return c.add
+}
+
+func main() {
+	c := counter{n: 10}
+
+	get := c.get
+	c.n = 20
+	// 10 20
+	external.Printf("%d %d\n", get(), c.get())
+
+	add := c.add
+	add(5)
+	// 25 25
+	external.Printf("%d %d\n", add(0), c.n)
+
+	p := &counter{n: 100}
+	addP := p.add
+	// 101 101
+	external.Printf("%d %d\n", addP(1), p.n)
+	getP := p.get
+	p.n = 0
+	// 101
+	external.Printf("%d\n", getP())
+
+	dm := c.divmod
+	q, r := dm(4)
+	// 6 1
+	external.Printf("%d %d\n", q, r)
+
+	var temp celsius = -5
+	str := temp.String
+	temp = 5
+	// freezing warm
+	external.Printf("%s %s\n", str(), temp.String())
+
+	a := &counter{n: 1}
+	b := &counter{n: 1000}
+	handlers := []handler{
+		handler{name: "a", fn: a.add},
+		handler{name: "b", fn: b.add},
+		handler{name: "double", fn: func(v int) int {
+			return v * 2
+		}},
+	}
+	for _, h := range handlers {
+		// a 3
+		// b 1002
+		// double 4
+		external.Printf("%s %d\n", h.name, h.fn(2))
+	}
+	// 3 1002
+	external.Printf("%d %d\n", a.n, b.n)
+
+	// 13
+	external.Printf("%d\n", call(a.add, 10))
+
+	getE := counter.get
+	// 42
+	external.Printf("%d\n", getE(counter{n: 42}))
+
+	addE := (*counter).add
+	// 18
+	external.Printf("%d\n", addE(a, 5))
+
+	getPE := (*counter).get
+	// 18
+	external.Printf("%d\n", getPE(a))
+
+	divmodE := counter.divmod
+	q, r = divmodE(counter{n: 17}, 5)
+	// 3 2
+	external.Printf("%d %d\n", q, r)
+
+	// 7
+	external.Printf("%d\n", counter.get(counter{n: 7}))
+
+	var f func(int) int
+	// true
+	if f == nil {
+		external.Printf("true\n")
+	}
+	f = b.add
+	// false
+	if f == nil {
+		external.Printf("true\n")
+	} else {
+		external.Printf("false\n")
+	}
+
+	add5 := newAdd(5)
+	add6 := newAdd(6)
+	// 5 6
+	external.Printf("%d %d\n", add5(0), add6(0))
+
+	add5 = newAddVar(5)
+	add6 = newAddVar(6)
+	// 5 6
+	external.Printf("%d %d\n", add5(0), add6(0))
+
+	add5 = newAddValue(5)
+	add6 = newAddValue(6)
+	// 6 8
+	external.Printf("%d %d\n", add5(1), add6(2))
+}
@@ -0,0 +1,63 @@
+package main
+
+import "external"
+
+type myErr struct{}
+
+func (e *myErr) Error() string {
+	return "myErr"
+}
+
+type node struct {
+	next *myErr
+	err  error
+}
+
+func noItems() []int {
+	return nil
+}
+
+func main() {
+	var p *node
+	var s []int
+	var f func()
+	var err error
+
+	if p == nil {
+		external.Printf("nil pointer\n") // nil pointer
+	}
+	if s == nil {
+		external.Printf("nil slice %d %d\n", len(s), cap(s)) // nil slice 0 0
+	}
+	if f == nil {
+		external.Printf("nil func\n") // nil func
+	}
+	if err == nil {
+		external.Printf("nil interface\n") // nil interface
+	}
+
+	s = append(s, 1)
+	if s != nil {
+		external.Printf("appended %d\n", s[0]) // appended 1
+	}
+	s = nil
+	if int(len(s)) == 0 {
+		external.Printf("assigned nil\n") // assigned nil
+	}
+	if noItems() == nil {
+		external.Printf("returned nil\n") // returned nil
+	}
+
+	n := &node{next: nil, err: nil}
+	if n.next == nil {
+		if n.err == nil {
+			external.Printf("nil fields\n") // nil fields
+		}
+	}
+
+	var e *myErr
+	err = e
+	if err != nil {
+		external.Printf("non-nil interface\n") // non-nil interface
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import "fmt"
+
+type item struct {
+	f     int
+	flags uint8
+}
+
+type set struct {
+	items []uint64
+}
+
+var calls int
+
+func next() int {
+	calls++
+	return calls - 1
+}
+
+func main() {
+	a := []item{item{f: 1}, item{f: 2}}
+	i := 1
+	a[i].f += 1
+	a[0].flags |= 6
+	a[0].flags &^= 2
+	// 1 3 4
+	fmt.Println(a[0].f, a[1].f, a[0].flags)
+
+	x := 3
+	p := &x
+	*p <<= 2
+	*p -= 1
+	*p %= 7
+	// 4
+	fmt.Println(x)
+
+	s := &set{items: []uint64{0, 0}}
+	var mask uint64 = 1 << 40
+	j := 1
+	s.items[j] |= mask
+	s.items[j] >>= 8
+	s.items[j] ^= 1
+	// 0 4294967297
+	fmt.Println(s.items[0], s.items[1])
+
+	nums := []int{10, 20, 30}
+	nums[next()] *= 3
+	nums[next()] /= 4
+	// 30 5 30 2
+	fmt.Println(nums[0], nums[1], nums[2], calls)
+
+	str := "a"
+	str += "b"
+	strs := []string{"x"}
+	strs[0] += str
+	// ab xab
+	fmt.Println(str, strs[0])
+
+	var n uint = 3
+	y := 1
+	y <<= n
+	f := 1.5
+	f *= 2
+	// 8 3
+	fmt.Println(y, f)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func readAll(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	res := ""
+	buf := make([]byte, 4)
+	for {
+		n, err := f.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		res = res + string(buf[:n])
+	}
+	return res, f.Close()
+}
+
+func main() {
+	fmt.Println(len(os.Args)) // 1
+
+	_, found := os.LookupEnv("TRE_OS_TEST_MISSING")
+	fmt.Println(found, len(os.Getenv("TRE_OS_TEST_MISSING"))) // false 0
+
+	path := "/tmp/tre-os-test.txt"
+
+	f, err := os.Create(path)
+	fmt.Println(err) // <nil>
+	fmt.Fprintf(f, "hello %d\n", 1)
+	f.WriteString("world\n")
+	fmt.Println(f.Close(), f.Close()) // <nil> close /tmp/tre-os-test.txt: file already closed
+
+	s, err := readAll(path)
+	fmt.Print(s) // hello 1
+	// world
+	fmt.Println(err) // <nil>
+
+	err = os.WriteFile(path, []byte("replaced"), 420)
+	data, err := os.ReadFile(path)
+	fmt.Println(string(data), len(data), err) // replaced 8 <nil>
+
+	fmt.Println(os.Remove(path)) // <nil>
+
+	_, err = os.Open(path)
+	fmt.Println(err)                // open /tmp/tre-os-test.txt: no such file or directory
+	fmt.Println(os.IsNotExist(err)) // true
+
+	os.Stdout.Write([]byte("stdout\n")) // stdout
+	os.Exit(1)
+	fmt.Println("unreachable")
+}
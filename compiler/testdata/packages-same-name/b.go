@@ -0,0 +1,10 @@
+package main
+
+import (
+	"b/util"
+	"external"
+)
+
+func printB() {
+	external.Printf("%s %d\n", util.Name(), util.Value)
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"a/util"
+	"external"
+)
+
+// a 1
+// b 2
+
+func main() {
+	external.Printf("%s %d\n", util.Name(), util.Value)
+	printB()
+}
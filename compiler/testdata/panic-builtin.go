@@ -0,0 +1,22 @@
+package main
+
+import "errors"
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+	check(nil)
+	check(errors.New("something failed"))
+}
+
+// panic: something failed
+//
+// goroutine 1 [running]:
+// main.check()
+// 	testdata/panic-builtin.go:7
+// main.main()
+// 	testdata/panic-builtin.go:13
@@ -0,0 +1,36 @@
+package main
+
+type list struct {
+	items []int
+}
+
+func (l *list) get(i int) int {
+	return l.items[i]
+}
+
+func lookup(l *list, i int) int {
+	v := l.get(i)
+	return v
+}
+
+func main() {
+	l := &list{items: []int{1, 2, 3}}
+	lookup(l, 1)
+
+	f := func(l *list) {
+		lookup(l, 5)
+	}
+	f(l)
+}
+
+// runtime panic: index out of range
+//
+// goroutine 1 [running]:
+// main.(*list).get()
+// 	testdata/panic-stacktrace.go:8
+// main.lookup()
+// 	testdata/panic-stacktrace.go:12
+// main.main.func1()
+// 	testdata/panic-stacktrace.go:21
+// main.main()
+// 	testdata/panic-stacktrace.go:23
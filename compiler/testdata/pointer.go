@@ -2,6 +2,21 @@ package main
 
 import "external"
 
+func ref(n int) *int {
+	v := n
+	return &v
+}
+
+func refZero() *int {
+	var v int
+	return &v
+}
+
+func elem(n int) *int {
+	a := [2]int{n - 1, n}
+	return &a[1]
+}
+
 func main() {
 	i := 100
 	iptr := &i
@@ -24,4 +39,30 @@ func main() {
 	// 300
 	external.Printf("%d\n", i)
 	external.Printf("%d\n", *iptr)
+
+	a := ref(1)
+	b := ref(2)
+	// 1 2
+	external.Printf("%d %d\n", *a, *b)
+
+	z := refZero()
+	// 0
+	external.Printf("%d\n", *z)
+
+	e := elem(1)
+	f := elem(2)
+	// 1 2
+	external.Printf("%d %d\n", *e, *f)
+
+	var ptrs []*int
+	for i := 0; i < 3; i++ {
+		v := i
+		ptrs = append(ptrs, &v)
+	}
+	for _, p := range ptrs {
+		// 0
+		// 1
+		// 2
+		external.Printf("%d\n", *p)
+	}
 }
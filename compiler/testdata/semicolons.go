@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type shape interface{ area() int; name() string }
+
+type square struct{ side int }
+
+func (s square) area() int { return s.side * s.side }
+func (s square) name() string { return "square" }
+
+type builder struct {
+	parts []string
+}
+
+func (b *builder) add(s string) *builder {
+	b.parts = append(b.parts, s)
+	return b
+}
+
+func (b *builder) count() int {
+	return len(b.parts)
+}
+
+func sum(
+	a int,
+	b int,
+) int {
+	return a +
+		b
+}
+
+func main() {
+	var s shape = square{side: 3}
+	// 9 square
+	fmt.Println(s.area(), s.name())
+
+	a := 1; b := 2
+	// 1 2
+	fmt.Println(a, b)
+
+	total := sum(
+		3,
+		4,
+	)
+	// 7
+	fmt.Println(total)
+
+	n := (&builder{}).
+		add("x").
+		add("y").
+		count()
+	// 2
+	fmt.Println(n)
+
+	ok := a == 1 &&
+		b == 2 &&
+		strings.Contains("abc",
+			"b")
+	// true
+	fmt.Println(ok)
+
+	nums := []int{
+		1,
+		2,
+		3,
+	}
+	// 3
+	fmt.Println(len(nums))
+
+	// i 1
+	// i 2
+	for i := 0; i < 2; {
+		i++
+		fmt.Println("i", i)
+	}
+
+	j := 0
+	for ; j < 2; j++ {
+	}
+	// j 2
+	fmt.Println("j", j)
+
+	k := 0
+	for ; ; k++ {
+		if k == 4 { break }
+	}
+	// k 4
+	fmt.Println("k", k)
+
+	switch k {
+	case 4: k++; fmt.Println("four")
+	default: fmt.Println("other")
+	}
+	// four
+	// 5
+	fmt.Println(k)
+}
@@ -6,7 +6,7 @@ func main() {
 	var arr []int
 
 	external.Printf("%d\n", len(arr)) // 0
-	external.Printf("%d\n", cap(arr)) // 2
+	external.Printf("%d\n", cap(arr)) // 0
 
 	arr = append(arr, 100)
 
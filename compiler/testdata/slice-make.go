@@ -0,0 +1,31 @@
+package main
+
+import "fmt"
+
+func main() {
+	a := make([]int, 3)
+	fmt.Println(a, len(a), cap(a)) // [0 0 0] 3 3
+
+	b := make([]byte, 2, 8)
+	fmt.Println(len(b), cap(b)) // 2 8
+
+	s := make([]int, 3, 10)
+	s[1] = 5
+	t := s[1:]
+	fmt.Println(t, len(t), cap(t)) // [5 0] 2 9
+
+	t = append(t, 7)
+	fmt.Println(t, s[:4]) // [5 0 7] [0 5 0 7]
+
+	u := s[2:2]
+	u = append(u, 9)
+	fmt.Println(s, u) // [0 5 9] [9]
+
+	n := -1
+	// runtime panic: makeslice: len out of range
+	//
+	// goroutine 1 [running]:
+	// main.main()
+	// 	testdata/slice-make.go:30
+	make([]int, n)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+type point struct {
+	x int
+}
+
+var names = []string{"ann", "bob", "cid"}
+
+func startsWithB(i int) bool {
+	return names[i][0] == byte(98)
+}
+
+func main() {
+	nums := []int{4, 8, 15, 16, 23, 42}
+	fmt.Println(slices.Index(nums, 15), slices.Index(nums, 7))
+	// 2 -1
+	fmt.Println(slices.Contains(nums, 42), slices.Contains(nums, 43))
+	// true false
+
+	fmt.Println(slices.Index(names, "cid"), slices.Contains(names, "dan"))
+	// 2 false
+
+	var u8 uint8 = 4
+	fmt.Println(slices.Contains(nums, u8))
+	// false
+
+	p1 := &point{x: 1}
+	p2 := &point{x: 1}
+	ptrs := []*point{p1}
+	fmt.Println(slices.Contains(ptrs, p1), slices.Contains(ptrs, p2))
+	// true false
+
+	fmt.Println(slices.IndexFunc(names, startsWithB), slices.ContainsFunc(names, startsWithB))
+	// 1 true
+}
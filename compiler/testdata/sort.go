@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type person struct {
+	name string
+	age  int
+}
+
+var people []person
+
+func byAge(i, j int) bool {
+	return people[i].age < people[j].age
+}
+
+type byLen struct {
+	s []string
+}
+
+func (b *byLen) Len() int {
+	return len(b.s)
+}
+
+func (b *byLen) Less(i, j int) bool {
+	return len(b.s[i]) < len(b.s[j])
+}
+
+func (b *byLen) Swap(i, j int) {
+	tmp := b.s[i]
+	b.s[i] = b.s[j]
+	b.s[j] = tmp
+}
+
+func atLeast42(i int) bool {
+	return i >= 42
+}
+
+func main() {
+	a := []int{5, 2, 8, 1, 9, 3, 7, 3, 0, -4, 12, 11, 6, 10, 15, 14, 13, -1, 4}
+	sort.Ints(a)
+	fmt.Println(a, sort.IntsAreSorted(a))
+	// [-4 -1 0 1 2 3 3 4 5 6 7 8 9 10 11 12 13 14 15] true
+
+	big := make([]int, 1000)
+	for i := 0; i < 1000; i++ {
+		v := i * 7919
+		big[i] = v - v/1000*1000
+	}
+	fmt.Println(sort.IntsAreSorted(big))
+	// false
+	sort.Ints(big)
+	fmt.Println(sort.IntsAreSorted(big), big[0], big[500], big[999])
+	// true 0 500 999
+
+	s := []string{"pear", "apple", "fig", "banana", "apricot", "app"}
+	sort.Strings(s)
+	fmt.Println(s, sort.StringsAreSorted(s), sort.SearchStrings(s, "banana"))
+	// [app apple apricot banana fig pear] true 3
+
+	people = []person{
+		person{name: "a", age: 30},
+		person{name: "b", age: 20},
+		person{name: "c", age: 30},
+		person{name: "d", age: 10},
+		person{name: "e", age: 20},
+	}
+	sort.SliceStable(people, byAge)
+	fmt.Println(people, sort.SliceIsSorted(people, byAge))
+	// [{d 10} {b 20} {e 20} {a 30} {c 30}] true
+
+	for i := 0; i < 50; i++ {
+		people = append(people, person{name: "x", age: 50 - i})
+	}
+	sort.Slice(people, byAge)
+	fmt.Println(len(people), people[0].age, people[54].age, sort.SliceIsSorted(people, byAge))
+	// 55 1 50 true
+
+	w := &byLen{s: []string{"ccc", "a", "bb", "dddd", "x", "yy"}}
+	sort.Stable(w)
+	fmt.Println(w.s)
+	// [a x bb yy ccc dddd]
+	sort.Sort(sort.Reverse(w))
+	fmt.Println(w.s[0], w.s[1], len(w.s[5]), sort.IsSorted(w))
+	// dddd ccc 1 false
+
+	fmt.Println(sort.SearchInts(a, 7), sort.SearchInts(a, 100), sort.Search(100, atLeast42))
+	// 10 19 42
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+func main() {
+	fmt.Println(strconv.Itoa(-123), strconv.FormatInt(255, 16), strconv.FormatInt(-5, 2)) // -123 ff -101
+	fmt.Println(strconv.Quote("hi \"there\"\n\té"))                                       // "hi \"there\"\n\té"
+	fmt.Println(strconv.Quote(string([]byte{97, 255, 237, 160, 128, 226, 130, 172})))     // "a\xff\xed\xa0\x80€"
+
+	n, err := strconv.Atoi("1234")
+	fmt.Println(n, err) // 1234 <nil>
+	n, err = strconv.Atoi("12a")
+	fmt.Println(n, err) // 0 strconv.Atoi: parsing "12a": invalid syntax
+
+	i, err := strconv.ParseInt("-0x1f", 0, 64)
+	fmt.Println(i, err) // -31 <nil>
+	i, err = strconv.ParseInt("0b101", 0, 8)
+	fmt.Println(i, err) // 5 <nil>
+	i, err = strconv.ParseInt("-9223372036854775808", 10, 64)
+	fmt.Println(i, err) // -9223372036854775808 <nil>
+	i, err = strconv.ParseInt("128", 10, 8)
+	fmt.Println(i, err) // 127 strconv.ParseInt: parsing "128": value out of range
+	if errors.Is(err, strconv.ErrRange) {
+		fmt.Println("out of range") // out of range
+	}
+}
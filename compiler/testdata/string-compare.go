@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const greeting = "hello"
+
+func kind(s string) string {
+	switch s {
+	case "a", "b":
+		return "ab"
+	case "":
+		return "empty"
+	case greeting:
+		return "greeting"
+	}
+	return "other"
+}
+
+var evaluated []string
+
+func eval(s string) string {
+	evaluated = append(evaluated, s)
+	return s
+}
+
+func lazy(s string) string {
+	evaluated = nil
+	switch s {
+	case eval("x"), eval("y"):
+		return "xy"
+	case eval("z"):
+		return "z"
+	}
+	return "none"
+}
+
+func main() {
+	a := "abc"
+	b := "abd"
+	fmt.Println(a == b, a != b, a < b, a <= b, a > b, a >= b)          // false true true true false false
+	fmt.Println(a == "abc", "ab" < a, a < "ab", "" < a, a[:2] == "ab") // true true false true true
+
+	var empty string
+	fmt.Println(empty == "", empty < "a", "é" > "z", greeting == "hello") // true true true true
+
+	fmt.Println(kind("a"), kind("b"), kind(""), kind("hello"), kind("x")) // ab ab empty greeting other
+	fmt.Println(lazy("x"), strings.Join(evaluated, ","))                  // xy x
+	fmt.Println(lazy("z"), strings.Join(evaluated, ","))                  // z x,y,z
+	fmt.Println(lazy("q"), strings.Join(evaluated, ","))                  // none x,y,z
+
+	s := []string{"pear", "apple", "fig", "app", ""}
+	sort.Strings(s)
+	fmt.Printf("%q\n", s)                                                                        // ["" "app" "apple" "fig" "pear"]
+	fmt.Println(sort.SearchStrings(s, "fig"), sort.StringsAreSorted(s))                          // 3 true
+	fmt.Println(strings.Compare("a", "b"), strings.Compare("b", "a"), strings.Compare("a", "a")) // -1 1 0
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"external"
+	"fmt"
+)
+
+var global string
+
+func repeat(s string, n int) string {
+	res := ""
+	for i := 0; i < n; i++ {
+		res += s
+	}
+	return res
+}
+
+func main() {
+	nul := string([]byte{97, 0, 98})
+	s := nul + nul
+	fmt.Println(len(s), s[1], s[4], s[5]) // 6 0 0 98
+
+	base := "x" + "y"
+	t := base + "1"
+	u := base + "2"
+	v := t + "3"
+	w := t + "4"
+	fmt.Println(base, t, u, v, w) // xy xy1 xy2 xy13 xy14
+
+	// xy xy1 xy13
+	external.Printf("%s %s %s\n", base, t, v)
+
+	global = repeat("ab", 3)
+	parts := []string{}
+	for i := 0; i < 3; i++ {
+		parts = append(parts, global+"!")
+	}
+	fmt.Println(global, parts[0], parts[2]) // ababab ababab! ababab!
+
+	big := repeat("ab", 1000000)
+	fmt.Println(len(big), big[:4]+"-"+big[len(big)-4:]) // 2000000 abab-abab
+
+	e := ""
+	fmt.Println(len(e+e), e+"z", "z"+e) // 0 z z
+}
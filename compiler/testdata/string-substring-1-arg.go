@@ -12,5 +12,9 @@ func main() {
 	external.Printf("%c\n", mystr[4])
 
 	// runtime panic: index out of range
+	//
+	// goroutine 1 [running]:
+	// main.main()
+	// 	testdata/string-substring-1-arg.go:19
 	external.Printf("%s\n", mystr[5])
 }
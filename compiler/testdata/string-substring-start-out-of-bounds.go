@@ -5,5 +5,9 @@ import "external"
 func main() {
 	mystr := "hello"
 	// runtime panic: substring out of bounds
+	//
+	// goroutine 1 [running]:
+	// main.main()
+	// 	testdata/string-substring-start-out-of-bounds.go:12
 	external.Printf("%s\n", mystr[6:10])
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func main() {
+	fmt.Println(strings.Contains("seafood", "foo"), strings.Contains("seafood", "bar")) // true false
+	fmt.Println(strings.Index("chicken", "ken"), strings.Index("chicken", "dmr"))       // 4 -1
+	fmt.Println(strings.HasPrefix("Gopher", "Go"), strings.HasSuffix("Amigo", "Ami"))   // true false
+
+	fmt.Printf("%q\n", strings.Split("a,b,c", ","))         // ["a" "b" "c"]
+	fmt.Printf("%q\n", strings.Split("a man a plan", "a ")) // ["" "man " "plan"]
+	fmt.Printf("%q\n", strings.Split("héj", ""))            // ["h" "é" "j"]
+	fmt.Printf("%q\n", strings.Fields("  foo bar\tbaz   ")) // ["foo" "bar" "baz"]
+
+	nbsp := string([]byte{194, 160})
+	fmt.Printf("%q\n", strings.Fields("Ņ a"+nbsp+"b"))    // ["Ņ" "a" "b"]
+	fmt.Printf("%q\n", strings.TrimSpace(nbsp+"Ņ "+nbsp)) // "Ņ"
+
+	fmt.Println(strings.Join([]string{"foo", "bar", "baz"}, ", "))         // foo, bar, baz
+	fmt.Println(strings.Replace("oink oink oink", "k", "ky", 2))           // oinky oinky oink
+	fmt.Println(strings.Replace("oink oink oink", "oink", "moo", -1))      // moo moo moo
+	fmt.Println(strings.ReplaceAll("abc", "", "-"))                        // -a-b-c-
+	fmt.Printf("%q\n", strings.TrimSpace(" \t\n Hello, Gophers \n\t\r\n")) // "Hello, Gophers"
+
+	var b strings.Builder
+	for i := 3; i > 0; i-- {
+		fmt.Fprintf(&b, "%d...", i)
+	}
+	b.WriteString("ignition")
+	fmt.Println(b.String(), b.Len()) // 3...2...1...ignition 20
+
+	s := "hello world"
+	fmt.Println(s[:5], s[6:], s[:]) // hello world hello world
+}
@@ -0,0 +1,141 @@
+package main
+
+import "external"
+
+type item struct {
+	name  string
+	count int
+}
+
+type config struct {
+	name  string
+	inner struct {
+		x, y int
+	}
+}
+
+type counter struct {
+	n int
+}
+
+func newCounter(n int) *counter {
+	return &counter{n: n}
+}
+
+var defaults = []*item{{name: "d1"}, {name: "d2", count: 2}}
+
+func main() {
+	var point struct {
+		x int
+		y int
+	}
+	point.x = 3
+	point.y = 4
+	external.Printf("%d %d\n", point.x, point.y)
+	// 3 4
+
+	point = struct {
+		x int
+		y int
+	}{x: 1, y: 2}
+	external.Printf("%d %d\n", point.x, point.y)
+	// 1 2
+
+	pair := struct {
+		a int
+		b string
+	}{a: 1, b: "one"}
+	external.Printf("%d %s\n", pair.a, pair.b)
+	// 1 one
+
+	ref := &struct{ a int }{a: 7}
+	ref.a++
+	external.Printf("%d\n", ref.a)
+	// 8
+
+	var c config
+	c.name = "cfg"
+	c.inner.x = 10
+	c.inner.y = 20
+	external.Printf("%s %d %d\n", c.name, c.inner.x, c.inner.y)
+	// cfg 10 20
+
+	tests := []struct {
+		a int
+		b string
+	}{
+		{a: 1, b: "x"},
+		{a: 2, b: "y"},
+		{
+			a: 3,
+			b: "z",
+		},
+	}
+	for _, tc := range tests {
+		external.Printf("%d %s\n", tc.a, tc.b)
+	}
+	// 1 x
+	// 2 y
+	// 3 z
+
+	items := []item{{name: "a", count: 1}, {name: "b", count: 2}}
+	for i, it := range items {
+		external.Printf("%d %s %d\n", i, it.name, it.count)
+	}
+	// 0 a 1
+	// 1 b 2
+
+	ptrs := []*item{{name: "p", count: 5}, {name: "q", count: 6}}
+	ptrs[0].count++
+	external.Printf("%s %d %s %d\n", ptrs[0].name, ptrs[0].count, ptrs[1].name, ptrs[1].count)
+	// p 6 q 6
+
+	arr := [2]item{{name: "first"}, {name: "second", count: 2}}
+	external.Printf("%s %d %s %d\n", arr[0].name, arr[0].count, arr[1].name, arr[1].count)
+	// first 0 second 2
+
+	nested := [][]item{{{name: "a"}, {name: "b"}}, {{name: "c", count: 3}}}
+	external.Printf("%s %s %s %d\n", nested[0][0].name, nested[0][1].name, nested[1][0].name, nested[1][0].count)
+	// a b c 3
+
+	var list []*item
+	for i := 0; i < 3; i++ {
+		list = append(list, &item{name: "loop", count: i})
+	}
+	for i := 3; i < 5; i++ {
+		p := &item{name: "var", count: i}
+		list = append(list, p)
+	}
+	for _, it := range list {
+		external.Printf("%s %d\n", it.name, it.count)
+	}
+	// loop 0
+	// loop 1
+	// loop 2
+	// var 3
+	// var 4
+
+	var saved *item
+	for i := 0; i < 2; i++ {
+		it := item{count: i + 100}
+		if i == 0 {
+			saved = &it
+		}
+	}
+	external.Printf("%d\n", saved.count)
+	// 100
+
+	var counters []*counter
+	for i := 0; i < 3; i++ {
+		counters = append(counters, newCounter(i*10))
+	}
+	for _, ct := range counters {
+		external.Printf("%d\n", ct.n)
+	}
+	// 0
+	// 10
+	// 20
+
+	external.Printf("%s %d\n", defaults[1].name, defaults[1].count)
+	// d2 2
+}
@@ -1,6 +1,7 @@
 // yoloyolo = 100
 // yoloyolo = 100
 // witharg = 500
+// yoloyolo = 300
 
 package main
 
@@ -19,10 +20,17 @@ func (m myint) WithArg(arg int) {
 	external.Printf("witharg = %d\n", arg)
 }
 
+func newMyint(a int) myint {
+	return myint{A: a}
+}
+
 func main() {
 	var abc myint
 	abc.A = 100
 	abc.Yolo()
 	abc.Yolo()
 	abc.WithArg(500)
+
+	def := newMyint(300)
+	def.Yolo()
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"external"
+	"fmt"
+	"sync"
+)
+
+var mu sync.Mutex
+var rw sync.RWMutex
+var wg sync.WaitGroup
+var once sync.Once
+
+var counter int
+var writes int
+var reads int
+var setups int
+
+func setup() {
+	setups++
+}
+
+func worker(n int) {
+	once.Do(setup)
+
+	for i := 0; i < 10000; i++ {
+		mu.Lock()
+		counter++
+		mu.Unlock()
+	}
+
+	for i := 0; i < 1000; i++ {
+		rw.Lock()
+		writes++
+		rw.Unlock()
+
+		rw.RLock()
+		if writes > 0 {
+			mu.Lock()
+			reads++
+			mu.Unlock()
+		}
+		rw.RUnlock()
+	}
+
+	wg.Done()
+}
+
+func main() {
+	var threads []int
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		threads = append(threads, external.ThreadCreate(worker, i))
+	}
+	wg.Wait()
+	fmt.Println(counter, writes, reads, setups) // 40000 4000 4000 1
+
+	for _, t := range threads {
+		external.ThreadJoin(t)
+	}
+
+	fmt.Println(mu.TryLock(), mu.TryLock()) // true false
+	mu.Unlock()
+
+	var l sync.Locker = &mu
+	l.Lock()
+	fmt.Println(mu.TryLock()) // false
+	l.Unlock()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func main() {
+	fmt.Println(time.Duration(0).String(), time.Duration(1).String(), time.Duration(1100).String()) // 0s 1ns 1.1µs
+	fmt.Println((2200 * time.Microsecond).String(), (3300 * time.Millisecond).String())             // 2.2ms 3.3s
+	fmt.Println((4*time.Minute + 5*time.Second + 5001*time.Millisecond).String())                   // 4m10.001s
+	fmt.Println((5*time.Hour + 6*time.Minute + 7001*time.Millisecond).String())                     // 5h6m7.001s
+	fmt.Println((-1 * time.Second).String(), time.Duration(-1<<63).String())                        // -1s -2562047h47m16.854775808s
+	fmt.Println((1500 * time.Millisecond).Seconds(), (90 * time.Minute).Hours())                    // 1.5 1.5
+	fmt.Println(time.Second.Milliseconds(), (1999 * time.Millisecond).Truncate(time.Second))        // 1000 1s
+
+	t := time.Unix(1700000000, 5)
+	fmt.Println(t.Unix(), t.UnixNano(), t.Nanosecond(), time.Unix(-1, 5).Unix()) // 1700000000 1700000000000000005 5 -1
+	fmt.Println(t.Add(time.Second).Sub(t), t.Before(t.Add(1)), t.After(t))       // 1s true false
+
+	start := time.Now()
+	time.Sleep(20 * time.Millisecond)
+	elapsed := time.Since(start)
+	fmt.Println(elapsed >= 20*time.Millisecond, elapsed < time.Second) // true true
+	fmt.Println(time.Now().Unix() > 1700000000)                        // true
+}
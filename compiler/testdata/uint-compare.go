@@ -0,0 +1,26 @@
+package main
+
+import "fmt"
+
+type celsius int64
+
+func (c celsius) String() string {
+	return "celsius"
+}
+
+const (
+	freezing celsius = 0
+	boiling          = 100 * freezing
+)
+
+func main() {
+	var u uint64 = 1 << 63
+	var b byte = 200
+	fmt.Println(u > 5, u < 5, b > 100) // true false true
+
+	var w uint32 = 1<<32 - 1
+	fmt.Println(w, w > 5) // 4294967295 true
+
+	var i int64 = 5
+	fmt.Println(i, celsius(5), 2*boiling) // 5 celsius celsius
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+)
+
+func main() {
+	fmt.Println(unicode.IsSpace(32), unicode.IsSpace(9), unicode.IsSpace(13))   // true true true
+	fmt.Println(unicode.IsSpace(160), unicode.IsSpace(133))                     // true true
+	fmt.Println(unicode.IsSpace(8195), unicode.IsSpace(12288))                  // true true
+	fmt.Println(unicode.IsSpace(97), unicode.IsSpace(0), unicode.IsSpace(8203)) // false false false
+}
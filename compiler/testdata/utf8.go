@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+func main() {
+	s := "aé€😀"
+	fmt.Println(len(s), utf8.RuneCountInString(s), utf8.ValidString(s)) // 10 4 true
+
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		fmt.Println(r, size)
+		i = i + size
+	}
+	// 97 1
+	// 233 2
+	// 8364 3
+	// 128512 4
+
+	buf := make([]byte, 4)
+	fmt.Println(utf8.EncodeRune(buf, 8364), buf)   // 3 [226 130 172 0]
+	fmt.Println(utf8.EncodeRune(buf, 128512), buf) // 4 [240 159 152 128]
+	fmt.Println(utf8.EncodeRune(buf, 55296), buf)  // 3 [239 191 189 128]
+
+	fmt.Println(string(utf8.AppendRune([]byte("x"), 233))) // xé
+
+	bad := string([]byte{97, 255, 98})
+	fmt.Println(utf8.ValidString(bad), utf8.RuneCountInString(bad)) // false 3
+	r, size := utf8.DecodeRuneInString(bad[1:])
+	fmt.Println(r, size) // 65533 1
+	r, size = utf8.DecodeRuneInString("")
+	fmt.Println(r, size) // 65533 0
+
+	fmt.Println(utf8.ValidString(string([]byte{237, 160, 128}))) // false
+	fmt.Println(utf8.ValidString(string([]byte{192, 128})))      // false
+
+	fmt.Println(utf8.RuneLen(233), utf8.RuneLen(-1), utf8.ValidRune(1114112)) // 2 -1 false
+	fmt.Println(utf8.RuneCount([]byte("héllo")), utf8.Valid([]byte("héllo"))) // 5 true
+}